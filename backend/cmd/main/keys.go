@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/configs"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+var (
+	// errKeysUsage for when the keys subcommand is misused.
+	errKeysUsage = errors.New("usage: keys issue -name=NAME -scopes=scope,... | keys list | keys revoke ID")
+)
+
+// runKeys runs the keys subcommand: "issue" prints a new API key (the only time it can be seen), "list" prints every
+// key, "revoke ID" revokes one.
+func runKeys(ctx context.Context, cfg *configs.DataConfig, args []string) error {
+	if len(args) == 0 {
+		return errKeysUsage
+	}
+
+	flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	name := flags.String("name", "", "what (or who) the key is for.")
+	rawScopes := flags.String("scopes", "", "comma-separated scopes, i.e.: data:read,data:write.")
+
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return errKeysUsage
+	}
+
+	err = ensureSchema(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	apiKeyDB := buildAPIKeyRepository(cfg)
+	defer apiKeyDB.Close(ctx)
+
+	keys := service.NewAPIKey(ctx, apiKeyDB, "")
+
+	switch {
+	case args[0] == "issue" && flags.NArg() == 0:
+		return issueKey(ctx, keys, *name, *rawScopes)
+	case args[0] == "list" && flags.NArg() == 0:
+		return listKeys(ctx, keys)
+	case args[0] == "revoke" && flags.NArg() == 1:
+		key, err := keys.Revoke(ctx, flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("could not revoke %s: %w", flags.Arg(0), err)
+		}
+
+		fmt.Printf("revoked %s (%s)\n", key.ID, key.Name)
+
+		return nil
+	}
+
+	return errKeysUsage
+}
+
+// issueKey issues an API key, and prints it.
+func issueKey(ctx context.Context, keys *service.APIKey, name string, rawScopes string) error {
+	input := types.APIKeyInput{Name: name}
+
+	for _, scope := range strings.Split(rawScopes, ",") {
+		if strings.TrimSpace(scope) != "" {
+			input.Scopes = append(input.Scopes, types.Scope(strings.TrimSpace(scope)))
+		}
+	}
+
+	key, secret, err := keys.Issue(ctx, input)
+	if err != nil {
+		return fmt.Errorf("could not issue API key: %w", err)
+	}
+
+	fmt.Printf("issued %s (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+	fmt.Printf("key (shown only once): %s\n", secret)
+
+	return nil
+}
+
+// listKeys prints every API key, one per line.
+func listKeys(ctx context.Context, keys *service.APIKey) error {
+	opts := types.ListOptions{Limit: types.MaxLimit}
+
+	for {
+		page, err := keys.List(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("could not list API keys: %w", err)
+		}
+
+		for _, key := range page.Items {
+			status := "active"
+			if key.IsRevoked() {
+				status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), status)
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+
+		opts.Cursor = page.NextCursor
+	}
+}
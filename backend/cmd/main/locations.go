@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/configs"
+	"github.com/wakka-2/Namless/backend/pkg/locationio"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// stdio names standard input or output, in place of a file.
+const stdio = "-"
+
+var (
+	// errLocationsUsage for when the locations subcommand is misused.
+	errLocationsUsage = errors.New(
+		"usage: locations import [-format=csv|gpx|kml] [-columns=field=column,...] [-dry-run] file | " +
+			"locations export [-format=csv|gpx|kml] [-columns=field=column,...] [file]",
+	)
+)
+
+// runLocations runs the locations subcommand: "import file" reads locations from a file ("-" for standard input),
+// "export [file]" writes every location to a file (standard output by default).
+func runLocations(ctx context.Context, cfg *configs.DataConfig, args []string) error {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		return errLocationsUsage
+	}
+
+	flags := flag.NewFlagSet("locations "+args[0], flag.ContinueOnError)
+	format := flags.String("format", locationio.FormatCSV, "csv, gpx or kml.")
+	rawColumns := flags.String("columns", "", "CSV column of each field, i.e.: location=name,latitude=lat.")
+	dryRun := flags.Bool("dry-run", false, "only report what an import would do.")
+
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return errLocationsUsage
+	}
+
+	columns, err := locationio.ParseColumns(*rawColumns)
+	if err != nil {
+		return fmt.Errorf("could not parse columns: %w", err)
+	}
+
+	err = ensureSchema(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+
+	locationDB := buildLocationRepository(cfg)
+	defer locationDB.Close(ctx)
+
+	locations, err := service.NewLocation(ctx, locationDB, buildBlobStore(cfg))
+	if err != nil {
+		return fmt.Errorf("could not build Location service: %w", err)
+	}
+
+	if args[0] == "import" && flags.NArg() == 1 {
+		return importLocations(ctx, locations, flags.Arg(0), *format, columns, *dryRun)
+	}
+
+	if args[0] == "export" && flags.NArg() <= 1 {
+		return exportLocations(ctx, locations, flags.Arg(0), *format, columns)
+	}
+
+	return errLocationsUsage
+}
+
+// importLocations imports the locations of a file, prints the bad ones to standard error, and sums up.
+func importLocations(
+	ctx context.Context,
+	locations *service.Location,
+	path string,
+	format string,
+	columns locationio.Columns,
+	dryRun bool,
+) error {
+	input := io.Reader(os.Stdin)
+
+	if path != stdio {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %w", path, err)
+		}
+
+		defer file.Close()
+
+		input = file
+	}
+
+	reader, err := locationio.NewReader(format, input, columns)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	output := types.ImportOutput{DryRun: dryRun}
+
+	err = locations.ImportFrom(ctx, reader, dryRun, func(result types.ImportResult) error {
+		output.Count(result)
+
+		if result.Outcome == types.ImportCreated || result.Outcome == types.ImportUpdated {
+			return nil
+		}
+
+		problems := make([]string, 0, len(result.Fields))
+		for _, field := range result.Fields {
+			problems = append(problems, field.Field+": "+field.Message)
+		}
+
+		if len(problems) == 0 {
+			problems = append(problems, result.Error)
+		}
+
+		_, err := fmt.Fprintf(os.Stderr, "line %d: %s\n", result.Line, strings.Join(problems, "; "))
+
+		return err
+	})
+
+	summary := fmt.Sprintf("created %d, updated %d, failed %d", output.Created, output.Updated, output.Failed)
+	if dryRun {
+		summary += " (dry run: nothing was written)"
+	}
+
+	fmt.Println(summary)
+
+	if err != nil {
+		return fmt.Errorf("could not import %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// exportLocations writes every location to a file.
+func exportLocations(
+	ctx context.Context,
+	locations *service.Location,
+	path string,
+	format string,
+	columns locationio.Columns,
+) error {
+	output := io.Writer(os.Stdout)
+
+	if path != "" && path != stdio {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create %s: %w", path, err)
+		}
+
+		defer file.Close()
+
+		output = file
+	}
+
+	writer, err := locationio.NewWriter(format, output, columns)
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	err = locations.ExportTo(ctx, writer)
+	if err != nil {
+		return fmt.Errorf("could not export locations: %w", err)
+	}
+
+	return nil
+}
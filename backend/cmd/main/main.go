@@ -6,21 +6,44 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/wakka-2/Namless/backend/pkg/api"
+	"github.com/wakka-2/Namless/backend/pkg/blob"
 	"github.com/wakka-2/Namless/backend/pkg/configs"
+	"github.com/wakka-2/Namless/backend/pkg/jwt"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/ratelimit"
 	"github.com/wakka-2/Namless/backend/pkg/repository"
 	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
 const (
 	readHeaderTimeout = 3 * time.Minute
+	closeTimeout      = 10 * time.Second
+	// mintPollInterval is the time between two looks for due mint jobs.
+	mintPollInterval = 5 * time.Second
+	// jwksTimeout bounds every fetch of the keys signing JSON Web Tokens.
+	jwksTimeout = 10 * time.Second
+	// dataFile, locationFile, mintJobFile, claimFile, idempotencyFile and apiKeyFile are the names of the files kept
+	// in StorageDir by the file storage.
+	dataFile        = "data.log"
+	locationFile    = "locations.log"
+	mintJobFile     = "mint_jobs.log"
+	claimFile       = "claims.log"
+	idempotencyFile = "idempotency.log"
+	apiKeyFile      = "api_keys.log"
+	// blobDir is the name of the directory kept in StorageDir for uploaded images, by the file storage.
+	blobDir = "blobs"
 )
 
 // @title           Data storage API
@@ -30,6 +53,7 @@ const (
 // main starts the application.
 func main() {
 	configLocation := flag.String("config", "/etc/data/recon.json", "`configfile` for data service.")
+	migrate := flag.Bool("migrate", false, "apply the pending schema migrations before starting.")
 	flag.Parse()
 
 	cfg, err := configs.ReadConfigs(*configLocation)
@@ -47,26 +71,432 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	database, err := repository.New(cfg.DSN, true)
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(ctx, cfg, flag.Args()[1:])
+		if err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "keys" {
+		err = runKeys(ctx, cfg, flag.Args()[1:])
+		if err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "locations" {
+		err = runLocations(ctx, cfg, flag.Args()[1:])
+		if err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	err = ensureSchema(ctx, cfg, *migrate)
 	if err != nil {
-		panic("could not build Data repository")
+		panic(fmt.Sprintf("refusing to start: %s", err))
 	}
 
-	locationDB, err := repository.NewLocation(cfg.DSN, true)
+	database, locationDB := buildRepositories(cfg)
+
+	quotas, err := buildQuotas(cfg.Namespaces)
 	if err != nil {
-		panic("could not build Location repository")
+		panic(fmt.Sprintf("could not build quotas: %s", err))
+	}
+
+	dataService := service.New(ctx, database, quotas)
+
+	go dataService.RunReaper(service.ReaperConfig{
+		Interval:      time.Duration(cfg.ReaperIntervalSeconds) * time.Second,
+		BatchSize:     cfg.ReaperBatchSize,
+		HistoryKeep:   cfg.HistoryKeepVersions,
+		HistoryMaxAge: time.Duration(cfg.HistoryMaxAgeSeconds) * time.Second,
+		TrashMaxAge:   time.Duration(cfg.TrashRetentionSeconds) * time.Second,
+	})
+
+	locationService, err := service.NewLocation(ctx, locationDB, buildBlobStore(cfg))
+	if err != nil {
+		panic(fmt.Sprintf("could not build Location service: %s", err))
+	}
+
+	minter, err := buildMinter(cfg.Minting)
+	if err != nil {
+		panic(fmt.Sprintf("could not build minter: %s", err))
 	}
 
-	dataService := service.New(ctx, database)
-	locationService := service.NewLocation(ctx, locationDB)
+	mintJobDB := buildMintJobRepository(cfg)
+	tokenService := service.NewToken(ctx, minter, mintJobDB)
 
-	restAPI := api.New(dataService, locationService)
+	go tokenService.RunWorkers(service.MintConfig{
+		Workers:      cfg.Minting.Workers,
+		MaxAttempts:  cfg.Minting.MaxAttempts,
+		RetryBase:    time.Duration(cfg.Minting.RetryBaseSeconds) * time.Second,
+		RetryMax:     time.Duration(cfg.Minting.RetryMaxSeconds) * time.Second,
+		Lease:        time.Duration(cfg.Minting.LeaseSeconds) * time.Second,
+		PollInterval: mintPollInterval,
+	})
+
+	claimDB := buildClaimRepository(cfg)
+	checkinService := service.NewCheckin(ctx, locationService, tokenService, claimDB, cfg.CheckinRadiusMeters)
+
+	idempotencyDB := buildIdempotencyRepository(cfg)
+	idempotencyService := service.NewIdempotency(
+		ctx, idempotencyDB, time.Duration(cfg.IdempotencyWindowSeconds)*time.Second)
+
+	go idempotencyService.RunReaper(time.Duration(cfg.ReaperIntervalSeconds)*time.Second, cfg.ReaperBatchSize)
+
+	apiKeyDB := buildAPIKeyRepository(cfg)
+
+	var (
+		apiKeyService *service.APIKey
+		jwtService    *service.JWT
+	)
+
+	if cfg.Auth.Disabled {
+		log.Default().Printf("authentication disabled: every route is open to anyone")
+	} else {
+		apiKeyService = service.NewAPIKey(ctx, apiKeyDB, cfg.Auth.AdminKey)
+
+		jwtService, err = buildJWTService(ctx, cfg.Auth.JWT)
+		if err != nil {
+			panic(fmt.Sprintf("could not build JWT service: %s", err))
+		}
+	}
+
+	rateLimits, err := buildRateLimits(cfg.RateLimits)
+	if err != nil {
+		panic(fmt.Sprintf("could not build rate limits: %s", err))
+	}
+
+	restAPI := api.New(
+		dataService, locationService, tokenService, idempotencyService, checkinService, apiKeyService, jwtService,
+		rateLimits,
+	)
 
 	go runServer(restAPI, cfg.ListenAddress)
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
+
+	cancel()
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer closeCancel()
+
+	err = database.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close Data repository: %s", err)
+	}
+
+	err = locationDB.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close Location repository: %s", err)
+	}
+
+	err = mintJobDB.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close MintJob repository: %s", err)
+	}
+
+	err = claimDB.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close Claim repository: %s", err)
+	}
+
+	err = idempotencyDB.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close Idempotency repository: %s", err)
+	}
+
+	err = apiKeyDB.Close(closeCtx)
+	if err != nil {
+		log.Default().Printf("could not close APIKey repository: %s", err)
+	}
+}
+
+// buildRepositories builds the Data and Location repositories for the configured storage.
+func buildRepositories(cfg *configs.DataConfig) (repository.DataStore, repository.LocationStore) {
+	var database repository.DataStore
+
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		database = repository.NewMemory()
+	case configs.StorageFile:
+		var err error
+
+		database, err = repository.NewFile(filepath.Join(cfg.StorageDir, dataFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build Data repository: %s", err))
+		}
+	default:
+		var err error
+
+		database, err = repository.New(cfg.DSN, true)
+		if err != nil {
+			panic("could not build Data repository")
+		}
+	}
+
+	return database, buildLocationRepository(cfg)
+}
+
+// buildLocationRepository builds the Location repository for the configured storage.
+func buildLocationRepository(cfg *configs.DataConfig) repository.LocationStore {
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		return repository.NewMemoryLocation()
+	case configs.StorageFile:
+		locationDB, err := repository.NewFileLocation(filepath.Join(cfg.StorageDir, locationFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build Location repository: %s", err))
+		}
+
+		return locationDB
+	}
+
+	locationDB, err := repository.NewLocation(cfg.DSN, true)
+	if err != nil {
+		panic("could not build Location repository")
+	}
+
+	return locationDB
+}
+
+// buildMintJobRepository builds the MintJob repository for the configured storage.
+func buildMintJobRepository(cfg *configs.DataConfig) repository.MintJobStore {
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		return repository.NewMemoryMintJob()
+	case configs.StorageFile:
+		mintJobDB, err := repository.NewFileMintJob(filepath.Join(cfg.StorageDir, mintJobFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build MintJob repository: %s", err))
+		}
+
+		return mintJobDB
+	}
+
+	mintJobDB, err := repository.NewMintJob(cfg.DSN, true)
+	if err != nil {
+		panic("could not build MintJob repository")
+	}
+
+	return mintJobDB
+}
+
+// buildClaimRepository builds the Claim repository for the configured storage.
+func buildClaimRepository(cfg *configs.DataConfig) repository.ClaimStore {
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		return repository.NewMemoryClaim()
+	case configs.StorageFile:
+		claimDB, err := repository.NewFileClaim(filepath.Join(cfg.StorageDir, claimFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build Claim repository: %s", err))
+		}
+
+		return claimDB
+	}
+
+	claimDB, err := repository.NewClaim(cfg.DSN, true)
+	if err != nil {
+		panic("could not build Claim repository")
+	}
+
+	return claimDB
+}
+
+// buildIdempotencyRepository builds the Idempotency repository for the configured storage.
+func buildIdempotencyRepository(cfg *configs.DataConfig) repository.IdempotencyStore {
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		return repository.NewMemoryIdempotency()
+	case configs.StorageFile:
+		idempotencyDB, err := repository.NewFileIdempotency(filepath.Join(cfg.StorageDir, idempotencyFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build Idempotency repository: %s", err))
+		}
+
+		return idempotencyDB
+	}
+
+	idempotencyDB, err := repository.NewIdempotency(cfg.DSN, true)
+	if err != nil {
+		panic("could not build Idempotency repository")
+	}
+
+	return idempotencyDB
+}
+
+// buildAPIKeyRepository builds the APIKey repository for the configured storage.
+func buildAPIKeyRepository(cfg *configs.DataConfig) repository.APIKeyStore {
+	switch cfg.Storage {
+	case configs.StorageMemory:
+		return repository.NewMemoryAPIKey()
+	case configs.StorageFile:
+		apiKeyDB, err := repository.NewFileAPIKey(filepath.Join(cfg.StorageDir, apiKeyFile))
+		if err != nil {
+			panic(fmt.Sprintf("could not build APIKey repository: %s", err))
+		}
+
+		return apiKeyDB
+	}
+
+	apiKeyDB, err := repository.NewAPIKey(cfg.DSN, true)
+	if err != nil {
+		panic("could not build APIKey repository")
+	}
+
+	return apiKeyDB
+}
+
+// buildJWTService builds the service verifying JSON Web Tokens; nil when they are not accepted.
+func buildJWTService(ctx context.Context, cfg configs.JWTConfig) (*service.JWT, error) {
+	if !cfg.IsEnabled() {
+		return nil, nil
+	}
+
+	var keys jwt.KeySource
+
+	if cfg.JWKSFile != "" {
+		keySet, err := jwt.ReadKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read JWKSFile: %w", err)
+		}
+
+		keys = keySet
+	} else {
+		client := &http.Client{Timeout: jwksTimeout}
+		keys = jwt.NewRemote(cfg.JWKSURL, client, time.Duration(cfg.JWKSCacheSeconds)*time.Second)
+	}
+
+	scopes := make(map[string][]types.Scope, len(cfg.Scopes))
+
+	for value, names := range cfg.Scopes {
+		for _, name := range names {
+			scopes[value] = append(scopes[value], types.Scope(name))
+		}
+	}
+
+	verifier := jwt.NewVerifier(keys, cfg.Issuer, cfg.Audience, time.Duration(cfg.LeewaySeconds)*time.Second)
+
+	result, err := service.NewJWT(ctx, verifier, cfg.ScopeClaim, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("could not map scopes: %w", err)
+	}
+
+	return result, nil
+}
+
+// buildQuotas builds the quotas of the namespaces. Returns types.ErrInvalidNamespace when one is given to a name that
+// is not a valid namespace.
+func buildQuotas(cfg configs.NamespacesConfig) (service.Quotas, error) {
+	quota := func(config configs.QuotaConfig) service.Quota {
+		return service.Quota{
+			MaxKeys:       config.MaxKeys,
+			MaxValueBytes: config.MaxValueBytes,
+			MaxTotalBytes: config.MaxTotalBytes,
+		}
+	}
+
+	result := service.Quotas{Default: quota(cfg.Quota), Namespaces: make(map[string]service.Quota, len(cfg.Quotas))}
+
+	for name, config := range cfg.Quotas {
+		err := types.ValidateNamespace(name)
+		if err != nil {
+			return service.Quotas{}, fmt.Errorf("could not set quota: %w", err)
+		}
+
+		result.Namespaces[name] = quota(config)
+	}
+
+	return result, nil
+}
+
+// buildRateLimits builds the rate limits of the routes, kept in memory; nil when they are disabled. Route groups that
+// are not configured keep their default limits.
+func buildRateLimits(cfg configs.RateLimitsConfig) (*api.RateLimits, error) {
+	if cfg.Disabled {
+		log.Default().Printf("rate limits disabled: every request goes through")
+
+		return nil, nil
+	}
+
+	groups := maps.Clone(api.DefaultRateLimits)
+
+	for group, configured := range cfg.Groups {
+		limits := make([]api.RateLimit, 0, len(configured))
+
+		for _, limit := range configured {
+			limits = append(limits, api.RateLimit{
+				By:    limit.By,
+				Limit: ratelimit.PerPeriod(limit.Requests, time.Duration(limit.PeriodSeconds)*time.Second, limit.Burst),
+			})
+		}
+
+		groups[group] = limits
+	}
+
+	result, err := api.NewRateLimits(ratelimit.NewMemory(), groups, cfg.TrustForwardedFor)
+	if err != nil {
+		return nil, fmt.Errorf("could not check rate limits: %w", err)
+	}
+
+	return result, nil
+}
+
+// buildMinter builds the client of the configured minting service; nil when minting is disabled.
+func buildMinter(cfg configs.MintingConfig) (minting.Minter, error) {
+	switch cfg.Provider {
+	case configs.MinterNMKR:
+		result, err := minting.NewNMKR(minting.NMKRConfig{
+			BaseURL:         cfg.BaseURL,
+			ProjectUID:      cfg.ProjectUID,
+			APIKey:          cfg.APIKey,
+			ReceiverAddress: cfg.ReceiverAddress,
+			UploadSource:    cfg.UploadSource,
+			Timeout:         time.Duration(cfg.TimeoutSeconds) * time.Second,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not build NMKR client: %w", err)
+		}
+
+		return result, nil
+	case configs.MinterFake:
+		log.Default().Printf("fake minting: tokens are kept in memory, and never reach a chain")
+
+		return minting.NewFake(), nil
+	}
+
+	return nil, nil
+}
+
+// buildBlobStore builds the store of uploaded images for the configured storage.
+func buildBlobStore(cfg *configs.DataConfig) blob.Store {
+	dir := cfg.BlobDir
+	if dir == "" && cfg.Storage == configs.StorageFile {
+		dir = filepath.Join(cfg.StorageDir, blobDir)
+	}
+
+	if dir == "" {
+		log.Default().Printf("no BlobDir: uploaded images are kept in memory, and lost on restart")
+
+		return blob.NewMemory()
+	}
+
+	store, err := blob.NewFilesystem(dir)
+	if err != nil {
+		panic(fmt.Sprintf("could not build blob store: %s", err))
+	}
+
+	return store
 }
 
 func runServer(restAPI *api.RESTAPI, listenAddress string) {
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/configs"
+	"github.com/wakka-2/Namless/backend/pkg/migrations"
+)
+
+var (
+	// errNoSchema for when migrations are asked for a storage that has no schema.
+	errNoSchema = errors.New("migrations only apply to the postgres storage")
+	// errMigrateUsage for when the migrate subcommand is misused.
+	errMigrateUsage = errors.New("usage: migrate up | down [steps] | status")
+)
+
+// runMigrate runs the migrate subcommand: "up", "down [steps]" (one step by default) or "status".
+func runMigrate(ctx context.Context, cfg *configs.DataConfig, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errMigrateUsage
+	}
+
+	if cfg.Storage != configs.StoragePostgres {
+		return errNoSchema
+	}
+
+	runner, err := migrations.Open(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("could not open migrations: %w", err)
+	}
+
+	defer runner.Close()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := runner.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %s\n", migration)
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not migrate up: %w", err)
+		}
+
+		return nil
+	case args[0] == "down":
+		steps := 1
+
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return errMigrateUsage
+			}
+		}
+
+		reverted, err := runner.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("took back %s\n", migration)
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not migrate down: %w", err)
+		}
+
+		return nil
+	case args[0] == "status" && len(args) == 1:
+		return printStatus(ctx, runner)
+	}
+
+	return errMigrateUsage
+}
+
+// printStatus prints every known migration, and when it was applied.
+func printStatus(ctx context.Context, runner *migrations.Runner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get migration status: %w", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\n", status.Migration, appliedAt)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("could not print migration status: %w", err)
+	}
+
+	return nil
+}
+
+// ensureSchema checks that the DB schema is up to date, applying the pending migrations when migrate is true.
+//
+// Does nothing for the storages that have no schema.
+func ensureSchema(ctx context.Context, cfg *configs.DataConfig, migrate bool) error {
+	if cfg.Storage != configs.StoragePostgres {
+		return nil
+	}
+
+	runner, err := migrations.Open(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("could not open migrations: %w", err)
+	}
+
+	defer runner.Close()
+
+	if !migrate {
+		err = runner.Check(ctx)
+		if err != nil {
+			return fmt.Errorf("%w; run \"migrate up\", or start with -migrate", err)
+		}
+
+		return nil
+	}
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("could not migrate: %w", err)
+	}
+
+	for _, migration := range applied {
+		fmt.Printf("applied %s\n", migration)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// issuedAPIKey models the reply to a request issuing an API key: the only time the key can be read.
+type issuedAPIKey struct {
+	models.APIKey
+	// Key to authenticate with, in the Authorization (Bearer) or X-API-Key header.
+	Key string `json:"key"`
+}
+
+// IssueAPIKey issues an API key with the name and the scopes in the body of a request, and replies with it; the key
+// to authenticate with is only part of this reply.
+func (r *RESTAPI) IssueAPIKey(writer http.ResponseWriter, req *http.Request) {
+	input := types.APIKeyInput{}
+
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, key, err := r.apiKeyService.Issue(req.Context(), input)
+	if r.handleInvalidInput(writer, err) {
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not issue API key", http.StatusInternalServerError)
+		return
+	}
+
+	result.Hash = ""
+
+	err = writeJSON(writer, issuedAPIKey{APIKey: result, Key: key}, http.StatusCreated)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// RequestAPIKeys replies with a page of API keys, without their keys.
+//
+// Supports the limit, cursor, sort (created_at, name or id) and order query parameters, and filtering by name.
+func (r *RESTAPI) RequestAPIKeys(writer http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req, "name")
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.apiKeyService.List(req.Context(), opts)
+	if isInvalidListing(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+
+	for index := range result.Items {
+		result.Items[index].Hash = ""
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// RevokeAPIKey revokes the API key with the ID in the path; it is refused from then on.
+func (r *RESTAPI) RevokeAPIKey(writer http.ResponseWriter, req *http.Request) {
+	_, err := r.apiKeyService.Revoke(req.Context(), req.PathValue("id"))
+	if isNotFound(err) {
+		r.handleError(writer, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
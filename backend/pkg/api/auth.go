@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/jwt"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// apiKeyHeader holds an API key, for the callers that cannot send an Authorization header.
+	apiKeyHeader = "X-API-Key"
+	// bearerPrefix starts the Authorization header holding a key.
+	bearerPrefix = "Bearer "
+	// authenticateChallenge tells callers getting a 401 how to authenticate.
+	authenticateChallenge = `Bearer realm="namless"`
+	// jwtDots is the number of dots in a JSON Web Token; API keys have none.
+	jwtDots = 2
+)
+
+// Authenticate middleware reads the API key (or the JSON Web Token) of a request, from its Authorization header
+// (Bearer) or its X-API-Key header, and puts its caller in the context of the request (see types.PrincipalFrom).
+//
+// Requests without credentials go through, and routes that require a scope refuse them (see require); requests with
+// invalid credentials get 401 at once, once counted against the rate limits as anonymous requests (see RateLimit), so
+// that credentials cannot be guessed faster than anonymous callers are let through.
+func (r *RESTAPI) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(apiKeyHeader)
+
+		if authorization := req.Header.Get("Authorization"); authorization != "" {
+			var found bool
+
+			key, found = strings.CutPrefix(authorization, bearerPrefix)
+			if !found {
+				r.refuse(writer, req, "unsupported Authorization header, expected Bearer")
+				return
+			}
+		}
+
+		if r.apiKeyService == nil || key == "" {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		principal, err := r.authenticate(req, strings.TrimSpace(key))
+		if errors.Is(err, service.ErrInvalidAPIKey) || errors.Is(err, service.ErrInvalidToken) {
+			r.refuse(writer, req, err.Error())
+			return
+		}
+
+		if errors.Is(err, jwt.ErrKeysUnavailable) {
+			log.Default().Printf("could not authenticate: %s", err)
+			r.handleError(writer, "could not verify token, try again later", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		if err != nil {
+			log.Default().Printf("could not authenticate: %s", err)
+			r.handleError(writer, "could not authenticate", http.StatusInternalServerError)
+
+			return
+		}
+
+		next.ServeHTTP(writer, req.WithContext(types.WithPrincipal(req.Context(), principal)))
+	})
+}
+
+// refuse answers 401 to a request with invalid credentials, unless it is over the rate limits of anonymous requests.
+func (r *RESTAPI) refuse(writer http.ResponseWriter, req *http.Request, message string) {
+	r.RateLimit(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		r.handleUnauthorized(writer, message)
+	})).ServeHTTP(writer, req)
+}
+
+// authenticate returns the caller holding a given credential: a JSON Web Token (when accepted), or an API key.
+func (r *RESTAPI) authenticate(req *http.Request, credential string) (types.Principal, error) {
+	if r.jwtService != nil && strings.Count(credential, ".") == jwtDots {
+		return r.jwtService.Authenticate(req.Context(), credential)
+	}
+
+	return r.apiKeyService.Authenticate(req.Context(), credential)
+}
+
+// require wraps the handler of a route with the scope it requires: callers without credentials get 401, and the
+// ones whose credentials lack the scope get 403. Authentication is disabled without an API key service.
+func (r *RESTAPI) require(scope types.Scope, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if r.apiKeyService == nil {
+			handler(writer, req)
+			return
+		}
+
+		principal, found := types.PrincipalFrom(req.Context())
+		if !found {
+			r.handleUnauthorized(writer, "missing credentials")
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			r.handleError(writer, "missing scope "+string(scope), http.StatusForbidden)
+			return
+		}
+
+		handler(writer, req)
+	})
+}
+
+// handleUnauthorized replies with 401, and tells how to authenticate.
+func (r *RESTAPI) handleUnauthorized(writer http.ResponseWriter, message string) {
+	writer.Header().Set("WWW-Authenticate", authenticateChallenge)
+	r.handleError(writer, message, http.StatusUnauthorized)
+}
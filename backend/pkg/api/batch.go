@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// Batch will apply put, delete and check operations on data entries, all or nothing.
+// @Summary      Batch will apply put, delete and check operations on data entries, all or nothing.
+// @Accept       json
+// @Produce      json
+// @Param        types.BatchInput	payload		string		true	"Request Body"
+// @Success      200		{object}	types.BatchOutput
+// @Failure      400		{object}	ErrorMessage
+// @Failure      403		{object}	types.BatchOutput
+// @Failure      404		{object}	types.BatchOutput
+// @Failure      412		{object}	types.BatchOutput
+// @Failure      413		{object}	types.BatchOutput
+// @Failure      429		{object}	types.BatchOutput
+// @Router       /data/batch	[post].
+func (r *RESTAPI) Batch(writer http.ResponseWriter, req *http.Request) {
+	input := types.BatchInput{}
+
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := r.dataService.Batch(req.Context(), input)
+	if errors.Is(err, types.ErrInvalidBatch) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	statusCode := http.StatusOK
+
+	switch {
+	case err == nil:
+	case errors.Is(err, types.ErrInvalidExpiry):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, types.ErrPreconditionFailed):
+		statusCode = http.StatusPreconditionFailed
+	case isNotFound(err):
+		statusCode = http.StatusNotFound
+	case isForbidden(err):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, types.ErrValueTooLarge):
+		statusCode = http.StatusRequestEntityTooLarge
+	case errors.Is(err, types.ErrQuotaExceeded):
+		statusCode = http.StatusTooManyRequests
+	default:
+		r.handleError(writer, "could not apply batch", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, types.BatchOutput{Committed: statusCode == http.StatusOK, Results: results}, uint(statusCode))
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// CheckIn claims the location with the ID in the path for the wallet in the body of a request, when the position in
+// the body is within the radius of the location, and enqueues a job minting the earned token; it replies with the
+// claim, and with the URL of the job in the Location header.
+//
+// A wallet checks in once at a location (409), from within its radius (403); the location must have an uploaded
+// image, which the token is made of (409).
+func (r *RESTAPI) CheckIn(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	input := types.CheckinInput{}
+
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.checkinService.CheckIn(req.Context(), id, input)
+
+	switch {
+	case isNotFound(err):
+		r.handleError(writer, "location not found", http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrTooFar):
+		r.handleError(writer, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrAlreadyClaimed), errors.Is(err, service.ErrNoTokenImage):
+		r.handleError(writer, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		r.handleMintingError(writer, err, "could not check in")
+		return
+	}
+
+	writer.Header().Set("Location", "/token/jobs/"+url.PathEscape(result.JobID))
+
+	err = writeJSON(writer, result, http.StatusAccepted)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// RequestLocationClaims replies with a page of the claims of the location with the ID in the path.
+//
+// Supports the limit, cursor, sort (created_at, distance or id) and order query parameters, and filtering by wallet.
+func (r *RESTAPI) RequestLocationClaims(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := listOptions(req, "wallet")
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.checkinService.ByLocation(req.Context(), id, opts)
+	r.writeClaims(writer, result, err)
+}
+
+// RequestWalletClaims replies with a page of the claims of the wallet in the path.
+//
+// Supports the limit, cursor, sort (created_at, distance or id) and order query parameters, and filtering by
+// location_id.
+func (r *RESTAPI) RequestWalletClaims(writer http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req, "location_id")
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.checkinService.ByWallet(req.Context(), req.PathValue("address"), opts)
+	r.writeClaims(writer, result, err)
+}
+
+// writeClaims replies with a page of claims, or with the error that kept it from being listed.
+func (r *RESTAPI) writeClaims(writer http.ResponseWriter, page types.Page[models.Claim], err error) {
+	if isInvalidListing(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve claims", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, page, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
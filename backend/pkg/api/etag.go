@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// weakPrefix marks a weak ETag.
+const weakPrefix = "W/"
+
+// formatETag returns the ETag of a given version of a data entry.
+func formatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// precondition reads the If-Match and If-None-Match headers of a request.
+func precondition(req *http.Request) types.Precondition {
+	return types.Precondition{
+		IfMatch:     parseETagCondition(req.Header.Values("If-Match"), false),
+		IfNoneMatch: parseETagCondition(req.Header.Values("If-None-Match"), true),
+	}
+}
+
+// parseETagCondition parses the values of an If-Match or If-None-Match header.
+//
+// Weak ETags only count when weak is true, as If-Match uses the strong comparison. ETags that are not ours can never
+// match, so they are skipped.
+func parseETagCondition(values []string, weak bool) types.ETagCondition {
+	condition := types.ETagCondition{}
+
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+
+			condition.Present = true
+
+			if tag == "*" {
+				condition.Any = true
+				continue
+			}
+
+			if strings.HasPrefix(tag, weakPrefix) {
+				if !weak {
+					continue
+				}
+
+				tag = strings.TrimPrefix(tag, weakPrefix)
+			}
+
+			unquoted, err := strconv.Unquote(tag)
+			if err != nil {
+				continue
+			}
+
+			version, err := strconv.ParseInt(unquoted, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			condition.Versions = append(condition.Versions, version)
+		}
+	}
+
+	return condition
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/geo"
+)
+
+const (
+	// defaultNearest is the number of locations found by GET /location/nearest when k is not given.
+	defaultNearest = 10
+	// maxNearest is the largest number of locations found by GET /location/nearest.
+	maxNearest = 100
+)
+
+var (
+	// errInvalidRadius for when the radius query parameter is not a distance in meters.
+	errInvalidRadius = fmt.Errorf("invalid radius, expected meters in (0, %.0f]", math.Floor(geo.MaxDistance))
+	// errInvalidCoordinates for when the lat or lon query parameter is not a coordinate in range.
+	errInvalidCoordinates = errors.New("invalid coordinates, expected lat in [-90, 90] and lon in [-180, 180]")
+	// errInvalidK for when the k query parameter is not a number of locations.
+	errInvalidK = fmt.Errorf("invalid k, expected a number in [1, %d]", maxNearest)
+	// errNearAndBBox for when a listing asks for both the locations near a point and the ones inside a box.
+	errNearAndBBox = errors.New("near and bbox cannot be combined")
+)
+
+// nearParameters reads the near (lat,lon) and radius (meters) query parameters.
+func nearParameters(query url.Values) (geo.Point, float64, error) {
+	center, err := geo.ParsePoint(query.Get("near"))
+	if err != nil {
+		return geo.Point{}, 0, fmt.Errorf("could not parse near: %w", err)
+	}
+
+	raw := query.Get("radius")
+
+	radius, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(radius > 0 && radius <= geo.MaxDistance) {
+		return geo.Point{}, 0, fmt.Errorf("%w: %q", errInvalidRadius, raw)
+	}
+
+	return center, radius, nil
+}
+
+// nearestParameters reads the lat, lon and k query parameters.
+func nearestParameters(query url.Values) (float64, float64, int, error) {
+	latitude, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+	longitude, lonErr := strconv.ParseFloat(query.Get("lon"), 64)
+
+	point := geo.Point{Latitude: latitude, Longitude: longitude}
+	if latErr != nil || lonErr != nil || !point.IsValid() {
+		return 0, 0, 0, fmt.Errorf("%w: %q,%q", errInvalidCoordinates, query.Get("lat"), query.Get("lon"))
+	}
+
+	k := defaultNearest
+
+	if raw := query.Get("k"); raw != "" {
+		var err error
+
+		k, err = strconv.Atoi(raw)
+		if err != nil || k <= 0 || k > maxNearest {
+			return 0, 0, 0, fmt.Errorf("%w: %q", errInvalidK, raw)
+		}
+	}
+
+	return latitude, longitude, k, nil
+}
+
+// isInvalidGeo tells whether an error was caused by the geospatial query parameters of the caller.
+func isInvalidGeo(err error) bool {
+	return errors.Is(err, geo.ErrInvalidPoint) ||
+		errors.Is(err, geo.ErrInvalidBBox) ||
+		errors.Is(err, errInvalidRadius) ||
+		errors.Is(err, errInvalidCoordinates) ||
+		errors.Is(err, errInvalidK) ||
+		errors.Is(err, errNearAndBBox)
+}
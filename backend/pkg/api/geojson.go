@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// formatGeoJSON asks for listings as GeoJSON feature collections.
+	formatGeoJSON = "geojson"
+	// contentTypeGeoJSON is the media type of GeoJSON (RFC 7946).
+	contentTypeGeoJSON = "application/geo+json"
+	// maxImportBytes bounds the size of the body of an import.
+	maxImportBytes = 32 << 20
+)
+
+var (
+	// errInvalidFormat for when the format query parameter is unknown.
+	errInvalidFormat = errors.New("invalid format, expected json or geojson")
+	// errInvalidDryRun for when the dry_run query parameter is not a boolean.
+	errInvalidDryRun = errors.New("invalid dry_run flag")
+)
+
+// ImportLocations upserts the points of a GeoJSON feature collection, and replies with the outcome of each.
+//
+// A feature with an ID (or an "id" property) replaces the location with this ID, one without is created; the
+// "location" and "image" properties are the name and the image. With dry_run=true, nothing is written.
+//
+// With format=csv, gpx or kml, the body is read as a stream of that format instead (see importStream).
+func (r *RESTAPI) ImportLocations(writer http.ResponseWriter, req *http.Request) {
+	dryRun, err := dryRunFlag(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if format := req.URL.Query().Get("format"); format != "" && format != formatGeoJSON {
+		r.importStream(writer, req, format, dryRun)
+		return
+	}
+
+	collection := types.FeatureCollection{}
+
+	err = json.NewDecoder(http.MaxBytesReader(writer, req.Body, maxImportBytes)).Decode(&collection)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if collection.Type != types.GeoJSONFeatureCollection || len(collection.Features) > types.MaxImportFeatures {
+		message := fmt.Sprintf("%s: expected a %s of at most %d features",
+			types.ErrInvalidImport, types.GeoJSONFeatureCollection, types.MaxImportFeatures)
+		r.handleError(writer, message, http.StatusBadRequest)
+
+		return
+	}
+
+	output := types.ImportOutput{DryRun: dryRun, Results: make([]types.ImportResult, 0, len(collection.Features))}
+
+	for index, feature := range collection.Features {
+		result, err := r.importFeature(req, feature, dryRun)
+		if err != nil {
+			log.Default().Printf("could not import feature %d: %s", index, err)
+			r.handleError(writer, fmt.Sprintf("could not import feature %d", index), http.StatusInternalServerError)
+
+			return
+		}
+
+		result.Index = index
+		output.Add(result)
+	}
+
+	err = writeJSON(writer, output, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// importFeature imports a single feature, and returns its outcome.
+func (r *RESTAPI) importFeature(req *http.Request, feature types.Feature, dryRun bool) (types.ImportResult, error) {
+	input, err := feature.LocationInput()
+
+	var invalid *types.ValidationError
+	if errors.As(err, &invalid) {
+		return types.ImportResult{
+			Outcome: types.ImportInvalid, Error: types.ErrInvalidInput.Error(), Fields: invalid.Fields,
+		}, nil
+	}
+
+	return r.locationService.Import(req.Context(), input, dryRun)
+}
+
+// dryRunFlag reads the dry_run query parameter; false when it is not set.
+func dryRunFlag(req *http.Request) (bool, error) {
+	if !req.URL.Query().Has("dry_run") {
+		return false, nil
+	}
+
+	result, err := strconv.ParseBool(req.URL.Query().Get("dry_run"))
+	if err != nil {
+		return false, errInvalidDryRun
+	}
+
+	return result, nil
+}
+
+// featureCollection returns a page of locations as a GeoJSON feature collection.
+//
+// Locations with their distance from a point also get a "distance" property.
+func featureCollection(page any) types.FeatureCollection {
+	result := types.FeatureCollection{Type: types.GeoJSONFeatureCollection, Features: []types.Feature{}}
+
+	switch typed := page.(type) {
+	case types.Page[models.Location]:
+		for _, location := range typed.Items {
+			result.Features = append(result.Features, types.LocationFeature(location))
+		}
+
+		result.NextCursor, result.Total = typed.NextCursor, &typed.Total
+	case types.Page[models.LocationDistance]:
+		for _, location := range typed.Items {
+			feature := types.LocationFeature(location.Location)
+			feature.Properties["distance"] = location.Distance
+			result.Features = append(result.Features, feature)
+		}
+
+		result.NextCursor, result.Total = typed.NextCursor, &typed.Total
+	}
+
+	return result
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// ShareEntry grants the principal in the path the access in the body of a request (read or write) to the data entry
+// with the key in the path, and replies with the entry. Only its owner, or an admin, may share it.
+func (r *RESTAPI) ShareEntry(writer http.ResponseWriter, req *http.Request) {
+	r.share(writer, req, "entry", func(ctx context.Context, principalID string, access string) (any, error) {
+		return r.dataService.Share(ctx, req.PathValue("key"), principalID, access)
+	})
+}
+
+// UnshareEntry takes back the access granted to the principal in the path to the data entry with the key in the path.
+func (r *RESTAPI) UnshareEntry(writer http.ResponseWriter, req *http.Request) {
+	r.unshare(writer, req, "entry", func(ctx context.Context, principalID string) error {
+		_, err := r.dataService.Unshare(ctx, req.PathValue("key"), principalID)
+
+		return err
+	})
+}
+
+// ShareLocation grants the principal in the path write access to the location with the ID in the path, and replies
+// with the location. Locations can be read by everyone, so the body must ask for write access.
+func (r *RESTAPI) ShareLocation(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.share(writer, req, "location", func(ctx context.Context, principalID string, access string) (any, error) {
+		return r.locationService.Share(ctx, id, principalID, access)
+	})
+}
+
+// UnshareLocation takes back the access granted to the principal in the path to the location with the ID in the path.
+func (r *RESTAPI) UnshareLocation(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.unshare(writer, req, "location", func(ctx context.Context, principalID string) error {
+		_, err := r.locationService.Unshare(ctx, id, principalID)
+
+		return err
+	})
+}
+
+// share applies the grant in the body of a request to the principal in its path, through grant, and replies with the
+// shared item (an entry or a location, as what tells).
+func (r *RESTAPI) share(
+	writer http.ResponseWriter,
+	req *http.Request,
+	what string,
+	grant func(ctx context.Context, principalID string, access string) (any, error),
+) {
+	input := types.GrantInput{}
+
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := grant(req.Context(), req.PathValue("principal"), input.Access)
+	if r.handleInvalidInput(writer, err) || r.handleShareError(writer, what, err) {
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// unshare takes back the grant of the principal in the path of a request, through revoke.
+func (r *RESTAPI) unshare(
+	writer http.ResponseWriter,
+	req *http.Request,
+	what string,
+	revoke func(ctx context.Context, principalID string) error,
+) {
+	err := revoke(req.Context(), req.PathValue("principal"))
+	if r.handleShareError(writer, what, err) {
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// handleShareError replies with the error of sharing an item, and tells whether it did: when err is not nil.
+func (r *RESTAPI) handleShareError(writer http.ResponseWriter, what string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case isNotFound(err):
+		r.handleError(writer, what+" not found", http.StatusNotFound)
+	case isForbidden(err):
+		r.handleError(writer, "only the owner may share this "+what, http.StatusForbidden)
+	default:
+		r.handleError(writer, "could not share "+what, http.StatusInternalServerError)
+	}
+
+	return true
+}
@@ -5,9 +5,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/wakka-2/Namless/backend/pkg/repository"
 	"github.com/wakka-2/Namless/backend/pkg/service"
 	"github.com/wakka-2/Namless/backend/pkg/types"
 )
@@ -16,34 +20,100 @@ import (
 type RESTAPI struct {
 	dataService     *service.Data
 	locationService *service.Location
+	tokenService    *service.Token
+	// idempotencyService records the responses replayed by the Idempotency middleware.
+	idempotencyService *service.Idempotency
+	checkinService     *service.Checkin
+	// apiKeyService authenticates callers; nil when authentication is disabled.
+	apiKeyService *service.APIKey
+	// jwtService authenticates callers with tokens issued by another service; nil when tokens are not accepted.
+	jwtService *service.JWT
+	// rateLimits limits the rate of requests; nil when they are not limited.
+	rateLimits *RateLimits
 }
 
-// New builds a new REST API.
+// New builds a new REST API; a nil apiKeyService disables authentication, and leaves every route open, while a nil
+// jwtService only refuses tokens, and nil rateLimits let every request through.
 func New(
 	dataService *service.Data,
 	locationService *service.Location,
+	tokenService *service.Token,
+	idempotencyService *service.Idempotency,
+	checkinService *service.Checkin,
+	apiKeyService *service.APIKey,
+	jwtService *service.JWT,
+	rateLimits *RateLimits,
 ) *RESTAPI {
 	return &RESTAPI{
-		dataService:     dataService,
-		locationService: locationService,
+		dataService:        dataService,
+		locationService:    locationService,
+		tokenService:       tokenService,
+		idempotencyService: idempotencyService,
+		checkinService:     checkinService,
+		apiKeyService:      apiKeyService,
+		jwtService:         jwtService,
+		rateLimits:         rateLimits,
 	}
 }
 
 // BuildMultiplexer adds handlers and middleware to the resulting multiplexer.
+//
+// Every route declares the scope it requires (see require); locations, their images and their claims can be read by
+// anyone.
 func (r *RESTAPI) BuildMultiplexer() http.Handler {
 	multiplexer := http.NewServeMux()
 
-	multiplexer.Handle("GET /data/{key}", http.HandlerFunc(r.Request))
-	multiplexer.Handle("POST /data", http.HandlerFunc(r.Create))
-	multiplexer.Handle("PUT /data", http.HandlerFunc(r.Update))
-	multiplexer.Handle("DELETE /data/{key}", http.HandlerFunc(r.Delete))
+	// the data routes are served in the default namespace (or the one of the X-Namespace header), and under /ns/...
+	for _, prefix := range []string{"", "/ns/{namespace}"} {
+		data := func(pattern string, scope types.Scope, handler http.HandlerFunc) {
+			method, path, _ := strings.Cut(pattern, " ")
+			multiplexer.Handle(method+" "+prefix+path, r.require(scope, r.namespaced(handler)))
+		}
+
+		data("GET /data", types.ScopeDataRead, r.RequestAll)
+		data("GET /data/{key}", types.ScopeDataRead, r.Request)
+		data("GET /data/_trash", types.ScopeDataRead, r.RequestTrash)
+		data("GET /data/_usage", types.ScopeDataRead, r.RequestUsage)
+		data("GET /data/{key}/history", types.ScopeDataRead, r.RequestHistory)
+		data("POST /data/{key}/revert", types.ScopeDataWrite, r.Revert)
+		data("POST /data/{key}/restore", types.ScopeDataWrite, r.Restore)
+		data("POST /data", types.ScopeDataWrite, r.Create)
+		data("POST /data/batch", types.ScopeDataWrite, r.Batch)
+		data("PUT /data", types.ScopeDataWrite, r.Update)
+		data("DELETE /data/{key}", types.ScopeDataWrite, r.Delete)
+		data("PUT /data/{key}/grants/{principal}", types.ScopeDataWrite, r.ShareEntry)
+		data("DELETE /data/{key}/grants/{principal}", types.ScopeDataWrite, r.UnshareEntry)
+	}
+
+	multiplexer.Handle("GET /location/nearest", http.HandlerFunc(r.RequestNearestLocations))
 	multiplexer.Handle("GET /location/{id}", http.HandlerFunc(r.RequestLocation))
 	multiplexer.Handle("GET /location", http.HandlerFunc(r.RequestAllLocations))
-	multiplexer.Handle("POST /location", http.HandlerFunc(r.CreateLocation))
-	multiplexer.Handle("POST /token", http.HandlerFunc(r.CreateToken))
-	multiplexer.Handle("GET /two/{name}", http.HandlerFunc(r.CreateToken2))
+	multiplexer.Handle("POST /location", r.require(types.ScopeLocationWrite, r.CreateLocation))
+	multiplexer.Handle("POST /location/import", r.require(types.ScopeLocationWrite, r.ImportLocations))
+	multiplexer.Handle("GET /location/export", http.HandlerFunc(r.ExportLocations))
+	multiplexer.Handle("PUT /location/{id}", r.require(types.ScopeLocationWrite, r.ReplaceLocation))
+	multiplexer.Handle("PATCH /location/{id}", r.require(types.ScopeLocationWrite, r.PatchLocation))
+	multiplexer.Handle("DELETE /location/{id}", r.require(types.ScopeLocationWrite, r.DeleteLocation))
+	multiplexer.Handle("POST /location/{id}/image", r.require(types.ScopeLocationWrite, r.UploadLocationImage))
+	multiplexer.Handle("PUT /location/{id}/grants/{principal}", r.require(types.ScopeLocationWrite, r.ShareLocation))
+	multiplexer.Handle("DELETE /location/{id}/grants/{principal}", r.require(types.ScopeLocationWrite, r.UnshareLocation))
+	multiplexer.Handle("POST /location/{id}/checkin", r.require(types.ScopeTokenMint, r.CheckIn))
+	multiplexer.Handle("GET /location/{id}/claims", http.HandlerFunc(r.RequestLocationClaims))
+	multiplexer.Handle("GET /wallet/{address}/claims", http.HandlerFunc(r.RequestWalletClaims))
+	multiplexer.Handle("GET /blobs/{hash}", http.HandlerFunc(r.RequestBlob))
+	multiplexer.Handle("POST /token", r.require(types.ScopeTokenMint, r.CreateToken))
+	multiplexer.Handle("GET /token/{uid}", r.require(types.ScopeTokenMint, r.RequestToken))
+	multiplexer.Handle("GET /token/jobs/{id}", r.require(types.ScopeTokenMint, r.RequestMintJob))
+	multiplexer.Handle("POST /token/{uid}/mint", r.require(types.ScopeTokenMint, r.MintToken))
+	multiplexer.Handle("GET /two/{name}", r.require(types.ScopeTokenMint, r.CreateToken2))
+
+	if r.apiKeyService != nil {
+		multiplexer.Handle("POST /admin/keys", r.require(types.ScopeAdmin, r.IssueAPIKey))
+		multiplexer.Handle("GET /admin/keys", r.require(types.ScopeAdmin, r.RequestAPIKeys))
+		multiplexer.Handle("DELETE /admin/keys/{id}", r.require(types.ScopeAdmin, r.RevokeAPIKey))
+	}
 
-	return RecoverMiddleware(EnableCORS(multiplexer))
+	return RecoverMiddleware(EnableCORS(r.Authenticate(r.RateLimit(r.Idempotency(multiplexer)))))
 }
 
 // Create will create a new data entry.
@@ -53,6 +123,9 @@ func (r *RESTAPI) BuildMultiplexer() http.Handler {
 // @Param        models.Data	payload		string				true	"Request Body"
 // @Success      200		{object}	string
 // @Failure      400		{object}	ErrorMessage
+// @Failure      409		{object}	ErrorMessage
+// @Failure      413		{object}	ErrorMessage
+// @Failure      429		{object}	ErrorMessage
 // @Router       /create	[post].
 //
 //nolint:dupl
@@ -65,11 +138,27 @@ func (r *RESTAPI) Create(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = r.dataService.Add(req.Context(), input.Key, input.Value)
+	result, err := r.dataService.Add(req.Context(), input)
+	if errors.Is(err, types.ErrInvalidExpiry) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.handleQuotaError(writer, req, err) {
+		return
+	}
+
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		r.handleError(writer, "entry already exists", http.StatusConflict)
+		return
+	}
+
 	if err != nil {
 		r.handleError(writer, "could not create entry", http.StatusInternalServerError)
 		return
 	}
+
+	writer.Header().Set("ETag", formatETag(result.Version))
 }
 
 // Request will retrieve the data entry with a given key.
@@ -77,7 +166,11 @@ func (r *RESTAPI) Create(writer http.ResponseWriter, req *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        key		path		string				true	"Request Path"
+// @Param        version	query		int					false	"Value at this version"
+// @Param        at			query		string				false	"Value at this moment (RFC 3339)"
+// @Param        If-None-Match	header	string				false	"ETags the client already has"
 // @Success      200		{object}	string
+// @Success      304
 // @Failure      400		{object}	ErrorMessage
 // @Router       /create	[post].
 func (r *RESTAPI) Request(writer http.ResponseWriter, req *http.Request) {
@@ -87,25 +180,93 @@ func (r *RESTAPI) Request(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	result, err := r.dataService.Get(req.Context(), key)
+	result, version, err := r.requestValue(req, key)
+	if errors.Is(err, errInvalidPointInTime) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isNotFound(err) {
+		r.handleError(writer, "entry not found", http.StatusNotFound)
+		return
+	}
+
 	if err != nil {
 		r.handleError(writer, "could not retrieve entry", http.StatusInternalServerError)
 		return
 	}
 
+	writer.Header().Set("ETag", formatETag(version))
+
+	if precondition(req).IfNoneMatch.Matches(true, version) {
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	err = write(writer, []byte(result), http.StatusOK)
 	if err != nil {
 		log.Default().Printf("could not write: %s", err)
 	}
 }
 
+// RequestAll will retrieve a page of data entries.
+// @Summary      RequestAll will retrieve a page of data entries.
+// @Produce      json
+// @Param        limit		query		int					false	"Page size"
+// @Param        cursor		query		string				false	"next_cursor of the previous page"
+// @Param        sort		query		string				false	"id, created_at or updated_at"
+// @Param        order		query		string				false	"asc or desc"
+// @Param        value		query		string				false	"Only entries with this value"
+// @Param        prefix		query		string				false	"Only keys starting with this prefix"
+// @Param        start		query		string				false	"Only keys after this one, inclusive"
+// @Param        end		query		string				false	"Only keys before this one, exclusive"
+// @Success      200		{object}	types.Page[models.Data]
+// @Failure      400		{object}	ErrorMessage
+// @Router       /data	[get].
+func (r *RESTAPI) RequestAll(writer http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req, "value")
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts.Range = types.KeyRange{
+		Prefix: req.URL.Query().Get("prefix"),
+		Start:  req.URL.Query().Get("start"),
+		End:    req.URL.Query().Get("end"),
+	}
+
+	result, err := r.dataService.GetAll(req.Context(), opts)
+	if isInvalidListing(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve entries", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
 // Update will update an existing data entry.
 // @Summary      Update will update an existing data entry.
 // @Accept       json
 // @Produce      json
 // @Param        models.Data	payload		string				true	"Request Body"
-// @Success      200		{object}	string
+// @Param        If-Match		header		string				false	"Only update the entry at one of these ETags"
+// @Param        If-None-Match	header		string				false	"* to only create the entry"
+// @Success      201		{object}	string
 // @Failure      400		{object}	ErrorMessage
+// @Failure      403		{object}	ErrorMessage
+// @Failure      404		{object}	ErrorMessage
+// @Failure      412		{object}	ErrorMessage
+// @Failure      413		{object}	ErrorMessage
+// @Failure      429		{object}	ErrorMessage
 // @Router       /create	[post].
 //
 //nolint:dupl
@@ -118,12 +279,37 @@ func (r *RESTAPI) Update(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = r.dataService.Update(req.Context(), input.Key, input.Value)
+	result, err := r.dataService.Update(req.Context(), input, precondition(req))
+	if errors.Is(err, types.ErrInvalidExpiry) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.handleQuotaError(writer, req, err) {
+		return
+	}
+
+	if errors.Is(err, types.ErrPreconditionFailed) {
+		r.handleError(writer, "entry does not match the precondition", http.StatusPreconditionFailed)
+		return
+	}
+
+	if isNotFound(err) {
+		r.handleError(writer, "entry not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this entry", http.StatusForbidden)
+		return
+	}
+
 	if err != nil {
 		r.handleError(writer, "could not update entry", http.StatusInternalServerError)
 		return
 	}
 
+	writer.Header().Set("ETag", formatETag(result.Version))
 	writer.WriteHeader(http.StatusCreated)
 }
 
@@ -132,8 +318,13 @@ func (r *RESTAPI) Update(writer http.ResponseWriter, req *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        key		path		string				true	"Request Path"
-// @Success      200		{object}	string
+// @Param        purge		query		bool				false	"Delete the entry for good, even from the trash"
+// @Param        If-Match		header		string				false	"Only delete the entry at one of these ETags"
+// @Success      204
 // @Failure      400		{object}	ErrorMessage
+// @Failure      403		{object}	ErrorMessage
+// @Failure      404		{object}	ErrorMessage
+// @Failure      412		{object}	ErrorMessage
 // @Router       /create	[post].
 func (r *RESTAPI) Delete(writer http.ResponseWriter, req *http.Request) {
 	key := req.PathValue("key")
@@ -142,7 +333,41 @@ func (r *RESTAPI) Delete(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.dataService.Delete(req.Context(), key)
+	purge := false
+
+	if req.URL.Query().Has("purge") {
+		var err error
+
+		purge, err = strconv.ParseBool(req.URL.Query().Get("purge"))
+		if err != nil {
+			r.handleError(writer, "invalid purge flag", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var err error
+
+	if purge {
+		err = r.dataService.Purge(req.Context(), key, precondition(req))
+	} else {
+		err = r.dataService.Delete(req.Context(), key, precondition(req))
+	}
+
+	if errors.Is(err, types.ErrPreconditionFailed) {
+		r.handleError(writer, "entry does not match the precondition", http.StatusPreconditionFailed)
+		return
+	}
+
+	if isNotFound(err) {
+		r.handleError(writer, "entry not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this entry", http.StatusForbidden)
+		return
+	}
+
 	if err != nil {
 		r.handleError(writer, "could not delete entry", http.StatusInternalServerError)
 		return
@@ -151,6 +376,32 @@ func (r *RESTAPI) Delete(writer http.ResponseWriter, req *http.Request) {
 	writer.WriteHeader(http.StatusNoContent)
 }
 
+// isNotFound tells whether an error was caused by a missing item.
+func isNotFound(err error) bool {
+	return errors.Is(err, repository.ErrDoesNotExist)
+}
+
+// isForbidden tells whether an error was caused by a caller changing an item it may only read.
+func isForbidden(err error) bool {
+	return errors.Is(err, types.ErrForbidden)
+}
+
+// handleInvalidInput replies with the field errors of err, and tells whether it did: when err holds a
+// types.ValidationError.
+func (r *RESTAPI) handleInvalidInput(w http.ResponseWriter, err error) bool {
+	var invalid *types.ValidationError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+
+	err = writeJSON(w, ErrorMessage{Error: types.ErrInvalidInput.Error(), Fields: invalid.Fields}, http.StatusBadRequest)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+
+	return true
+}
+
 // handleError wraps an error message in a struct and sends it.
 func (r *RESTAPI) handleError(w http.ResponseWriter, message string, statusCode uint) {
 	err := writeJSON(w, ErrorMessage{Error: message}, statusCode)
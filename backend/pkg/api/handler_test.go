@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+)
+
+func Test_StatusCodes(t *testing.T) {
+	handler := newTestAPI(t).BuildMultiplexer()
+
+	cases := []struct {
+		method, target, body string
+		status               int
+	}{
+		{method: http.MethodPost, target: "/data", body: `{"Key": "a", "Value": "1"}`, status: http.StatusOK},
+		{method: http.MethodPost, target: "/data", body: `{"Key": `, status: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/data/a", status: http.StatusOK},
+		{method: http.MethodPut, target: "/data", body: `{"Key": "a", "Value": "2"}`, status: http.StatusCreated},
+		{method: http.MethodPut, target: "/data", body: `[]`, status: http.StatusBadRequest},
+		{method: http.MethodDelete, target: "/data/a", status: http.StatusNoContent},
+		{
+			method: http.MethodPost, target: "/location", status: http.StatusCreated,
+			body: `{"location": "Paris", "latitude": 48.86, "longitude": 2.35}`,
+		},
+		{method: http.MethodPost, target: "/location", body: `{"latitude": "north"}`, status: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/location", status: http.StatusOK},
+		{method: http.MethodGet, target: "/location/paris", status: http.StatusBadRequest},
+	}
+
+	for _, test := range cases {
+		req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+		assert.Equal(t, test.status, response.Code, "%s %s", test.method, test.target)
+
+		// errors go out with their status code, and a message
+		if test.status >= http.StatusBadRequest {
+			var message ErrorMessage
+
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&message))
+			assert.NotEmpty(t, message.Error)
+		}
+	}
+}
+
+// newTestAPI builds a REST API keeping everything in memory.
+func newTestAPI(t *testing.T) *RESTAPI {
+	t.Helper()
+
+	ctx := context.Background()
+
+	locations, err := service.NewLocation(ctx, repository.NewMemoryLocation(), blob.NewMemory())
+	require.NoError(t, err)
+
+	tokens := service.NewToken(ctx, minting.NewFake(), repository.NewMemoryMintJob())
+
+	idempotency := service.NewIdempotency(ctx, repository.NewMemoryIdempotency(), time.Hour)
+	checkins := service.NewCheckin(ctx, locations, tokens, repository.NewMemoryClaim(), 100)
+
+	data := service.New(ctx, repository.NewMemory(), service.Quotas{})
+
+	return New(data, locations, tokens, idempotency, checkins, nil, nil, nil)
+}
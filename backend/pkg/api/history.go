@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+var (
+	// errInvalidPointInTime for when a read asks for a malformed version or moment, or for both.
+	errInvalidPointInTime = errors.New("expected either a version number or an RFC 3339 moment")
+)
+
+// RequestHistory will retrieve a page of the revisions of the data entry with a given key.
+// @Summary      RequestHistory will retrieve a page of the revisions of the data entry with a given key.
+// @Produce      json
+// @Param        key		path		string				true	"Request Path"
+// @Param        limit		query		int					false	"Page size"
+// @Param        cursor		query		string				false	"next_cursor of the previous page"
+// @Param        order		query		string				false	"asc or desc"
+// @Success      200		{object}	types.Page[models.DataVersion]
+// @Failure      400		{object}	ErrorMessage
+// @Failure      404		{object}	ErrorMessage
+// @Router       /data/{key}/history	[get].
+func (r *RESTAPI) RequestHistory(writer http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.dataService.History(req.Context(), req.PathValue("key"), opts)
+	if isInvalidListing(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isNotFound(err) {
+		r.handleError(writer, "entry not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve revisions", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// Revert will set the data entry with a given key back to the value it had at a given version.
+// @Summary      Revert will set the data entry with a given key back to the value it had at a given version.
+// @Accept       json
+// @Param        key		path		string				true	"Request Path"
+// @Param        types.RevertInput	payload		string		true	"Request Body"
+// @Success      204
+// @Failure      400		{object}	ErrorMessage
+// @Failure      403		{object}	ErrorMessage
+// @Failure      404		{object}	ErrorMessage
+// @Failure      409		{object}	ErrorMessage
+// @Failure      413		{object}	ErrorMessage
+// @Failure      429		{object}	ErrorMessage
+// @Router       /data/{key}/revert	[post].
+func (r *RESTAPI) Revert(writer http.ResponseWriter, req *http.Request) {
+	input := types.RevertInput{}
+
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.dataService.Revert(req.Context(), req.PathValue("key"), input.Version)
+	if errors.Is(err, types.ErrPreconditionFailed) {
+		r.handleError(writer, "entry changed while reverting", http.StatusConflict)
+		return
+	}
+
+	if r.handleQuotaError(writer, req, err) {
+		return
+	}
+
+	if isNotFound(err) {
+		r.handleError(writer, "entry or version not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this entry", http.StatusForbidden)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not revert entry", http.StatusInternalServerError)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// requestValue returns the current value of a data entry, or the one at the version or moment asked for, along with
+// its version.
+func (r *RESTAPI) requestValue(req *http.Request, key string) (string, int64, error) {
+	query := req.URL.Query()
+
+	switch {
+	case query.Has("version") && query.Has("at"):
+		return "", 0, errInvalidPointInTime
+	case query.Has("version"):
+		version, err := strconv.ParseInt(query.Get("version"), 10, 64)
+		if err != nil {
+			return "", 0, errInvalidPointInTime
+		}
+
+		result, err := r.dataService.GetVersion(req.Context(), key, version)
+
+		return result.Value, result.Version, err
+	case query.Has("at"):
+		at, err := time.Parse(time.RFC3339Nano, query.Get("at"))
+		if err != nil {
+			return "", 0, errInvalidPointInTime
+		}
+
+		result, err := r.dataService.GetAt(req.Context(), key, at)
+
+		return result.Value, result.Version, err
+	}
+
+	result, err := r.dataService.Get(req.Context(), key)
+
+	return result.Value, result.Version, err
+}
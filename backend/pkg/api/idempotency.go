@@ -0,0 +1,248 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// idempotencyHeader holds the key that makes a request idempotent.
+	idempotencyHeader = "Idempotency-Key"
+	// replayedHeader marks the responses that were replayed.
+	replayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKey is the largest number of characters in an idempotency key.
+	maxIdempotencyKey = 255
+	// maxReplayedBody bounds the responses kept for replay; the keys of larger ones are released.
+	maxReplayedBody = 1 << 20
+	// maxFingerprintedBody bounds the request bodies that can be fingerprinted; the keys of larger ones are released.
+	maxFingerprintedBody = 64 << 20
+)
+
+// idempotentMethods are the methods that honour idempotency keys.
+var idempotentMethods = map[string]bool{http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true}
+
+// Idempotency middleware makes POST, PUT and PATCH requests with an Idempotency-Key header safe to retry.
+//
+// The response to the first request with a key is recorded (unless it is a server error), and replayed to the next
+// ones during the replay window, with an Idempotent-Replayed header. A request sent while the first one is in flight
+// gets 409, and one whose method, target or body differ from the first one gets 422.
+func (r *RESTAPI) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(idempotencyHeader)
+		if key == "" || !idempotentMethods[req.Method] {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		if !isIdempotencyKey(key) {
+			r.handleError(writer, "invalid Idempotency-Key, expected 1 to 255 visible ASCII characters", http.StatusBadRequest)
+			return
+		}
+
+		// keys are per caller, so that one cannot get the responses of another
+		if principal, found := types.PrincipalFrom(req.Context()); found {
+			key = principal.ID + ":" + key
+		}
+
+		// the namespace header picks the target as much as the path does
+		target := req.URL.RequestURI()
+		if namespace := req.Header.Get(namespaceHeader); namespace != "" {
+			target += " " + namespaceHeader + ": " + namespace
+		}
+
+		record, err := r.idempotencyService.Begin(req.Context(), key, req.Method, target)
+
+		switch {
+		case errors.Is(err, service.ErrIdempotencyInFlight):
+			writer.Header().Set("Retry-After", "1")
+			r.handleError(writer, err.Error(), http.StatusConflict)
+		case errors.Is(err, service.ErrIdempotencyMismatch):
+			r.handleError(writer, err.Error(), http.StatusUnprocessableEntity)
+		case err != nil:
+			log.Default().Printf("could not check Idempotency-Key: %s", err)
+			r.handleError(writer, "could not check Idempotency-Key", http.StatusInternalServerError)
+		case record.IsInFlight():
+			r.recordResponse(writer, req, next, record)
+		default:
+			r.replayResponse(writer, req, record)
+		}
+	})
+}
+
+// recordResponse serves the first request with a key, and records its response.
+//
+// The body of the request is fingerprinted as the handler reads it; whatever it leaves is read before the response
+// is written, since it may not be readable afterwards.
+func (r *RESTAPI) recordResponse(
+	writer http.ResponseWriter,
+	req *http.Request,
+	next http.Handler,
+	record models.IdempotencyRecord,
+) {
+	// the outcome is recorded even when the client is gone
+	ctx := context.WithoutCancel(req.Context())
+	recorder := &responseRecorder{ResponseWriter: writer, body: req.Body, hash: sha256.New()}
+	recorded := false
+
+	// a panicking handler leaves the key free for a retry
+	defer func() {
+		if !recorded {
+			r.releaseKey(ctx, record)
+		}
+	}()
+
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{Reader: io.TeeReader(req.Body, recorder.hash), Closer: req.Body}
+
+	next.ServeHTTP(recorder, req)
+	recorder.drain()
+
+	recorded = true
+
+	if recorder.status >= http.StatusInternalServerError || recorder.truncated {
+		r.releaseKey(ctx, record)
+		return
+	}
+
+	// a handler writing nothing gets an implicit 200, with the headers it set
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+		recorder.header = writer.Header().Clone()
+	}
+
+	record.StatusCode = recorder.status
+	record.Header = recorder.header
+	record.Body = recorder.response.Bytes()
+	record.Fingerprint = hex.EncodeToString(recorder.hash.Sum(nil))
+
+	err := r.idempotencyService.Complete(ctx, record)
+	if err != nil {
+		log.Default().Printf("could not record the response for Idempotency-Key %q: %s", record.Key, err)
+	}
+}
+
+// replayResponse replays the recorded response to a request, when its body is the one of the first request.
+func (r *RESTAPI) replayResponse(writer http.ResponseWriter, req *http.Request, record models.IdempotencyRecord) {
+	fingerprint := sha256.New()
+
+	read, err := io.Copy(fingerprint, io.LimitReader(req.Body, maxFingerprintedBody+1))
+	if err != nil {
+		r.handleError(writer, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	if read > maxFingerprintedBody || hex.EncodeToString(fingerprint.Sum(nil)) != record.Fingerprint {
+		r.handleError(writer, service.ErrIdempotencyMismatch.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	for name, values := range record.Header {
+		writer.Header()[name] = values
+	}
+
+	writer.Header().Set(replayedHeader, "true")
+	writer.WriteHeader(record.StatusCode)
+
+	_, err = writer.Write(record.Body)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// releaseKey frees an idempotency key, so that the request can be sent again.
+func (r *RESTAPI) releaseKey(ctx context.Context, record models.IdempotencyRecord) {
+	err := r.idempotencyService.Release(ctx, record)
+	if err != nil {
+		log.Default().Printf("could not release Idempotency-Key %q: %s", record.Key, err)
+	}
+}
+
+// isIdempotencyKey tells whether a key is made of 1 to 255 visible ASCII characters.
+func isIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKey {
+		return false
+	}
+
+	for _, character := range []byte(key) {
+		if character <= ' ' || character > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// responseRecorder passes a response on, and keeps a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	// body is the body of the request, whose rest is fingerprinted before the response is written.
+	body io.Reader
+	hash hash.Hash
+	// status and header are the ones written; zero and nil until then.
+	status   int
+	header   http.Header
+	response bytes.Buffer
+	// truncated is true when the request or the response were too large to be recorded.
+	truncated bool
+	drained   bool
+}
+
+// WriteHeader fingerprints the rest of the request body, keeps the status and the headers, and writes them.
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if rr.status != 0 {
+		return
+	}
+
+	rr.drain()
+
+	rr.status = statusCode
+	rr.header = rr.ResponseWriter.Header().Clone()
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write keeps a copy of (up to maxReplayedBody bytes of) the body, and writes it.
+func (rr *responseRecorder) Write(content []byte) (int, error) {
+	if rr.status == 0 {
+		rr.WriteHeader(http.StatusOK)
+	}
+
+	if rr.response.Len()+len(content) > maxReplayedBody {
+		rr.truncated = true
+	} else {
+		rr.response.Write(content)
+	}
+
+	return rr.ResponseWriter.Write(content)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// drain fingerprints what the handler left of the request body.
+func (rr *responseRecorder) drain() {
+	if rr.drained {
+		return
+	}
+
+	rr.drained = true
+
+	read, err := io.Copy(rr.hash, io.LimitReader(rr.body, maxFingerprintedBody+1))
+	if err != nil || read > maxFingerprintedBody {
+		rr.truncated = true
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+)
+
+func Test_Idempotency(t *testing.T) {
+	var calls atomic.Int32
+
+	handler := newIdempotentAPI().Idempotency(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+
+		writer.Header().Set("Location", "/data/"+string(body))
+		writer.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(writer, "call %d", calls.Add(1))
+	}))
+
+	first := sendIdempotent(handler, http.MethodPost, "/data", "key-1", "a")
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "call 1", first.Body.String())
+	assert.Empty(t, first.Header().Get(replayedHeader))
+
+	// retries get the first response
+	replayed := sendIdempotent(handler, http.MethodPost, "/data", "key-1", "a")
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "call 1", replayed.Body.String())
+	assert.Equal(t, "/data/a", replayed.Header().Get("Location"))
+	assert.Equal(t, "true", replayed.Header().Get(replayedHeader))
+
+	// a key is not reused for another request
+	assert.Equal(t, http.StatusUnprocessableEntity, sendIdempotent(handler, http.MethodPost, "/data", "key-1", "b").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, sendIdempotent(handler, http.MethodPut, "/data", "key-1", "a").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, sendIdempotent(handler, http.MethodPost, "/data/a", "key-1", "a").Code)
+
+	// other keys, requests without a key and invalid keys
+	assert.Equal(t, "call 2", sendIdempotent(handler, http.MethodPost, "/data", "key-2", "a").Body.String())
+	assert.Equal(t, "call 3", sendIdempotent(handler, http.MethodPost, "/data", "", "a").Body.String())
+	assert.Equal(t, "call 4", sendIdempotent(handler, http.MethodPost, "/data", "", "a").Body.String())
+	assert.Equal(t, http.StatusBadRequest, sendIdempotent(handler, http.MethodPost, "/data", "key 3", "a").Code)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func Test_IdempotencyInFlight(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+
+	handler := newIdempotentAPI().Idempotency(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-finish
+		writer.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+
+	go func() {
+		done <- sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a")
+	}()
+
+	<-started
+
+	conflict := sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a")
+	assert.Equal(t, http.StatusConflict, conflict.Code)
+	assert.Equal(t, "1", conflict.Header().Get("Retry-After"))
+
+	close(finish)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+	assert.Equal(t, http.StatusCreated, sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a").Code)
+}
+
+func Test_IdempotencyServerError(t *testing.T) {
+	var calls atomic.Int32
+
+	handler := newIdempotentAPI().Idempotency(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		writer.WriteHeader(http.StatusCreated)
+	}))
+
+	// server errors are not recorded, so that they can be retried
+	assert.Equal(t, http.StatusBadGateway, sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a").Code)
+	assert.Equal(t, http.StatusCreated, sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a").Code)
+	assert.Equal(t, http.StatusCreated, sendIdempotent(handler, http.MethodPost, "/token", "key-1", "a").Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+// newIdempotentAPI builds a REST API recording idempotent requests in memory.
+func newIdempotentAPI() *RESTAPI {
+	idempotencyService := service.NewIdempotency(context.Background(), repository.NewMemoryIdempotency(), time.Hour)
+
+	return New(nil, nil, nil, idempotencyService, nil, nil, nil, nil)
+}
+
+// sendIdempotent sends a request with a given Idempotency-Key (unless empty) and body to a handler, and returns the
+// response.
+func sendIdempotent(
+	handler http.Handler,
+	method string,
+	target string,
+	key string,
+	body string,
+) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotencyHeader, key)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/imaging"
+)
+
+const (
+	// maxImageBytes bounds the size of uploaded images.
+	maxImageBytes = 10 << 20
+	// multipartOverhead is the room left, on top of the image, for the rest of a multipart body.
+	multipartOverhead = 1 << 20
+	// imageField is the multipart field holding an uploaded image.
+	imageField = "image"
+	// blobMaxAge is how long clients can cache blobs for, in seconds: they never change.
+	blobMaxAge = 365 * 24 * time.Hour / time.Second
+)
+
+var (
+	// errMissingImage for when a multipart body has no image field.
+	errMissingImage = fmt.Errorf("missing %q multipart field", imageField)
+	// errImageTooLarge for when an uploaded image is larger than maxImageBytes.
+	errImageTooLarge = fmt.Errorf("image too large, expected at most %d bytes", maxImageBytes)
+)
+
+// UploadLocationImage makes the JPEG, PNG or GIF image in the "image" field of a multipart body the image of the
+// location with a given ID, and replies with the location.
+//
+// The image (at most 10 MiB) is checked by its content, and stored along with thumbnails; the image and thumbnails
+// of the location become /blobs/{hash} URLs.
+func (r *RESTAPI) UploadLocationImage(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(writer, req.Body, maxImageBytes+multipartOverhead)
+
+	content, err := readImageField(req)
+	if errors.Is(err, errImageTooLarge) {
+		r.handleError(writer, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.locationService.SetImage(req.Context(), id, content)
+	if isNotFound(err) {
+		r.handleError(writer, "location not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this location", http.StatusForbidden)
+		return
+	}
+
+	if errors.Is(err, imaging.ErrUnsupportedImage) {
+		r.handleError(writer, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not store image", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// RequestBlob replies with the uploaded image (or thumbnail) with a given hash.
+//
+// Blobs are found by the hash of their content, so they never change: the hash is their ETag, and they can be cached
+// for good. Supports If-None-Match and Range requests.
+func (r *RESTAPI) RequestBlob(writer http.ResponseWriter, req *http.Request) {
+	hash := req.PathValue("hash")
+
+	content, err := r.locationService.Blob(req.Context(), hash)
+	if errors.Is(err, blob.ErrInvalidHash) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, blob.ErrNotFound) {
+		r.handleError(writer, "blob not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve blob", http.StatusInternalServerError)
+		return
+	}
+
+	defer content.Close()
+
+	writer.Header().Set("ETag", `"`+hash+`"`)
+	writer.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", blobMaxAge))
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// sniffs the content type, and answers conditional and range requests
+	http.ServeContent(writer, req, "", time.Time{}, content)
+}
+
+// readImageField reads the image field of a multipart body, without buffering the other fields.
+func readImageField(req *http.Request) ([]byte, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("expected a multipart body: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errMissingImage
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read multipart body: %w", err)
+		}
+
+		if part.FormName() != imageField {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, maxImageBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("could not read image: %w", err)
+		}
+
+		if len(content) > maxImageBytes {
+			return nil, errImageTooLarge
+		}
+
+		return content, nil
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/jwt"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_AuthenticateJWT(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	handler := newJWTHandler(t, jwt.KeySet{{ID: "key", Public: public}})
+	claims := map[string]any{
+		"sub":   "user-1",
+		"iss":   "https://issuer",
+		"aud":   "namless",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "data:write",
+	}
+
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodPost, "/data", signToken(t, private, claims)).Code)
+
+	forged := send(handler, http.MethodPost, "/data", signToken(t, other, claims))
+	assert.Equal(t, http.StatusUnauthorized, forged.Code)
+	assert.Equal(t, authenticateChallenge, forged.Header().Get("WWW-Authenticate"))
+
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, http.StatusUnauthorized, send(handler, http.MethodPost, "/data", signToken(t, private, claims)).Code)
+
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	claims["scope"] = "data:read"
+	assert.Equal(t, http.StatusForbidden, send(handler, http.MethodPost, "/data", signToken(t, private, claims)).Code)
+}
+
+func Test_AuthenticateJWTKeysUnavailable(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	handler := newJWTHandler(t, jwt.NewRemote(server.URL, server.Client(), time.Minute))
+	token := signToken(t, private, map[string]any{"sub": "user-1", "iss": "https://issuer", "aud": "namless"})
+
+	// tokens that cannot be checked get 503, so that callers retry rather than drop them
+	assert.Equal(t, http.StatusServiceUnavailable, send(handler, http.MethodPost, "/data", token).Code)
+}
+
+// newJWTHandler returns a route requiring ScopeDataWrite, behind Authenticate, accepting tokens signed with the given
+// keys.
+func newJWTHandler(t *testing.T, keys jwt.KeySource) http.Handler {
+	t.Helper()
+
+	verifier := jwt.NewVerifier(keys, "https://issuer", "namless", 0)
+
+	tokens, err := service.NewJWT(context.Background(), verifier, service.DefaultScopeClaim, nil)
+	assert.NoError(t, err)
+
+	apiKeys := service.NewAPIKey(context.Background(), repository.NewMemoryAPIKey(), testAdminKey)
+	restAPI := New(nil, nil, nil, nil, nil, apiKeys, tokens, nil)
+
+	return restAPI.Authenticate(restAPI.require(types.ScopeDataWrite, created))
+}
+
+// signToken returns a token holding claims, signed with an Ed25519 key.
+func signToken(t *testing.T, private ed25519.PrivateKey, claims map[string]any) string {
+	t.Helper()
+
+	head := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","kid":"key"}`))
+
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	content := head + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return content + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(private, []byte(content)))
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+var (
+	// errInvalidLimit for when the limit query parameter is not a positive number.
+	errInvalidLimit = errors.New("invalid limit")
+	// errInvalidOrder for when the order query parameter is neither asc nor desc.
+	errInvalidOrder = errors.New("invalid order, expected asc or desc")
+)
+
+// listOptions reads the paging, sorting and filtering query parameters of a listing.
+//
+// Supported parameters: limit, cursor, sort, order (asc or desc) and one per name in filters.
+func listOptions(req *http.Request, filters ...string) (types.ListOptions, error) {
+	query := req.URL.Query()
+
+	result := types.ListOptions{
+		Cursor:  query.Get("cursor"),
+		SortBy:  query.Get("sort"),
+		Filters: make(map[string]string),
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return types.ListOptions{}, fmt.Errorf("%w: %q", errInvalidLimit, raw)
+		}
+
+		result.Limit = limit
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		result.Descending = true
+	default:
+		return types.ListOptions{}, errInvalidOrder
+	}
+
+	for _, name := range filters {
+		if query.Has(name) {
+			result.Filters[name] = query.Get(name)
+		}
+	}
+
+	return result, nil
+}
+
+// isInvalidListing tells whether an error was caused by the listing options of the caller.
+func isInvalidListing(err error) bool {
+	return errors.Is(err, types.ErrInvalidCursor) ||
+		errors.Is(err, types.ErrInvalidSort) ||
+		errors.Is(err, types.ErrInvalidFilter) ||
+		errors.Is(err, types.ErrInvalidRange)
+}
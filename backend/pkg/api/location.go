@@ -1,71 +1,144 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/wakka-2/Namless/backend/pkg/geo"
 	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
-// RequestAllLocations replies with all locations.
+var (
+	// errInvalidLocationID for when the ID in the path is not a number.
+	errInvalidLocationID = errors.New("invalid location ID")
+)
+
+// RequestAllLocations replies with a page of locations.
+//
+// Supports the limit, cursor, sort (id, location, latitude or longitude) and order query parameters, and filtering
+// by location and image.
+//
+// With near=lat,lon and radius=meters, only the locations within radius of the point are listed, along with their
+// distance, closest first (sort=distance). With bbox=minLat,minLon,maxLat,maxLon, only the locations inside the box
+// are listed; minLon may be greater than maxLon for boxes crossing the antimeridian.
+//
+// With format=geojson, the page is a GeoJSON FeatureCollection of points instead.
 func (r *RESTAPI) RequestAllLocations(writer http.ResponseWriter, req *http.Request) {
-	result, err := r.locationService.GetAll(req.Context())
+	opts, err := listOptions(req, "location", "image")
 	if err != nil {
-		r.handleError(writer, "could not retrieve entry", http.StatusInternalServerError)
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format != "" && format != "json" && format != formatGeoJSON {
+		r.handleError(writer, errInvalidFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.listLocations(req, opts)
+	if isInvalidListing(err) || isInvalidGeo(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	asJSON, err := json.Marshal(result)
 	if err != nil {
-		r.handleError(writer, "could not marshal", http.StatusInternalServerError)
+		r.handleError(writer, "could not retrieve entry", http.StatusInternalServerError)
 		return
 	}
 
-	err = write(writer, asJSON, http.StatusOK)
+	if format == formatGeoJSON {
+		writer.Header().Set("Content-Type", contentTypeGeoJSON)
+		result = featureCollection(result)
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
 	if err != nil {
 		log.Default().Printf("could not write: %s", err)
 	}
 }
 
-// RequestLocation will return the Location with a given ID.
-//
-//nolint:dupl
-func (r *RESTAPI) RequestLocation(writer http.ResponseWriter, req *http.Request) {
-	key := req.PathValue("id")
-	if key == "" {
-		r.handleError(writer, "missing key", http.StatusBadRequest)
+// listLocations returns the page of locations matching the near, radius and bbox query parameters.
+func (r *RESTAPI) listLocations(req *http.Request, opts types.ListOptions) (any, error) {
+	query := req.URL.Query()
+
+	switch {
+	case query.Has("near") && query.Has("bbox"):
+		return nil, errNearAndBBox
+	case query.Has("near"):
+		center, radius, err := nearParameters(query)
+		if err != nil {
+			return nil, err
+		}
+
+		return r.locationService.Near(req.Context(), center, radius, opts)
+	case query.Has("bbox"):
+		box, err := geo.ParseBBox(query.Get("bbox"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse bbox: %w", err)
+		}
+
+		return r.locationService.InBox(req.Context(), box, opts)
+	}
+
+	return r.locationService.GetAll(req.Context(), opts)
+}
+
+// RequestNearestLocations replies with the k (10 by default, at most 100) locations nearest to the point given by
+// the lat and lon query parameters, with their distance in meters, closest first.
+func (r *RESTAPI) RequestNearestLocations(writer http.ResponseWriter, req *http.Request) {
+	latitude, longitude, k, err := nearestParameters(req.URL.Query())
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	id, err := strconv.Atoi(key)
+	result, err := r.locationService.Nearest(req.Context(), latitude, longitude, k)
 	if err != nil {
-		r.handleError(writer, "could not convert to int", http.StatusBadRequest)
+		r.handleError(writer, "could not retrieve locations", http.StatusInternalServerError)
 		return
 	}
 
-	result, err := r.locationService.Get(req.Context(), id)
+	err = writeJSON(writer, result, http.StatusOK)
 	if err != nil {
-		r.handleError(writer, "could not retrieve location", http.StatusInternalServerError)
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// RequestLocation will return the Location with a given ID.
+func (r *RESTAPI) RequestLocation(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.locationService.Get(req.Context(), id)
+	if isNotFound(err) {
+		r.handleError(writer, "location not found", http.StatusNotFound)
 		return
 	}
 
-	asJSON, err := json.Marshal(result)
 	if err != nil {
-		r.handleError(writer, "could not marshal", http.StatusInternalServerError)
+		r.handleError(writer, "could not retrieve location", http.StatusInternalServerError)
 		return
 	}
 
-	err = write(writer, asJSON, http.StatusOK)
+	err = writeJSON(writer, result, http.StatusOK)
 	if err != nil {
 		log.Default().Printf("could not write: %s", err)
 	}
 }
 
-// CreateLocation creates a new locattion.
+// CreateLocation creates a new location, and replies with it. Its ID is assigned by the server.
 func (r *RESTAPI) CreateLocation(writer http.ResponseWriter, req *http.Request) {
-	input := models.Location{}
+	input := types.LocationInput{}
 
 	err := json.NewDecoder(req.Body).Decode(&input)
 	if err != nil {
@@ -73,11 +146,115 @@ func (r *RESTAPI) CreateLocation(writer http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	err = r.locationService.Add(req.Context(), input)
+	result, err := r.locationService.Add(req.Context(), input)
+	if r.handleInvalidInput(writer, err) {
+		return
+	}
+
 	if err != nil {
 		r.handleError(writer, "could not create location", http.StatusInternalServerError)
 		return
 	}
 
-	writer.WriteHeader(http.StatusCreated)
+	writer.Header().Set("Location", fmt.Sprintf("/location/%d", result.ID))
+
+	err = writeJSON(writer, result, http.StatusCreated)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// ReplaceLocation replaces every field of the location with a given ID, and replies with the result.
+//
+// The name and the coordinates are required; an image that is not given is removed.
+func (r *RESTAPI) ReplaceLocation(writer http.ResponseWriter, req *http.Request) {
+	r.modifyLocation(writer, req, r.locationService.Replace)
+}
+
+// PatchLocation updates the fields given in the body of the location with a given ID, and replies with the result.
+func (r *RESTAPI) PatchLocation(writer http.ResponseWriter, req *http.Request) {
+	r.modifyLocation(writer, req, r.locationService.Patch)
+}
+
+// DeleteLocation deletes the location with a given ID.
+func (r *RESTAPI) DeleteLocation(writer http.ResponseWriter, req *http.Request) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.locationService.Delete(req.Context(), id)
+	if isNotFound(err) {
+		r.handleError(writer, "location not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this location", http.StatusForbidden)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not delete location", http.StatusInternalServerError)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// modifyLocation applies the body of a request to the location with the ID in its path, through modify.
+func (r *RESTAPI) modifyLocation(
+	writer http.ResponseWriter,
+	req *http.Request,
+	modify func(ctx context.Context, id int, input types.LocationInput) (models.Location, error),
+) {
+	id, err := locationID(req)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	input := types.LocationInput{}
+
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := modify(req.Context(), id, input)
+	if isNotFound(err) {
+		r.handleError(writer, "location not found", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this location", http.StatusForbidden)
+		return
+	}
+
+	if r.handleInvalidInput(writer, err) {
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not update location", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// locationID reads the ID in the path of a request.
+func locationID(req *http.Request) (int, error) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalidLocationID, req.PathValue("id"))
+	}
+
+	return id, nil
 }
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/locationio"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// ExportLocations streams every location, by ascending ID, as CSV (by default), GPX or KML, as the format query
+// parameter asks. The CSV columns can be renamed, or left out, with columns=field=column,...
+func (r *RESTAPI) ExportLocations(writer http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = locationio.FormatCSV
+	}
+
+	if !locationio.IsFormat(format) {
+		r.handleError(writer, locationio.ErrUnknownFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns, err := locationio.ParseColumns(req.URL.Query().Get("columns"))
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Content-Type", locationio.ContentType(format))
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "locations."+format))
+
+	exporter, err := locationio.NewWriter(format, writer, columns)
+	if err == nil {
+		err = r.locationService.ExportTo(req.Context(), exporter)
+	}
+
+	// the status is already sent
+	if err != nil {
+		log.Default().Printf("could not export locations: %s", err)
+	}
+}
+
+// importStream imports the locations of a body in a given format: csv (see the columns query parameter), gpx or
+// kml. The body is read one location at a time, so it can be of any size.
+//
+// Replies with the number of locations created, updated and failed, and with the outcome of the failed ones only,
+// along with their line. When the body cannot be read any further, the reply also tells why.
+func (r *RESTAPI) importStream(writer http.ResponseWriter, req *http.Request, format string, dryRun bool) {
+	columns, err := locationio.ParseColumns(req.URL.Query().Get("columns"))
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reader, err := locationio.NewReader(format, req.Body, columns)
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	output := types.ImportOutput{DryRun: dryRun, Results: []types.ImportResult{}}
+
+	err = r.locationService.ImportFrom(req.Context(), reader, dryRun, func(result types.ImportResult) error {
+		output.Count(result)
+
+		if result.Outcome != types.ImportCreated && result.Outcome != types.ImportUpdated {
+			output.Results = append(output.Results, result)
+		}
+
+		return nil
+	})
+
+	status := uint(http.StatusOK)
+
+	switch {
+	case errors.Is(err, locationio.ErrMalformed):
+		output.Error, status = err.Error(), http.StatusBadRequest
+	case err != nil:
+		log.Default().Printf("could not import locations: %s", err)
+
+		output.Error, status = "could not import locations", http.StatusInternalServerError
+	}
+
+	err = writeJSON(writer, output, status)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
@@ -8,8 +8,11 @@ import (
 func EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
 		writer.Header().Set("Access-Control-Allow-Origin", "*")
-		writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT")
-		writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept")
+		writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
+		writer.Header().Set("Access-Control-Allow-Headers",
+			"Origin, Content-Type, Accept, Authorization, X-API-Key, If-Match, If-None-Match, Idempotency-Key, X-Namespace")
+		writer.Header().Set("Access-Control-Expose-Headers",
+			"ETag, Location, Idempotent-Replayed, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if req.Method == http.MethodOptions {
 			writer.WriteHeader(http.StatusOK)
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// namespaceHeader names the namespace a request to /data works in, as an alternative to the /ns/{namespace} prefix.
+const namespaceHeader = "X-Namespace"
+
+// namespaced wraps the handler of a data route with the namespace it works in: the one of its /ns/{namespace} prefix,
+// or else of its X-Namespace header, or else types.DefaultNamespace. Requests naming an invalid namespace, or two
+// different ones, get 400.
+func (r *RESTAPI) namespaced(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		namespace, header := req.PathValue("namespace"), req.Header.Get(namespaceHeader)
+
+		switch {
+		case namespace == "" && header == "":
+			handler(writer, req)
+			return
+		case namespace == "":
+			namespace = header
+		case header != "" && header != namespace:
+			r.handleError(writer, "the path and the X-Namespace header name different namespaces", http.StatusBadRequest)
+			return
+		}
+
+		err := types.ValidateNamespace(namespace)
+		if err != nil {
+			r.handleError(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		handler(writer, req.WithContext(types.WithNamespace(req.Context(), namespace)))
+	}
+}
+
+// RequestUsage will retrieve how many data entries a namespace holds, and their size.
+// @Summary      RequestUsage will retrieve how many data entries a namespace holds, and their size.
+// @Produce      json
+// @Param        X-Namespace	header		string				false	"Namespace of the entries"
+// @Success      200		{object}	types.Usage
+// @Router       /data/_usage	[get].
+func (r *RESTAPI) RequestUsage(writer http.ResponseWriter, req *http.Request) {
+	result, err := r.dataService.Usage(req.Context())
+	if err != nil {
+		r.handleError(writer, "could not retrieve usage", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// handleQuotaError replies to a write that the quota of its namespace refused, and tells whether it did: 413 for a
+// value that is too large, and 429 for a namespace that is full.
+func (r *RESTAPI) handleQuotaError(writer http.ResponseWriter, req *http.Request, err error) bool {
+	namespace := types.NamespaceFrom(req.Context())
+
+	switch {
+	case errors.Is(err, types.ErrValueTooLarge):
+		r.handleError(writer, fmt.Sprintf("value too large for the quota of namespace %q", namespace),
+			http.StatusRequestEntityTooLarge)
+	case errors.Is(err, types.ErrQuotaExceeded):
+		r.handleError(writer, fmt.Sprintf("namespace %q is out of quota", namespace), http.StatusTooManyRequests)
+	default:
+		return false
+	}
+
+	return true
+}
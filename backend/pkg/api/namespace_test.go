@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_Namespaced(t *testing.T) {
+	restAPI := New(nil, nil, nil, nil, nil, nil, nil, nil)
+	multiplexer := http.NewServeMux()
+
+	for _, pattern := range []string{"GET /data", "GET /ns/{namespace}/data"} {
+		multiplexer.Handle(pattern, restAPI.namespaced(func(writer http.ResponseWriter, req *http.Request) {
+			writer.Write([]byte(types.NamespaceFrom(req.Context())))
+		}))
+	}
+
+	cases := []struct {
+		target, header string
+		status         int
+		namespace      string
+	}{
+		{target: "/data", status: http.StatusOK, namespace: types.DefaultNamespace},
+		{target: "/data", header: "team-a", status: http.StatusOK, namespace: "team-a"},
+		{target: "/ns/team-a/data", status: http.StatusOK, namespace: "team-a"},
+		{target: "/ns/team-a/data", header: "team-a", status: http.StatusOK, namespace: "team-a"},
+		{target: "/ns/team-a/data", header: "team-b", status: http.StatusBadRequest},
+		{target: "/ns/Team%20A/data", status: http.StatusBadRequest},
+		{target: "/data", header: "team/a", status: http.StatusBadRequest},
+	}
+
+	for _, test := range cases {
+		req := httptest.NewRequest(http.MethodGet, test.target, nil)
+		if test.header != "" {
+			req.Header.Set(namespaceHeader, test.header)
+		}
+
+		response := httptest.NewRecorder()
+		multiplexer.ServeHTTP(response, req)
+		assert.Equal(t, test.status, response.Code, test)
+
+		if test.status == http.StatusOK {
+			assert.Equal(t, test.namespace, response.Body.String(), test)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/ratelimit"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// RouteGroupData holds the /data routes, in every namespace.
+	RouteGroupData = "data"
+	// RouteGroupLocation holds the routes of locations, their claims and blobs.
+	RouteGroupLocation = "location"
+	// RouteGroupToken holds the routes that mint tokens, and spend money upstream: /token, /two and check-ins.
+	RouteGroupToken = "token"
+	// RouteGroupAdmin holds the /admin routes.
+	RouteGroupAdmin = "admin"
+
+	// RateByIP gives every client IP a bucket of its own.
+	RateByIP = "ip"
+	// RateByAPIKey gives every caller (API key, or subject of a token) a bucket of its own; anonymous callers get one
+	// per IP.
+	RateByAPIKey = "api_key"
+	// RateByRoute gives the whole route group a single bucket, shared by every caller.
+	RateByRoute = "route"
+
+	// callerRate and callerBurst limit each caller of the data and location routes.
+	callerRate, callerBurst = 600, 100
+	// adminRate and adminBurst limit each caller of the admin routes.
+	adminRate, adminBurst = 60, 10
+	// mintRate and mintBurst limit each caller of the token routes, and mintTotalRate and mintTotalBurst limit them
+	// all together, per hour.
+	mintRate, mintBurst           = 10, 3
+	mintTotalRate, mintTotalBurst = 100, 10
+)
+
+// ErrInvalidRateLimit for when a rate limit names an unknown route group or key, or lets no request through.
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+// DefaultRateLimits are the rate limits of the route groups that are not configured: ten requests a second per caller,
+// and for tokens, a mint every 6 seconds per caller and a hundred an hour overall.
+var DefaultRateLimits = map[string][]RateLimit{
+	RouteGroupData:     {{By: RateByAPIKey, Limit: ratelimit.PerPeriod(callerRate, time.Minute, callerBurst)}},
+	RouteGroupLocation: {{By: RateByAPIKey, Limit: ratelimit.PerPeriod(callerRate, time.Minute, callerBurst)}},
+	RouteGroupAdmin:    {{By: RateByAPIKey, Limit: ratelimit.PerPeriod(adminRate, time.Minute, adminBurst)}},
+	RouteGroupToken: {
+		{By: RateByAPIKey, Limit: ratelimit.PerPeriod(mintRate, time.Minute, mintBurst)},
+		{By: RateByRoute, Limit: ratelimit.PerPeriod(mintTotalRate, time.Hour, mintTotalBurst)},
+	},
+}
+
+// RateLimit of a route group: requests must get a token from the bucket picked by By.
+type RateLimit struct {
+	// By is RateByIP, RateByAPIKey or RateByRoute.
+	By    string
+	Limit ratelimit.Limit
+}
+
+// RateLimits limits the rate of requests to every route group.
+type RateLimits struct {
+	store ratelimit.Store
+	// groups maps route groups to their limits; a request must pass them all.
+	groups map[string][]RateLimit
+	// trustForwardedFor takes the client IP from the X-Forwarded-For header, set by a proxy.
+	trustForwardedFor bool
+	now               func() time.Time
+}
+
+// NewRateLimits builds the rate limits of route groups, kept in a given store; groups without limits are not limited.
+// Returns ErrInvalidRateLimit when a limit names an unknown route group or key, or lets no request through.
+func NewRateLimits(
+	store ratelimit.Store,
+	groups map[string][]RateLimit,
+	trustForwardedFor bool,
+) (*RateLimits, error) {
+	for group, limits := range groups {
+		switch group {
+		case RouteGroupData, RouteGroupLocation, RouteGroupToken, RouteGroupAdmin:
+		default:
+			return nil, fmt.Errorf("%w: unknown route group %q", ErrInvalidRateLimit, group)
+		}
+
+		for _, limit := range limits {
+			if limit.By != RateByIP && limit.By != RateByAPIKey && limit.By != RateByRoute {
+				return nil, fmt.Errorf("%w: unknown key %q for %q", ErrInvalidRateLimit, limit.By, group)
+			}
+
+			err := limit.Limit.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("%w for %q: %w", ErrInvalidRateLimit, group, err)
+			}
+		}
+	}
+
+	return &RateLimits{store: store, groups: groups, trustForwardedFor: trustForwardedFor, now: time.Now}, nil
+}
+
+// RateLimit middleware limits the rate of requests to every route group (see RateLimits), and tells callers how much
+// of the tightest limit they have left in RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. Requests
+// over a limit get 429, with a Retry-After header.
+//
+// The callers must be known already, so it goes after Authenticate, which puts requests with invalid credentials
+// through it as well. When the store fails, requests go through.
+func (r *RESTAPI) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if r.rateLimits == nil || req.Method == http.MethodOptions {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		decision, found, err := r.rateLimits.take(req)
+		if err != nil {
+			log.Default().Printf("could not check rate limit: %s", err)
+		}
+
+		if !found {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		writer.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		writer.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		writer.Header().Set("RateLimit-Reset", seconds(decision.Reset))
+
+		if !decision.Allowed {
+			writer.Header().Set("Retry-After", seconds(decision.RetryAfter))
+			r.handleError(writer, "too many requests, retry later", http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// take a token from every bucket of the route group of a request, when they all have one, and returns the tightest
+// decision, if any.
+func (rl *RateLimits) take(req *http.Request) (ratelimit.Decision, bool, error) {
+	group := routeGroup(req.URL.Path)
+	keys := make([]ratelimit.Key, 0, len(rl.groups[group]))
+
+	for position, limit := range rl.groups[group] {
+		keys = append(keys, ratelimit.Key{
+			Name:  fmt.Sprintf("%s/%d/%s", group, position, rl.key(req, limit.By)),
+			Limit: limit.Limit,
+		})
+	}
+
+	if len(keys) == 0 {
+		return ratelimit.Decision{}, false, nil
+	}
+
+	decisions, err := rl.store.Take(req.Context(), keys, rl.now())
+	if err != nil {
+		return ratelimit.Decision{}, false, fmt.Errorf("could not take token: %w", err)
+	}
+
+	result := decisions[0]
+
+	for _, decision := range decisions[1:] {
+		if tighter(decision, result) {
+			result = decision
+		}
+	}
+
+	return result, true, nil
+}
+
+// key returns what tells apart the buckets of a request, for a given RateBy* key.
+func (rl *RateLimits) key(req *http.Request, by string) string {
+	switch by {
+	case RateByRoute:
+		return ""
+	case RateByAPIKey:
+		if principal, found := types.PrincipalFrom(req.Context()); found {
+			return "caller:" + principal.ID
+		}
+	}
+
+	return "ip:" + rl.clientIP(req)
+}
+
+// clientIP returns the IP of the client of a request: the last one of its X-Forwarded-For header, added by the proxy in
+// front, when trusted, and its remote address otherwise.
+func (rl *RateLimits) clientIP(req *http.Request) string {
+	if forwarded := req.Header.Get("X-Forwarded-For"); rl.trustForwardedFor && forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+
+		return strings.TrimSpace(hops[len(hops)-1])
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// routeGroup returns the route group of a path.
+func routeGroup(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+
+	switch {
+	case first == "data" || first == "ns":
+		return RouteGroupData
+	case first == "token" || first == "two" || strings.HasSuffix(path, "/checkin"):
+		return RouteGroupToken
+	case first == "admin":
+		return RouteGroupAdmin
+	}
+
+	return RouteGroupLocation
+}
+
+// tighter tells whether a decision leaves the caller with less than another one.
+func tighter(decision ratelimit.Decision, other ratelimit.Decision) bool {
+	if decision.Allowed != other.Allowed {
+		return !decision.Allowed
+	}
+
+	if !decision.Allowed {
+		return decision.RetryAfter > other.RetryAfter
+	}
+
+	return decision.Remaining < other.Remaining
+}
+
+// seconds formats a duration as a number of seconds, rounded up.
+func seconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/ratelimit"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/service"
+)
+
+func Test_RateLimit(t *testing.T) {
+	restAPI, _ := newRateLimitedAPI(t, map[string][]RateLimit{
+		RouteGroupData: {{By: RateByIP, Limit: ratelimit.PerPeriod(2, time.Minute, 2)}},
+	})
+	now := time.Now()
+	restAPI.rateLimits.now = func() time.Time { return now }
+	handler := restAPI.RateLimit(http.HandlerFunc(created))
+
+	for remaining, reset := range []string{"30", "60"} {
+		response := send(handler, http.MethodGet, "/data", "")
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(1-remaining), response.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, reset, response.Header().Get("RateLimit-Reset"))
+		assert.Empty(t, response.Header().Get("Retry-After"))
+	}
+
+	response := send(handler, http.MethodGet, "/ns/team-a/data", "")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", response.Header().Get("Retry-After"))
+
+	// tokens come back over time
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodGet, "/data", "").Code)
+
+	// other IPs, route groups without limits and preflight requests are let through
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	assert.Equal(t, http.StatusCreated, response.Code)
+
+	response = send(handler, http.MethodGet, "/location", "")
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Empty(t, response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodOptions, "/data", "").Code)
+}
+
+func Test_RateLimitCallers(t *testing.T) {
+	restAPI, keys := newRateLimitedAPI(t, map[string][]RateLimit{
+		RouteGroupToken: {
+			{By: RateByAPIKey, Limit: ratelimit.PerPeriod(1, time.Hour, 1)},
+			{By: RateByRoute, Limit: ratelimit.PerPeriod(3, time.Hour, 3)},
+		},
+	})
+	handler := restAPI.Authenticate(restAPI.RateLimit(http.HandlerFunc(created)))
+	alice, bob := issueKey(t, keys, "alice"), issueKey(t, keys, "bob")
+
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodPost, "/token", alice).Code)
+
+	// requests refused by the limit of a caller do not use up the limit of the route
+	for range 5 {
+		assert.Equal(t, http.StatusTooManyRequests, send(handler, http.MethodPost, "/token", alice).Code)
+	}
+
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodPost, "/token", bob).Code)
+}
+
+func Test_RateLimitFailedAuthentication(t *testing.T) {
+	restAPI, keys := newRateLimitedAPI(t, map[string][]RateLimit{
+		RouteGroupData: {{By: RateByAPIKey, Limit: ratelimit.PerPeriod(2, time.Hour, 2)}},
+	})
+	handler := restAPI.Authenticate(restAPI.RateLimit(http.HandlerFunc(created)))
+	alice := issueKey(t, keys, "alice")
+
+	// invalid credentials count against the limit of their client IP
+	assert.Equal(t, http.StatusUnauthorized, send(handler, http.MethodGet, "/data", "guess-1").Code)
+	assert.Equal(t, http.StatusUnauthorized, send(handler, http.MethodGet, "/data", alice+"0").Code)
+
+	response := send(handler, http.MethodGet, "/data", "guess-3")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "1800", response.Header().Get("Retry-After"), "a token every half hour")
+
+	assert.Equal(t, http.StatusTooManyRequests, send(handler, http.MethodGet, "/data", "").Code)
+	assert.Equal(t, http.StatusCreated, send(handler, http.MethodGet, "/data", alice).Code)
+}
+
+// newRateLimitedAPI builds a REST API authenticating callers with API keys, and limiting them to the given limits.
+func newRateLimitedAPI(t *testing.T, groups map[string][]RateLimit) (*RESTAPI, *service.APIKey) {
+	t.Helper()
+
+	keys := service.NewAPIKey(context.Background(), repository.NewMemoryAPIKey(), testAdminKey)
+
+	rateLimits, err := NewRateLimits(ratelimit.NewMemory(), groups, false)
+	assert.NoError(t, err)
+
+	return New(nil, nil, nil, nil, nil, keys, nil, rateLimits), keys
+}
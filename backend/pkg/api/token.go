@@ -1,23 +1,21 @@
 package api
 
 import (
-	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/service"
 	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
-const (
-	tokenURL = "https://studio-api.nmkr.io/v2/UploadNft/651195c1-4663-451d-90dd-bb3d8cfb8f43?uploadsource=Boom"
-)
-
-// CreateToken creates a new token.
-//
-//nolint:funlen
+// CreateToken enqueues a job that uploads the token in the body of a request to the minting service, mints it and
+// sends it to its receiver; it replies with the job, whose state can be followed with RequestMintJob.
 func (r *RESTAPI) CreateToken(writer http.ResponseWriter, req *http.Request) {
 	input := types.TokenInput{}
 
@@ -27,119 +25,111 @@ func (r *RESTAPI) CreateToken(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	payload := map[string]any{
-		"tokenname":   input.Tokenname,
-		"displayname": input.Displayname,
-		"description": input.Description,
-		"previewImageNft": map[string]string{
-			"mimetype":       "string",
-			"fileFromIPFS":   input.FileFromIPFS,
-			"fileFromBase64": input.FileFromBase64,
-		},
-		"metadataPlaceholder": []map[string]any{
-			{
-				"name":  input.MetadataPlaceholderName,
-				"value": input.MetadataPlaceholderValue,
-			},
-		},
-	}
-
-	asJSON, err := json.MarshalIndent(payload, "", " ")
+	result, err := r.tokenService.Enqueue(req.Context(), input)
 	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	request, err := http.NewRequestWithContext(req.Context(), http.MethodPost, tokenURL, bytes.NewBuffer(asJSON))
-	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		r.handleMintingError(writer, err, "could not enqueue mint job")
 		return
 	}
 
-	request.Header = http.Header{
-		"Accept":       {"text/plain"},
-		"Content-Type": {"application/json"},
-	}
-	request.Header.Add("Authorization", input.Bearer)
+	writeMintJob(writer, result, http.StatusAccepted)
+}
 
-	client := http.Client{}
+// MintToken enqueues a job that mints the uploaded token with a given UID, and sends it to the receiver in the body
+// of a request (or to the wallet of the project, when the body is empty); it replies with the job.
+func (r *RESTAPI) MintToken(writer http.ResponseWriter, req *http.Request) {
+	input := types.MintInput{}
 
-	response, err := client.Do(request)
-	if err != nil {
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
 		r.handleError(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer response.Body.Close()
 
-	bytes, err := io.ReadAll(response.Body)
+	r.mintToken(writer, req, req.PathValue("uid"), input)
+}
+
+// CreateToken2 enqueues a job that mints the uploaded token with a given UID, and sends it to the wallet of the
+// project; it replies with the job.
+//
+// Deprecated: use MintToken (POST /token/{uid}/mint).
+func (r *RESTAPI) CreateToken2(writer http.ResponseWriter, req *http.Request) {
+	r.mintToken(writer, req, req.PathValue("name"), types.MintInput{})
+}
+
+// RequestToken replies with the state of the uploaded token with a given UID.
+func (r *RESTAPI) RequestToken(writer http.ResponseWriter, req *http.Request) {
+	result, err := r.tokenService.Status(req.Context(), req.PathValue("uid"))
 	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		r.handleMintingError(writer, err, "could not get token status")
 		return
 	}
 
-	err = write(writer, bytes, http.StatusOK)
+	err = writeJSON(writer, result, http.StatusOK)
 	if err != nil {
 		log.Default().Printf("could not write: %s", err)
 	}
-
-	// TODO: remove
-	fmt.Println("## bytes response:", string(bytes))
 }
 
-// CreateToken2 creates a new token.
-//
-//nolint:funlen
-func (r *RESTAPI) CreateToken2(writer http.ResponseWriter, req *http.Request) {
-	// TODO: remove
-	fmt.Println("##222 ")
-
-	key := req.PathValue("name")
-	if key == "" {
-		r.handleError(writer, "missing key", http.StatusBadRequest)
+// RequestMintJob replies with the mint job with a given ID: its state is queued, uploading, minting, done or failed.
+func (r *RESTAPI) RequestMintJob(writer http.ResponseWriter, req *http.Request) {
+	result, err := r.tokenService.Job(req.Context(), req.PathValue("id"))
+	if isNotFound(err) {
+		r.handleError(writer, "mint job not found", http.StatusNotFound)
 		return
 	}
 
-	// TODO: remove
-	fmt.Println("##")
-	fmt.Println("## CreateToken2 #### :", key)
+	if err != nil {
+		r.handleMintingError(writer, err, "could not get mint job")
+		return
+	}
 
-	url := fmt.Sprintf("https://studio-api.nmkr.io/v2/MintAndSendSpecific/651195c1-4663-451d-90dd-bb3d8cfb8f43/%s/0.1/addr1qxz79ckr8ewtupsa3reut65t7l80xalv3d8vp38855jkq34hdj2er5qm75szuxaw2aw28qy9wyaywheaxghvn9hgq60qypfa2x",
-		key)
+	writeMintJob(writer, result, http.StatusOK)
+}
 
-	request, err := http.NewRequestWithContext(req.Context(), http.MethodGet, url, nil)
+// mintToken enqueues a job that mints the uploaded token with a given UID, and replies with it.
+func (r *RESTAPI) mintToken(writer http.ResponseWriter, req *http.Request, uid string, input types.MintInput) {
+	result, err := r.tokenService.EnqueueMint(req.Context(), uid, input)
 	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		r.handleMintingError(writer, err, "could not enqueue mint job")
 		return
 	}
 
-	request.Header = http.Header{
-		"Accept": {"text/plain"},
-	}
-	request.Header.Add("Authorization", "59b826e660db4fbf941aabe3b5d5b84b")
+	writeMintJob(writer, result, http.StatusAccepted)
+}
 
-	client := http.Client{}
+// writeMintJob replies with a mint job, without its token, and with its URL in the Location header.
+func writeMintJob(writer http.ResponseWriter, job models.MintJob, statusCode uint) {
+	job.Token = nil
 
-	response, err := client.Do(request)
+	writer.Header().Set("Location", "/token/jobs/"+url.PathEscape(job.ID))
+
+	err := writeJSON(writer, job, statusCode)
 	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
-		return
+		log.Default().Printf("could not write: %s", err)
 	}
-	defer response.Body.Close()
+}
 
-	bytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		r.handleError(writer, err.Error(), http.StatusBadRequest)
+// handleMintingError replies with the status matching an error of the token service.
+//
+// Refusals of the minting service are passed on, since they tell what is wrong with the token; other failures are
+// only logged, as they may hold details of the upstream requests.
+func (r *RESTAPI) handleMintingError(writer http.ResponseWriter, err error, message string) {
+	if r.handleInvalidInput(writer, err) {
 		return
 	}
 
-	err = write(writer, bytes, http.StatusOK)
-	if err != nil {
-		log.Default().Printf("could not write: %s", err)
+	switch {
+	case errors.Is(err, service.ErrMintingDisabled):
+		r.handleError(writer, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, minting.ErrNotFound):
+		r.handleError(writer, "token not found", http.StatusNotFound)
+	case errors.Is(err, minting.ErrRejected):
+		r.handleError(writer, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, minting.ErrUnavailable):
+		log.Default().Printf("%s: %s", message, err)
+		r.handleError(writer, message+": minting service unavailable", http.StatusBadGateway)
+	default:
+		log.Default().Printf("%s: %s", message, err)
+		r.handleError(writer, message, http.StatusInternalServerError)
 	}
-
-	// TODO: remove
-	fmt.Println("##")
-	fmt.Println("##")
-	fmt.Println("##")
-	fmt.Println("## bytes response 222:", string(bytes))
 }
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"log"
+	"net/http"
+)
+
+// RequestTrash will retrieve a page of the data entries that were deleted, and can still be restored.
+// @Summary      RequestTrash will retrieve a page of the data entries that were deleted, and can still be restored.
+// @Produce      json
+// @Param        limit		query		int					false	"Page size"
+// @Param        cursor		query		string				false	"next_cursor of the previous page"
+// @Param        sort		query		string				false	"id or deleted_at"
+// @Param        order		query		string				false	"asc or desc"
+// @Param        value		query		string				false	"Only entries with this value"
+// @Success      200		{object}	types.Page[models.Data]
+// @Failure      400		{object}	ErrorMessage
+// @Router       /data/_trash	[get].
+func (r *RESTAPI) RequestTrash(writer http.ResponseWriter, req *http.Request) {
+	opts, err := listOptions(req, "value")
+	if err != nil {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.dataService.Trash(req.Context(), opts)
+	if isInvalidListing(err) {
+		r.handleError(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not retrieve deleted entries", http.StatusInternalServerError)
+		return
+	}
+
+	err = writeJSON(writer, result, http.StatusOK)
+	if err != nil {
+		log.Default().Printf("could not write: %s", err)
+	}
+}
+
+// Restore will bring back a deleted data entry.
+// @Summary      Restore will bring back a deleted data entry.
+// @Param        key		path		string				true	"Request Path"
+// @Success      204
+// @Failure      403		{object}	ErrorMessage
+// @Failure      404		{object}	ErrorMessage
+// @Failure      413		{object}	ErrorMessage
+// @Failure      429		{object}	ErrorMessage
+// @Router       /data/{key}/restore	[post].
+func (r *RESTAPI) Restore(writer http.ResponseWriter, req *http.Request) {
+	result, err := r.dataService.Restore(req.Context(), req.PathValue("key"))
+	if isNotFound(err) {
+		r.handleError(writer, "entry not in the trash", http.StatusNotFound)
+		return
+	}
+
+	if isForbidden(err) {
+		r.handleError(writer, "not allowed to change this entry", http.StatusForbidden)
+		return
+	}
+
+	if r.handleQuotaError(writer, req, err) {
+		return
+	}
+
+	if err != nil {
+		r.handleError(writer, "could not restore entry", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("ETag", formatETag(result.Version))
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
 // writeJSON writes a JSON to a response writer.
@@ -21,11 +23,14 @@ func writeJSON(writer http.ResponseWriter, toBeWritten interface{}, statusCode u
 
 // write a []byte to a response writer.
 //
-// Also adds the Content-Length header.
+// Also adds the Content-Length header, and the Content-Type one unless it is set.
 func write(writer http.ResponseWriter, toBeWritten []byte, statusCode uint) error {
-	writer.Header().Add("Content-Type", "application/json")
+	if writer.Header().Get("Content-Type") == "" {
+		writer.Header().Set("Content-Type", "application/json")
+	}
+
 	writer.Header().Set("Content-Length", strconv.Itoa(len(toBeWritten)))
-	//writer.WriteHeader(int(statusCode))
+	writer.WriteHeader(int(statusCode))
 
 	_, err := writer.Write(toBeWritten)
 	if err != nil {
@@ -38,4 +43,6 @@ func write(writer http.ResponseWriter, toBeWritten []byte, statusCode uint) erro
 // ErrorMessage is used to encapsulate error replies.
 type ErrorMessage struct {
 	Error string
+	// Fields lists what is wrong with each invalid field of the input, if any.
+	Fields []types.FieldError `json:",omitempty"`
 }
@@ -0,0 +1,56 @@
+/*
+Package blob offers content-addressed storage of binary objects: a blob is found by the SHA-256 of its content, so it
+never changes once stored.
+*/
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var (
+	// ErrNotFound for when there is no blob with a given hash.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidHash for when a hash is not the lowercase hexadecimal SHA-256 of a blob.
+	ErrInvalidHash = errors.New("invalid blob hash")
+)
+
+// validHash matches hashes of blobs.
+var validHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Store keeps blobs by hash.
+type Store interface {
+	// Put a blob, and returns its hash. Putting a blob that is already stored does nothing.
+	Put(ctx context.Context, content []byte) (string, error)
+	// Get the blob with a given hash. Returns ErrNotFound when there is none.
+	Get(ctx context.Context, hash string) (Blob, error)
+}
+
+// Blob is the content of a stored blob. Callers must close it.
+type Blob interface {
+	io.ReadSeekCloser
+	// Size returns the number of bytes of the blob.
+	Size() int64
+}
+
+// Hash returns the hash of a blob.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckHash returns ErrInvalidHash when hash cannot be the hash of a blob.
+func CheckHash(hash string) error {
+	if !validHash.MatchString(hash) {
+		return fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+	}
+
+	return nil
+}
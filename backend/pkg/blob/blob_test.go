@@ -0,0 +1,45 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Store(t *testing.T) {
+	filesystem, err := NewFilesystem(filepath.Join(t.TempDir(), "blobs"))
+	assert.NoError(t, err)
+
+	for name, store := range map[string]Store{"memory": NewMemory(), "filesystem": filesystem} {
+		t.Run(name, func(t *testing.T) {
+			content := []byte("hello")
+
+			hash, err := store.Put(context.TODO(), content)
+			assert.NoError(t, err)
+			assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", hash)
+
+			// putting it again does nothing
+			again, err := store.Put(context.TODO(), content)
+			assert.NoError(t, err)
+			assert.Equal(t, hash, again)
+
+			blob, err := store.Get(context.TODO(), hash)
+			assert.NoError(t, err)
+
+			read, err := io.ReadAll(blob)
+			assert.NoError(t, err)
+			assert.Equal(t, content, read)
+			assert.Equal(t, int64(len(content)), blob.Size())
+			assert.NoError(t, blob.Close())
+
+			_, err = store.Get(context.TODO(), Hash([]byte("other")))
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			_, err = store.Get(context.TODO(), "../../etc/passwd")
+			assert.ErrorIs(t, err, ErrInvalidHash)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// shardLength is the number of characters of the hash naming the subdirectory of a blob, to keep directories small.
+const shardLength = 2
+
+// Filesystem keeps blobs as files in a directory: dir/ab/abcdef... for the blob with hash abcdef...
+type Filesystem struct {
+	dir string
+}
+
+// NewFilesystem builds a new Filesystem store, creating its directory if needed.
+func NewFilesystem(dir string) (*Filesystem, error) {
+	err := os.MkdirAll(dir, types.PermissionDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("could not create blob directory: %w", err)
+	}
+
+	return &Filesystem{dir: dir}, nil
+}
+
+// Put a blob, and returns its hash. The file is written aside, then renamed, so readers never see half a blob.
+func (f *Filesystem) Put(ctx context.Context, content []byte) (string, error) {
+	if ctx.Err() != nil {
+		return "", types.ErrCancelledContext
+	}
+
+	hash := Hash(content)
+	path := f.path(hash)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return hash, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), types.PermissionDirectory)
+	if err != nil {
+		return "", fmt.Errorf("could not create blob directory: %w", err)
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("could not create blob: %w", err)
+	}
+
+	defer os.Remove(temporary.Name())
+
+	_, err = temporary.Write(content)
+	if err == nil {
+		err = temporary.Sync()
+	}
+
+	closeErr := temporary.Close()
+
+	err = errors.Join(err, closeErr)
+	if err != nil {
+		return "", fmt.Errorf("could not write blob: %w", err)
+	}
+
+	err = os.Rename(temporary.Name(), path)
+	if err != nil {
+		return "", fmt.Errorf("could not store blob: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Get the blob with a given hash.
+func (f *Filesystem) Get(ctx context.Context, hash string) (Blob, error) {
+	if ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	err := CheckHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(f.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not open blob: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("could not open blob: %w", err)
+	}
+
+	return &fileBlob{File: file, size: info.Size()}, nil
+}
+
+// path returns the path of the file of the blob with a given hash.
+func (f *Filesystem) path(hash string) string {
+	return filepath.Join(f.dir, hash[:shardLength], hash)
+}
+
+// fileBlob is a blob kept in a file.
+type fileBlob struct {
+	*os.File
+	size int64
+}
+
+// Size returns the number of bytes of the blob.
+func (fb *fileBlob) Size() int64 {
+	return fb.size
+}
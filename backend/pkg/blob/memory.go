@@ -0,0 +1,71 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// Memory keeps blobs in memory; they are lost on restart. Meant for local runs and tests.
+type Memory struct {
+	blobs map[string][]byte
+	mutex sync.RWMutex
+}
+
+// NewMemory builds a new, empty, Memory store.
+func NewMemory() *Memory {
+	return &Memory{blobs: make(map[string][]byte)}
+}
+
+// Put a blob, and returns its hash.
+func (m *Memory) Put(ctx context.Context, content []byte) (string, error) {
+	if ctx.Err() != nil {
+		return "", types.ErrCancelledContext
+	}
+
+	hash := Hash(content)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.blobs[hash]; !found {
+		m.blobs[hash] = bytes.Clone(content)
+	}
+
+	return hash, nil
+}
+
+// Get the blob with a given hash.
+func (m *Memory) Get(ctx context.Context, hash string) (Blob, error) {
+	if ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	err := CheckHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	content, found := m.blobs[hash]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+
+	return &memoryBlob{Reader: bytes.NewReader(content)}, nil
+}
+
+// memoryBlob is a blob kept in memory.
+type memoryBlob struct {
+	*bytes.Reader
+}
+
+// Close does nothing.
+func (mb *memoryBlob) Close() error {
+	return nil
+}
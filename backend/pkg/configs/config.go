@@ -3,21 +3,221 @@ package configs
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+)
+
+const (
+	// StoragePostgres keeps data in the postgres DB found at DSN. It is the default.
+	StoragePostgres = "postgres"
+	// StorageMemory keeps data in memory; it is lost on restart. Meant for local runs and tests.
+	StorageMemory = "memory"
+	// StorageFile keeps data in append-only files inside StorageDir, with no need for a DB server.
+	StorageFile = "file"
+
+	// MinterNMKR mints tokens with NMKR Studio.
+	MinterNMKR = "nmkr"
+	// MinterFake mints tokens in memory, for tests and local runs; nothing reaches a chain.
+	MinterFake = "fake"
+
+	// NMKRAPIKeyVariable is the environment variable that, when set, holds the API key of NMKR Studio.
+	NMKRAPIKeyVariable = "NAMLESS_NMKR_API_KEY"
+	// AdminKeyVariable is the environment variable that, when set, holds the admin key.
+	AdminKeyVariable = "NAMLESS_ADMIN_KEY"
+	// MinAdminKey is the smallest number of characters of the admin key.
+	MinAdminKey = 32
+
+	defaultReaperIntervalSeconds = 60
+	defaultReaperBatchSize       = 500
+	defaultMintWorkers           = 2
+	defaultMintMaxAttempts       = 5
+	defaultMintRetryBaseSeconds  = 10
+	defaultMintRetryMaxSeconds   = 600
+	defaultMintLeaseSeconds      = 300
+	defaultCheckinRadiusMeters   = 100
+	// defaultIdempotencyWindowSeconds is a day.
+	defaultIdempotencyWindowSeconds = 86_400
+	// defaultJWKSCacheSeconds is an hour.
+	defaultJWKSCacheSeconds = 3_600
+	// defaultRateLimitPeriodSeconds is a minute.
+	defaultRateLimitPeriodSeconds = 60
+)
+
+var (
+	// ErrUnknownStorage for when the configured storage backend is not supported.
+	ErrUnknownStorage = errors.New("unknown storage")
+	// ErrMissingStorageDir for when the file storage is selected without a directory to keep the files in.
+	ErrMissingStorageDir = errors.New("missing StorageDir")
+	// ErrUnknownMinter for when the configured minting provider is not supported.
+	ErrUnknownMinter = errors.New("unknown minting provider")
+	// ErrWeakAdminKey for when the admin key is too short to be safe.
+	ErrWeakAdminKey = fmt.Errorf("the admin key must be at least %d characters", MinAdminKey)
+	// ErrIncompleteJWT for when tokens are accepted without checking whom they were issued by, and for.
+	ErrIncompleteJWT = errors.New("JWT needs a single one of JWKSFile and JWKSURL, an Issuer and an Audience")
 )
 
 // DataConfig stores configs.
 type DataConfig struct {
 	ListenAddress string
-	DSN           string
+	// Storage selects the repository backend: StoragePostgres (when empty), StorageMemory or StorageFile.
+	Storage string
+	// DSN of the postgres DB, used by StoragePostgres.
+	DSN string
+	// StorageDir is the directory holding the files of StorageFile.
+	StorageDir string
+	// ReaperIntervalSeconds is the time between two purges of expired data entries.
+	ReaperIntervalSeconds int
+	// ReaperBatchSize is the number of expired data entries (or old revisions) purged at once.
+	ReaperBatchSize int
+	// HistoryKeepVersions is the number of revisions kept for every data entry; zero keeps them all.
+	HistoryKeepVersions int
+	// HistoryMaxAgeSeconds is how long revisions are kept for; zero keeps them forever.
+	HistoryMaxAgeSeconds int
+	// TrashRetentionSeconds is how long deleted data entries can be restored for; zero keeps them forever.
+	TrashRetentionSeconds int
+	// BlobDir is the directory holding uploaded images. When empty, they are kept in StorageDir/blobs by StorageFile,
+	// and in memory otherwise.
+	BlobDir string
+	// CheckinRadiusMeters is how close to a location check-ins must be made from, for the locations that have no
+	// radius of their own; 100 meters when zero.
+	CheckinRadiusMeters float64
+	// IdempotencyWindowSeconds is how long the responses to requests sent with an Idempotency-Key are replayed for;
+	// a day when zero.
+	IdempotencyWindowSeconds int
+	// RateLimits configures the rate limits of the routes.
+	RateLimits RateLimitsConfig
+	// Namespaces configures the quotas of the namespaces the key-value pairs are kept in.
+	Namespaces NamespacesConfig
+	// Minting configures the minting of tokens; it is disabled when Minting.Provider is empty.
+	Minting MintingConfig
+	// Auth configures the authentication of callers.
+	Auth AuthConfig
+}
+
+// RateLimitsConfig configures the rate limits of the routes, by route group: "data", "location" (along with claims and
+// blobs), "token" (along with check-ins, which mint tokens too) and "admin".
+type RateLimitsConfig struct {
+	// Disabled lets every request through.
+	Disabled bool
+	// TrustForwardedFor takes the IP of clients from the X-Forwarded-For header; only safe behind a proxy setting it.
+	TrustForwardedFor bool
+	// Groups maps route groups to the limits replacing their default ones; an empty list lifts them. A request must
+	// pass every limit of its group. I.e.: {"token": [{"By": "api_key", "Requests": 5, "PeriodSeconds": 60}]}.
+	Groups map[string][]RateLimitConfig
+}
+
+// RateLimitConfig configures a token bucket.
+type RateLimitConfig struct {
+	// By picks the bucket of a request: "ip", "api_key" (the caller, or its IP when anonymous) or "route" (a single
+	// one, shared by every caller).
+	By string
+	// Requests are let through every PeriodSeconds (60 when zero), on average.
+	Requests      int
+	PeriodSeconds int
+	// Burst is the largest number of requests let through at once; Requests when zero.
+	Burst int
+}
+
+// NamespacesConfig configures the quotas of the namespaces.
+type NamespacesConfig struct {
+	// Quota applies to the namespaces that are not in Quotas.
+	Quota QuotaConfig
+	// Quotas maps the names of some namespaces to their own quota. I.e.: {"tenant-a": {"MaxKeys": 1000}}.
+	Quotas map[string]QuotaConfig
+}
+
+// QuotaConfig bounds what a namespace can hold; zero fields are unlimited.
+type QuotaConfig struct {
+	// MaxKeys is the largest number of key-value pairs.
+	MaxKeys int64
+	// MaxValueBytes is the largest size of a value; larger ones are refused with 413 Payload Too Large.
+	MaxValueBytes int64
+	// MaxTotalBytes is the largest total size of the keys and values.
+	MaxTotalBytes int64
+}
+
+// AuthConfig configures the authentication of callers, with API keys and JSON Web Tokens.
+type AuthConfig struct {
+	// Disabled leaves every route open to anyone; meant for local runs.
+	Disabled bool
+	// AdminKey grants every scope without being stored, to issue the first API keys with. Better kept out of the
+	// configs, in the NAMLESS_ADMIN_KEY environment variable, which wins over it.
+	AdminKey string
+	// JWT configures the authentication with tokens issued by another service; disabled when it has no JWKS.
+	JWT JWTConfig
+}
+
+// JWTConfig configures the verification of JSON Web Tokens (RS256, ES256 or EdDSA).
+type JWTConfig struct {
+	// JWKSFile holds the signing keys, as a JSON Web Key Set; read on start.
+	JWKSFile string
+	// JWKSURL serves the signing keys, as a JSON Web Key Set; fetched when needed, and kept for JWKSCacheSeconds.
+	JWKSURL          string
+	JWKSCacheSeconds int
+	// Issuer and Audience must match the iss and aud claims of tokens.
+	Issuer   string
+	Audience string
+	// LeewaySeconds is the clock skew tolerated when checking the exp and nbf claims.
+	LeewaySeconds int
+	// ScopeClaim names the claim holding the scopes of a token: a space-separated string, or an array of strings.
+	// "scope" when empty.
+	ScopeClaim string
+	// Scopes maps the values of the scope claim to the scopes of the service; values that name a scope need not be
+	// mapped. I.e.: {"editor": ["data:read", "data:write"]}.
+	Scopes map[string][]string
+}
+
+// IsEnabled tells whether tokens are accepted.
+func (jc *JWTConfig) IsEnabled() bool {
+	return jc.JWKSFile != "" || jc.JWKSURL != ""
+}
+
+// MintingConfig configures the minting of tokens.
+type MintingConfig struct {
+	// Provider selects the minting service: MinterNMKR or MinterFake.
+	Provider string
+	// BaseURL of the NMKR Studio API; the public one when empty.
+	BaseURL string
+	// ProjectUID is the NMKR Studio project tokens are uploaded to.
+	ProjectUID string
+	// ReceiverAddress is the wallet tokens are sent to when a mint names none.
+	ReceiverAddress string
+	// UploadSource tags the uploaded tokens in NMKR Studio.
+	UploadSource string
+	// APIKey of NMKR Studio. Better kept out of the configs: in APIKeyFile, or in the NAMLESS_NMKR_API_KEY
+	// environment variable, which win over it.
+	APIKey string
+	// APIKeyFile is a file holding the API key of NMKR Studio.
+	APIKeyFile string
+	// TimeoutSeconds bounds every request to NMKR Studio; 30 seconds when zero.
+	TimeoutSeconds int
+	// Workers is the number of mint jobs processed at once.
+	Workers int
+	// MaxAttempts is the number of times a mint job is tried before it fails.
+	MaxAttempts int
+	// RetryBaseSeconds is the wait before the first retry of a mint job; it doubles with every attempt, up to
+	// RetryMaxSeconds.
+	RetryBaseSeconds int
+	RetryMaxSeconds  int
+	// LeaseSeconds is how long a worker holds a mint job before another one can take it, in case it crashed; it must
+	// outlast two requests to NMKR Studio.
+	LeaseSeconds int
 }
 
 // Obfuscate returns a string representation of the configs, without the security-risky entries.
 func (dc *DataConfig) Obfuscate() (string, error) {
 	aux := *dc
-	// aux.RiskyField = "xxx"
+
+	if aux.Minting.APIKey != "" {
+		aux.Minting.APIKey = "xxx"
+	}
+
+	if aux.Auth.AdminKey != "" {
+		aux.Auth.AdminKey = "xxx"
+	}
 
 	asJSON, err := json.MarshalIndent(aux, "", "  ")
 	if err != nil {
@@ -41,5 +241,136 @@ func ReadConfigs(filename string) (*DataConfig, error) {
 		return nil, fmt.Errorf("could not unmarshal: %w", err)
 	}
 
+	if result.Storage == "" {
+		result.Storage = StoragePostgres
+	}
+
+	if result.ReaperIntervalSeconds <= 0 {
+		result.ReaperIntervalSeconds = defaultReaperIntervalSeconds
+	}
+
+	if result.ReaperBatchSize <= 0 {
+		result.ReaperBatchSize = defaultReaperBatchSize
+	}
+
+	if result.CheckinRadiusMeters <= 0 {
+		result.CheckinRadiusMeters = defaultCheckinRadiusMeters
+	}
+
+	if result.IdempotencyWindowSeconds <= 0 {
+		result.IdempotencyWindowSeconds = defaultIdempotencyWindowSeconds
+	}
+
+	switch result.Storage {
+	case StoragePostgres, StorageMemory:
+	case StorageFile:
+		if result.StorageDir == "" {
+			return nil, ErrMissingStorageDir
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStorage, result.Storage)
+	}
+
+	err = readMintingSecrets(&result.Minting)
+	if err != nil {
+		return nil, err
+	}
+
+	setMintingDefaults(&result.Minting)
+
+	setRateLimitDefaults(&result.RateLimits)
+
+	err = readAuth(&result.Auth)
+	if err != nil {
+		return nil, err
+	}
+
 	return &result, nil
 }
+
+// readAuth reads the admin key from its environment variable, and checks the settings of the authentication.
+func readAuth(auth *AuthConfig) error {
+	if key := os.Getenv(AdminKeyVariable); key != "" {
+		auth.AdminKey = key
+	}
+
+	if auth.AdminKey != "" && len(auth.AdminKey) < MinAdminKey {
+		return ErrWeakAdminKey
+	}
+
+	if !auth.JWT.IsEnabled() {
+		return nil
+	}
+
+	if (auth.JWT.JWKSFile != "" && auth.JWT.JWKSURL != "") || auth.JWT.Issuer == "" || auth.JWT.Audience == "" {
+		return ErrIncompleteJWT
+	}
+
+	if auth.JWT.JWKSCacheSeconds <= 0 {
+		auth.JWT.JWKSCacheSeconds = defaultJWKSCacheSeconds
+	}
+
+	return nil
+}
+
+// readMintingSecrets checks the minting provider, and reads the API key from its file or environment variable.
+func readMintingSecrets(minting *MintingConfig) error {
+	switch minting.Provider {
+	case "", MinterNMKR, MinterFake:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownMinter, minting.Provider)
+	}
+
+	if minting.APIKeyFile != "" {
+		key, err := os.ReadFile(filepath.Clean(minting.APIKeyFile))
+		if err != nil {
+			return fmt.Errorf("could not read APIKeyFile: %w", err)
+		}
+
+		minting.APIKey = strings.TrimSpace(string(key))
+	}
+
+	if key := os.Getenv(NMKRAPIKeyVariable); key != "" {
+		minting.APIKey = key
+	}
+
+	return nil
+}
+
+// setMintingDefaults fills in the unset settings of the mint jobs.
+func setMintingDefaults(minting *MintingConfig) {
+	if minting.Workers <= 0 {
+		minting.Workers = defaultMintWorkers
+	}
+
+	if minting.MaxAttempts <= 0 {
+		minting.MaxAttempts = defaultMintMaxAttempts
+	}
+
+	if minting.RetryBaseSeconds <= 0 {
+		minting.RetryBaseSeconds = defaultMintRetryBaseSeconds
+	}
+
+	if minting.RetryMaxSeconds <= 0 {
+		minting.RetryMaxSeconds = defaultMintRetryMaxSeconds
+	}
+
+	if minting.LeaseSeconds <= 0 {
+		minting.LeaseSeconds = defaultMintLeaseSeconds
+	}
+}
+
+// setRateLimitDefaults fills in the unset settings of the rate limits.
+func setRateLimitDefaults(rateLimits *RateLimitsConfig) {
+	for _, limits := range rateLimits.Groups {
+		for position := range limits {
+			if limits[position].PeriodSeconds <= 0 {
+				limits[position].PeriodSeconds = defaultRateLimitPeriodSeconds
+			}
+
+			if limits[position].Burst <= 0 {
+				limits[position].Burst = limits[position].Requests
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Package geo offers geometry on the surface of the Earth: distances, bounding boxes and geohashes.
+*/
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EarthRadius is the mean radius of the Earth, in meters.
+	EarthRadius = 6_371_008.8
+	// MaxDistance is the largest distance between two points, in meters: half the circumference of the Earth.
+	MaxDistance = math.Pi * EarthRadius
+
+	// MaxLatitude and MaxLongitude bound the coordinates of points, in degrees.
+	MaxLatitude  = 90.0
+	MaxLongitude = 180.0
+
+	// pointValues and bboxValues are the number of coordinates in a point and in a bounding box.
+	pointValues = 2
+	bboxValues  = 4
+)
+
+var (
+	// ErrInvalidPoint for when a point is malformed, or out of range.
+	ErrInvalidPoint = errors.New("invalid point, expected lat,lon")
+	// ErrInvalidBBox for when a bounding box is malformed, or out of range.
+	ErrInvalidBBox = errors.New("invalid bounding box, expected minLat,minLon,maxLat,maxLon")
+)
+
+// Point models a position, in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// IsValid tells whether the latitude is in [-90, 90] and the longitude in [-180, 180].
+func (p Point) IsValid() bool {
+	return math.Abs(p.Latitude) <= MaxLatitude && math.Abs(p.Longitude) <= MaxLongitude
+}
+
+// ParsePoint parses a "lat,lon" point.
+func ParsePoint(raw string) (Point, error) {
+	values, err := parseFloats(raw, pointValues)
+	if err != nil {
+		return Point{}, fmt.Errorf("%w: %q", ErrInvalidPoint, raw)
+	}
+
+	result := Point{Latitude: values[0], Longitude: values[1]}
+	if !result.IsValid() {
+		return Point{}, fmt.Errorf("%w: %q", ErrInvalidPoint, raw)
+	}
+
+	return result, nil
+}
+
+// Distance returns the great-circle distance between two points, in meters, using the haversine formula.
+func Distance(from Point, to Point) float64 {
+	fromLatitude, toLatitude := radians(from.Latitude), radians(to.Latitude)
+	deltaLatitude := toLatitude - fromLatitude
+	deltaLongitude := radians(to.Longitude - from.Longitude)
+
+	haversine := math.Pow(math.Sin(deltaLatitude/2), 2) +
+		math.Cos(fromLatitude)*math.Cos(toLatitude)*math.Pow(math.Sin(deltaLongitude/2), 2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(haversine)))
+}
+
+// BBox models a bounding box, in degrees.
+//
+// When MinLongitude is greater than MaxLongitude, the box crosses the antimeridian.
+type BBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// ParseBBox parses a "minLat,minLon,maxLat,maxLon" bounding box.
+func ParseBBox(raw string) (BBox, error) {
+	values, err := parseFloats(raw, bboxValues)
+	if err != nil {
+		return BBox{}, fmt.Errorf("%w: %q", ErrInvalidBBox, raw)
+	}
+
+	result := BBox{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2], MaxLongitude: values[3]}
+
+	minimum := Point{Latitude: result.MinLatitude, Longitude: result.MinLongitude}
+	maximum := Point{Latitude: result.MaxLatitude, Longitude: result.MaxLongitude}
+
+	if !minimum.IsValid() || !maximum.IsValid() || result.MinLatitude > result.MaxLatitude {
+		return BBox{}, fmt.Errorf("%w: %q", ErrInvalidBBox, raw)
+	}
+
+	return result, nil
+}
+
+// Around returns the smallest bounding box holding every point within radius meters of center.
+func Around(center Point, radius float64) BBox {
+	delta := degrees(radius / EarthRadius)
+
+	result := BBox{
+		MinLatitude:  center.Latitude - delta,
+		MaxLatitude:  center.Latitude + delta,
+		MinLongitude: -MaxLongitude,
+		MaxLongitude: MaxLongitude,
+	}
+
+	// a box reaching a pole holds every longitude
+	if result.MinLatitude <= -MaxLatitude || result.MaxLatitude >= MaxLatitude {
+		result.MinLatitude = math.Max(result.MinLatitude, -MaxLatitude)
+		result.MaxLatitude = math.Min(result.MaxLatitude, MaxLatitude)
+
+		return result
+	}
+
+	// the widest point of a circle on a sphere is not on its center's parallel, hence the arcsine
+	ratio := math.Sin(radius/EarthRadius) / math.Cos(radians(center.Latitude))
+	if radius >= MaxDistance/2 || ratio >= 1 {
+		return result
+	}
+
+	deltaLongitude := degrees(math.Asin(ratio))
+
+	result.MinLongitude = wrapLongitude(center.Longitude - deltaLongitude)
+	result.MaxLongitude = wrapLongitude(center.Longitude + deltaLongitude)
+
+	return result
+}
+
+// Contains tells whether a point is inside the box, edges included.
+func (b BBox) Contains(point Point) bool {
+	if point.Latitude < b.MinLatitude || point.Latitude > b.MaxLatitude {
+		return false
+	}
+
+	if b.MinLongitude <= b.MaxLongitude {
+		return point.Longitude >= b.MinLongitude && point.Longitude <= b.MaxLongitude
+	}
+
+	return point.Longitude >= b.MinLongitude || point.Longitude <= b.MaxLongitude
+}
+
+// Split returns the box as boxes that do not cross the antimeridian: itself, or its two halves.
+func (b BBox) Split() []BBox {
+	if b.MinLongitude <= b.MaxLongitude {
+		return []BBox{b}
+	}
+
+	east, west := b, b
+	east.MaxLongitude = MaxLongitude
+	west.MinLongitude = -MaxLongitude
+
+	return []BBox{east, west}
+}
+
+// parseFloats parses count comma-separated numbers.
+func parseFloats(raw string, count int) ([]float64, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d numbers, got %d", count, len(parts))
+	}
+
+	result := make([]float64, 0, count)
+
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("could not parse %q", part)
+		}
+
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// wrapLongitude brings a longitude back into [-180, 180].
+func wrapLongitude(longitude float64) float64 {
+	switch {
+	case longitude < -MaxLongitude:
+		return longitude + 2*MaxLongitude
+	case longitude > MaxLongitude:
+		return longitude - 2*MaxLongitude
+	}
+
+	return longitude
+}
+
+// radians converts degrees to radians.
+func radians(value float64) float64 {
+	return value * math.Pi / MaxLongitude
+}
+
+// degrees converts radians to degrees.
+func degrees(value float64) float64 {
+	return value * MaxLongitude / math.Pi
+}
@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Distance(t *testing.T) {
+	paris := Point{Latitude: 48.8566, Longitude: 2.3522}
+	london := Point{Latitude: 51.5074, Longitude: -0.1278}
+
+	assert.InDelta(t, 343_500, Distance(paris, london), 1_000)
+	assert.InDelta(t, Distance(paris, london), Distance(london, paris), 1e-6)
+	assert.InDelta(t, 0, Distance(paris, paris), 1e-6)
+	assert.InDelta(t, MaxDistance, Distance(Point{Longitude: -90}, Point{Longitude: 90}), 1)
+}
+
+func Test_Parse(t *testing.T) {
+	point, err := ParsePoint("48.8566, 2.3522")
+	assert.NoError(t, err)
+	assert.Equal(t, Point{Latitude: 48.8566, Longitude: 2.3522}, point)
+
+	for _, raw := range []string{"", "1", "91,0", "0,181", "a,b", "1,2,3", "NaN,0"} {
+		_, err = ParsePoint(raw)
+		assert.ErrorIs(t, err, ErrInvalidPoint, raw)
+	}
+
+	box, err := ParseBBox("-10,170,10,-170")
+	assert.NoError(t, err)
+	assert.True(t, box.Contains(Point{Longitude: 179}))
+	assert.True(t, box.Contains(Point{Longitude: -179}))
+	assert.False(t, box.Contains(Point{Longitude: 0}))
+	assert.Len(t, box.Split(), 2)
+
+	for _, raw := range []string{"", "1,2,3", "10,0,-10,0", "-91,0,0,0"} {
+		_, err = ParseBBox(raw)
+		assert.ErrorIs(t, err, ErrInvalidBBox, raw)
+	}
+}
+
+func Test_Around(t *testing.T) {
+	random := rand.New(rand.NewSource(42)) //nolint:gosec
+
+	centers := []Point{{}, {Latitude: 60, Longitude: 179.9}, {Latitude: -89.99}, {Latitude: 45, Longitude: -45}}
+
+	for _, center := range centers {
+		for _, radius := range []float64{10, 10_000, 1_000_000} {
+			box := Around(center, radius)
+
+			for range 1_000 {
+				point := Point{Latitude: random.Float64()*180 - 90, Longitude: random.Float64()*360 - 180}
+				if Distance(center, point) <= radius {
+					assert.True(t, box.Contains(point), "%v should be within %v of %v", point, radius, center)
+				}
+			}
+
+			// a box always holds its center
+			assert.True(t, box.Contains(center))
+		}
+	}
+}
+
+func Test_Geohash(t *testing.T) {
+	assert.Equal(t, "u4pruydqq", Geohash(Point{Latitude: 57.64911, Longitude: 10.40744}, GeohashPrecision))
+	assert.Equal(t, "s0000", Geohash(Point{}, 5))
+	assert.Equal(t, "zzzz", Geohash(Point{Latitude: 90, Longitude: 180}, 4))
+
+	random := rand.New(rand.NewSource(42)) //nolint:gosec
+
+	for _, box := range []BBox{
+		{MinLatitude: 48.80, MinLongitude: 2.25, MaxLatitude: 48.90, MaxLongitude: 2.42},
+		{MinLatitude: -10, MinLongitude: -10, MaxLatitude: 10, MaxLongitude: 10},
+		{MinLatitude: -90, MinLongitude: -180, MaxLatitude: 90, MaxLongitude: 180},
+	} {
+		cells := Cover(box, 16)
+		assert.LessOrEqual(t, len(cells), 16)
+
+		for range 1_000 {
+			point := Point{
+				Latitude:  box.MinLatitude + random.Float64()*(box.MaxLatitude-box.MinLatitude),
+				Longitude: box.MinLongitude + random.Float64()*(box.MaxLongitude-box.MinLongitude),
+			}
+			hash := Geohash(point, GeohashPrecision)
+
+			covered := false
+
+			for _, cell := range cells {
+				covered = covered || strings.HasPrefix(hash, cell)
+			}
+
+			assert.True(t, covered, "%v (%s) should be covered by %v", point, hash, cells)
+		}
+	}
+}
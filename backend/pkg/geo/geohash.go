@@ -0,0 +1,120 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	// GeohashPrecision is the number of characters of the geohashes kept for locations (cells of about 5 meters).
+	//
+	// It must match the precision used by the locations_geohash trigger (see migration 0007).
+	GeohashPrecision = 9
+
+	// geohashAlphabet is the base 32 alphabet of geohashes.
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+	// bitsPerCharacter is the number of bits a geohash character holds.
+	bitsPerCharacter = 5
+)
+
+// Geohash returns the geohash of a point, with a given number of characters.
+//
+// Bits alternate between longitude and latitude, starting with longitude; a bit is set when the point is in the
+// upper half of the remaining range.
+func Geohash(point Point, precision int) string {
+	latitudes := [2]float64{-MaxLatitude, MaxLatitude}
+	longitudes := [2]float64{-MaxLongitude, MaxLongitude}
+
+	var result strings.Builder
+
+	even := true
+	character := 0
+
+	for bit := 0; bit < precision*bitsPerCharacter; bit++ {
+		character <<= 1
+
+		if even {
+			character |= halve(&longitudes, point.Longitude)
+		} else {
+			character |= halve(&latitudes, point.Latitude)
+		}
+
+		even = !even
+
+		if bit%bitsPerCharacter == bitsPerCharacter-1 {
+			result.WriteByte(geohashAlphabet[character])
+			character = 0
+		}
+	}
+
+	return result.String()
+}
+
+// Cover returns geohash cells, of the same precision, that together hold every point of a box which does not cross
+// the antimeridian (see BBox.Split).
+//
+// It picks the finest precision (up to GeohashPrecision) that needs at most maxCells cells; when even one character
+// is too fine, it returns a single empty cell, which holds the whole world.
+func Cover(box BBox, maxCells int) []string {
+	for precision := GeohashPrecision; precision > 0; precision-- {
+		height, width := cellSize(precision)
+
+		firstRow := cellIndex(box.MinLatitude, MaxLatitude, height)
+		lastRow := cellIndex(box.MaxLatitude, MaxLatitude, height)
+		firstColumn := cellIndex(box.MinLongitude, MaxLongitude, width)
+		lastColumn := cellIndex(box.MaxLongitude, MaxLongitude, width)
+
+		if (lastRow-firstRow+1)*(lastColumn-firstColumn+1) > maxCells {
+			continue
+		}
+
+		result := make([]string, 0, (lastRow-firstRow+1)*(lastColumn-firstColumn+1))
+
+		for row := firstRow; row <= lastRow; row++ {
+			for column := firstColumn; column <= lastColumn; column++ {
+				center := Point{
+					Latitude:  -MaxLatitude + (float64(row)+0.5)*height,
+					Longitude: -MaxLongitude + (float64(column)+0.5)*width,
+				}
+
+				result = append(result, Geohash(center, precision))
+			}
+		}
+
+		return result
+	}
+
+	return []string{""}
+}
+
+// halve keeps the half of a range that holds value, and returns 1 for the upper half, 0 for the lower one.
+func halve(bounds *[2]float64, value float64) int {
+	middle := (bounds[0] + bounds[1]) / 2
+
+	if value >= middle {
+		bounds[0] = middle
+
+		return 1
+	}
+
+	bounds[1] = middle
+
+	return 0
+}
+
+// cellSize returns the height and the width, in degrees, of the geohash cells with a given number of characters.
+func cellSize(precision int) (float64, float64) {
+	bits := precision * bitsPerCharacter
+	longitudeBits := (bits + 1) / 2
+	latitudeBits := bits / 2
+
+	return 2 * MaxLatitude / math.Exp2(float64(latitudeBits)), 2 * MaxLongitude / math.Exp2(float64(longitudeBits))
+}
+
+// cellIndex returns the index of the cell of a given size holding a coordinate, counting from -limit.
+func cellIndex(coordinate float64, limit float64, size float64) int {
+	index := int(math.Floor((coordinate + limit) / size))
+
+	// the upper limit belongs to the last cell
+	return min(index, int(2*limit/size)-1)
+}
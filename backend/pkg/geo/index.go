@@ -0,0 +1,300 @@
+package geo
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// dimensions of the positions kept by an Index: points on the unit sphere.
+	dimensions = 3
+	// minStale is the number of stale nodes an Index always tolerates before balancing its tree again.
+	minStale = 64
+)
+
+// Neighbor models a value of an Index, along with its distance from a point, in meters.
+type Neighbor[T any] struct {
+	Value    T
+	Distance float64
+}
+
+// Index finds the values nearest to a point.
+//
+// It is a k-d tree over the positions of the values on the unit sphere, where the straight-line distance grows with
+// the great-circle one, so the antimeridian and the poles need no special care. It is safe for concurrent use:
+// readers share the tree, and only wait for the changes to it, which are brief.
+type Index[T any] struct {
+	mutex sync.RWMutex
+	root  *kdNode[T]
+	nodes map[int]*kdNode[T]
+	// stale counts the removed nodes still in the tree, and the nodes added since it was last balanced.
+	stale int
+}
+
+// kdNode models a value in the tree of an Index.
+type kdNode[T any] struct {
+	id     int
+	point  Point
+	vector [dimensions]float64
+	value  T
+	// axis is the dimension splitting the children: the left ones are below the node on it.
+	axis        int
+	left, right *kdNode[T]
+	// removed nodes stay in the tree, to guide searches, until it is balanced again.
+	removed bool
+}
+
+// NewIndex builds a new, empty, Index.
+func NewIndex[T any]() *Index[T] {
+	return &Index[T]{nodes: make(map[int]*kdNode[T])}
+}
+
+// Set adds the value with a given ID at a given point, or moves it there.
+func (i *Index[T]) Set(id int, point Point, value T) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if previous, found := i.nodes[id]; found {
+		previous.removed = true
+		i.stale++
+	}
+
+	node := &kdNode[T]{id: id, point: point, vector: unitVector(point), value: value}
+	i.nodes[id] = node
+	i.insert(node)
+	i.stale++
+
+	i.rebalance()
+}
+
+// Remove the value with a given ID, if any.
+func (i *Index[T]) Remove(id int) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	node, found := i.nodes[id]
+	if !found {
+		return
+	}
+
+	node.removed = true
+	delete(i.nodes, id)
+	i.stale++
+
+	i.rebalance()
+}
+
+// Len returns the number of values in the index.
+func (i *Index[T]) Len() int {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	return len(i.nodes)
+}
+
+// Nearest returns (at most) the k values nearest to a point, closest first.
+func (i *Index[T]) Nearest(point Point, k int) []Neighbor[T] {
+	if k <= 0 {
+		return []Neighbor[T]{}
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	search := &kdSearch[T]{target: unitVector(point), k: k}
+	search.visit(i.root)
+
+	// the heap yields the farthest node first
+	result := make([]Neighbor[T], search.found.Len())
+
+	for index := len(result) - 1; index >= 0; index-- {
+		candidate, _ := heap.Pop(&search.found).(kdCandidate[T])
+		result[index] = Neighbor[T]{Value: candidate.node.value, Distance: Distance(point, candidate.node.point)}
+	}
+
+	return result
+}
+
+// insert a node as a new leaf of the tree. Callers must hold the write lock.
+func (i *Index[T]) insert(node *kdNode[T]) {
+	if i.root == nil {
+		i.root = node
+
+		return
+	}
+
+	parent := i.root
+
+	for {
+		child := &parent.right
+		if node.vector[parent.axis] < parent.vector[parent.axis] {
+			child = &parent.left
+		}
+
+		if *child == nil {
+			node.axis = (parent.axis + 1) % dimensions
+			*child = node
+
+			return
+		}
+
+		parent = *child
+	}
+}
+
+// rebalance builds the tree again, from the values in the index, when too many of its nodes are stale.
+//
+// Callers must hold the write lock.
+func (i *Index[T]) rebalance() {
+	if i.stale <= max(len(i.nodes), minStale) {
+		return
+	}
+
+	nodes := make([]*kdNode[T], 0, len(i.nodes))
+	for _, node := range i.nodes {
+		nodes = append(nodes, node)
+	}
+
+	i.root = build(nodes, 0)
+	i.stale = 0
+}
+
+// build returns a balanced tree of nodes, split on a given axis first.
+func build[T any](nodes []*kdNode[T], axis int) *kdNode[T] {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	sort.Slice(nodes, func(a, b int) bool { return nodes[a].vector[axis] < nodes[b].vector[axis] })
+
+	middle := len(nodes) / 2
+	// equal coordinates belong to the right
+	for middle > 0 && nodes[middle-1].vector[axis] == nodes[middle].vector[axis] {
+		middle--
+	}
+
+	node := nodes[middle]
+	node.axis = axis
+	node.left = build(nodes[:middle], (axis+1)%dimensions)
+	node.right = build(nodes[middle+1:], (axis+1)%dimensions)
+
+	return node
+}
+
+// kdSearch keeps the k nodes nearest to a target found so far.
+type kdSearch[T any] struct {
+	target [dimensions]float64
+	k      int
+	found  kdHeap[T]
+}
+
+// visit looks for nearer nodes in a subtree, starting with the side of the target.
+func (s *kdSearch[T]) visit(node *kdNode[T]) {
+	if node == nil {
+		return
+	}
+
+	delta := s.target[node.axis] - node.vector[node.axis]
+
+	near, far := node.left, node.right
+	if delta >= 0 {
+		near, far = node.right, node.left
+	}
+
+	s.visit(near)
+
+	if !node.removed {
+		s.offer(node)
+	}
+
+	// the far side can only hold nearer nodes when the splitting plane is nearer than the farthest node kept
+	if len(s.found.nodes) < s.k || delta*delta < s.found.distances[0] {
+		s.visit(far)
+	}
+}
+
+// offer a node, which is kept when it is among the k nearest seen so far.
+func (s *kdSearch[T]) offer(node *kdNode[T]) {
+	distance := squaredDistance(s.target, node.vector)
+
+	if len(s.found.nodes) < s.k {
+		heap.Push(&s.found, kdCandidate[T]{node: node, distance: distance})
+
+		return
+	}
+
+	if distance < s.found.distances[0] {
+		s.found.nodes[0], s.found.distances[0] = node, distance
+		heap.Fix(&s.found, 0)
+	}
+}
+
+// kdCandidate models a node found by a search, and its squared distance to the target.
+type kdCandidate[T any] struct {
+	node     *kdNode[T]
+	distance float64
+}
+
+// kdHeap keeps nodes as a heap, with the farthest one (or the one with the greatest ID, among equals) on top.
+type kdHeap[T any] struct {
+	nodes     []*kdNode[T]
+	distances []float64
+}
+
+func (h *kdHeap[T]) Len() int {
+	return len(h.nodes)
+}
+
+func (h *kdHeap[T]) Less(a, b int) bool {
+	if h.distances[a] != h.distances[b] {
+		return h.distances[a] > h.distances[b]
+	}
+
+	return h.nodes[a].id > h.nodes[b].id
+}
+
+func (h *kdHeap[T]) Swap(a, b int) {
+	h.nodes[a], h.nodes[b] = h.nodes[b], h.nodes[a]
+	h.distances[a], h.distances[b] = h.distances[b], h.distances[a]
+}
+
+func (h *kdHeap[T]) Push(item any) {
+	candidate, _ := item.(kdCandidate[T])
+
+	h.nodes = append(h.nodes, candidate.node)
+	h.distances = append(h.distances, candidate.distance)
+}
+
+func (h *kdHeap[T]) Pop() any {
+	last := len(h.nodes) - 1
+	result := kdCandidate[T]{node: h.nodes[last], distance: h.distances[last]}
+
+	h.nodes, h.distances = h.nodes[:last], h.distances[:last]
+
+	return result
+}
+
+// unitVector returns the position of a point on the unit sphere.
+func unitVector(point Point) [dimensions]float64 {
+	latitude, longitude := radians(point.Latitude), radians(point.Longitude)
+
+	return [dimensions]float64{
+		math.Cos(latitude) * math.Cos(longitude),
+		math.Cos(latitude) * math.Sin(longitude),
+		math.Sin(latitude),
+	}
+}
+
+// squaredDistance returns the square of the straight-line distance between two positions.
+func squaredDistance(from [dimensions]float64, to [dimensions]float64) float64 {
+	result := 0.0
+
+	for axis := range dimensions {
+		delta := from[axis] - to[axis]
+		result += delta * delta
+	}
+
+	return result
+}
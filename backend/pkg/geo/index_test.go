@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Index(t *testing.T) {
+	random := rand.New(rand.NewSource(42)) //nolint:gosec
+	index := NewIndex[int]()
+	points := make(map[int]Point)
+
+	randomPoint := func() Point {
+		return Point{Latitude: random.Float64()*180 - 90, Longitude: random.Float64()*360 - 180}
+	}
+
+	for id := range 2_000 {
+		points[id] = randomPoint()
+		index.Set(id, points[id], id)
+	}
+
+	// move some values, and remove others, so the tree gets balanced again
+	for id := range 1_000 {
+		if id%2 == 0 {
+			points[id] = randomPoint()
+			index.Set(id, points[id], id)
+		} else {
+			delete(points, id)
+			index.Remove(id)
+		}
+	}
+
+	assert.Equal(t, len(points), index.Len())
+
+	for range 200 {
+		target := randomPoint()
+		found := index.Nearest(target, 10)
+
+		assert.Equal(t, nearest(points, target, 10), found)
+	}
+
+	assert.Empty(t, index.Nearest(Point{}, 0))
+	assert.Len(t, index.Nearest(Point{}, 5_000), len(points))
+
+	// across the antimeridian
+	index = NewIndex[int]()
+	index.Set(1, Point{Longitude: 179.9}, 1)
+	index.Set(2, Point{Longitude: -170}, 2)
+	index.Set(3, Point{Longitude: 175}, 3)
+
+	found := index.Nearest(Point{Longitude: -179.9}, 2)
+	assert.Equal(t, []int{1, 3}, []int{found[0].Value, found[1].Value})
+	assert.InDelta(t, 22_239, found[0].Distance, 1)
+}
+
+func Test_IndexConcurrency(t *testing.T) {
+	index := NewIndex[int]()
+
+	var waitGroup sync.WaitGroup
+
+	for worker := range 4 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for id := range 500 {
+				index.Set(worker*1_000+id, Point{Latitude: float64(id % 90), Longitude: float64(worker)}, id)
+				index.Nearest(Point{Latitude: float64(id % 90)}, 3)
+
+				if id%3 == 0 {
+					index.Remove(worker*1_000 + id)
+				}
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+
+	assert.Equal(t, 4*(500-167), index.Len())
+}
+
+// nearest returns the k values nearest to a target by brute force, closest first.
+func nearest(points map[int]Point, target Point, k int) []Neighbor[int] {
+	result := make([]Neighbor[int], 0, len(points))
+
+	for id, point := range points {
+		result = append(result, Neighbor[int]{Value: id, Distance: Distance(target, point)})
+	}
+
+	sort.Slice(result, func(a, b int) bool { return result[a].Distance < result[b].Distance })
+
+	return result[:k]
+}
@@ -0,0 +1,161 @@
+/*
+Package imaging validates uploaded images, and shrinks them into thumbnails, with the image packages of the standard
+library only.
+*/
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxPixels is the largest number of pixels of an image, so that small files cannot expand into huge images.
+	MaxPixels = 25_000_000
+
+	// jpegQuality is the quality of the JPEG thumbnails.
+	jpegQuality = 85
+	// channels is the number of channels of a pixel: red, green, blue and alpha.
+	channels = 4
+)
+
+var (
+	// ErrUnsupportedImage for when content is not a JPEG, PNG or GIF image, or is too large.
+	ErrUnsupportedImage = errors.New("unsupported image, expected JPEG, PNG or GIF")
+)
+
+// decoders of the supported content types, as sniffed by http.DetectContentType.
+var decoders = map[string]func(data []byte) (image.Image, error){
+	"image/jpeg": func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+	"image/png":  func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) },
+	"image/gif":  func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) },
+}
+
+// Decode sniffs the content type of an image, and decodes it.
+//
+// The content type comes from the content itself, never from what the uploader claims. Returns ErrUnsupportedImage
+// when it is not a supported image, or when it has more than MaxPixels pixels.
+func Decode(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+
+	decode, found := decoders[contentType]
+	if !found {
+		return nil, "", fmt.Errorf("%w: got %s", ErrUnsupportedImage, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d pixels, at most %d", ErrUnsupportedImage, config.Width, config.Height,
+			MaxPixels)
+	}
+
+	result, err := decode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+
+	return result, contentType, nil
+}
+
+// Thumbnail shrinks an image to fit in a size x size square, keeping its proportions. Smaller images keep their
+// size.
+//
+// Every pixel of the thumbnail is the average of the pixels it covers in the source (a box filter).
+func Thumbnail(source image.Image, size int) *image.RGBA {
+	bounds := source.Bounds()
+
+	// draw converts the common image types quickly, and RGBA pixels are premultiplied, so they can be averaged
+	pixels := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(pixels, pixels.Bounds(), source, bounds.Min, draw.Src)
+
+	width, height := fit(bounds.Dx(), bounds.Dy(), size)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return pixels
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		top, bottom := span(y, height, bounds.Dy())
+
+		for x := range width {
+			left, right := span(x, width, bounds.Dx())
+
+			var sums [channels]int
+
+			for row := top; row < bottom; row++ {
+				offset := pixels.PixOffset(left, row)
+
+				for column := left; column < right; column++ {
+					for channel := range channels {
+						sums[channel] += int(pixels.Pix[offset+channel])
+					}
+
+					offset += channels
+				}
+			}
+
+			count := (bottom - top) * (right - left)
+			offset := result.PixOffset(x, y)
+
+			for channel := range channels {
+				result.Pix[offset+channel] = uint8((sums[channel] + count/2) / count)
+			}
+		}
+	}
+
+	return result
+}
+
+// Encode a thumbnail: as a JPEG when it is opaque, as a PNG otherwise. Returns the content and its content type.
+func Encode(thumbnail *image.RGBA) ([]byte, string, error) {
+	var output bytes.Buffer
+
+	if thumbnail.Opaque() {
+		err := jpeg.Encode(&output, thumbnail, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, "", fmt.Errorf("could not encode JPEG: %w", err)
+		}
+
+		return output.Bytes(), "image/jpeg", nil
+	}
+
+	err := png.Encode(&output, thumbnail)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not encode PNG: %w", err)
+	}
+
+	return output.Bytes(), "image/png", nil
+}
+
+// fit returns the dimensions of an image of a given width and height, once shrunk to fit in a size x size square.
+func fit(width int, height int, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+
+	if width >= height {
+		return size, max(1, (height*size+width/2)/width)
+	}
+
+	return max(1, (width*size+height/2)/height), size
+}
+
+// span returns the range of source pixels, along one axis, covered by a thumbnail pixel.
+func span(index int, thumbnailLength int, sourceLength int) (int, int) {
+	start := index * sourceLength / thumbnailLength
+	end := (index + 1) * sourceLength / thumbnailLength
+
+	return start, max(end, start+1)
+}
@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Thumbnail(t *testing.T) {
+	// left half black, right half white
+	source := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+
+	for y := range 200 {
+		for x := 200; x < 400; x++ {
+			source.Set(x, y, color.White)
+		}
+
+		for x := range 200 {
+			source.Set(x, y, color.Black)
+		}
+	}
+
+	var encoded bytes.Buffer
+
+	assert.NoError(t, png.Encode(&encoded, source))
+
+	decoded, contentType, err := Decode(encoded.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	thumbnail := Thumbnail(decoded, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumbnail.Bounds())
+	assert.Equal(t, color.RGBA{A: 255}, thumbnail.RGBAAt(10, 10))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, thumbnail.RGBAAt(90, 40))
+
+	// smaller images keep their size
+	assert.Equal(t, image.Rect(0, 0, 400, 200), Thumbnail(decoded, 1_000).Bounds())
+
+	thumbnailBytes, contentType, err := Encode(thumbnail)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+
+	_, contentType, err = Decode(thumbnailBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+
+	_, _, err = Decode([]byte("<svg></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
@@ -0,0 +1,283 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is how long the keys fetched from a URL are used before being fetched again.
+	DefaultCacheTTL = time.Hour
+	// MinRefresh is the shortest time between two fetches of the keys, so that tokens signed with unknown keys
+	// cannot flood the server of the keys.
+	MinRefresh = 30 * time.Second
+	// MinRSABits is the size of the smallest RSA keys that are used; smaller ones are left out.
+	MinRSABits = 2048
+
+	// minRSAExponent is the smallest valid public exponent of RSA keys.
+	minRSAExponent = 3
+	// maxKeySetBytes bounds the size of a key set.
+	maxKeySetBytes = 1 << 20
+	// p256Size is the number of bytes of a coordinate on the P-256 curve.
+	p256Size = 32
+	// uncompressedPoint starts the uncompressed encoding of a point on a curve.
+	uncompressedPoint = 4
+)
+
+// Key models a public key of a set.
+type Key struct {
+	ID string
+	// Algorithm is the only algorithm the key can be used with; any that fits the key when empty.
+	Algorithm string
+	// Public is an *rsa.PublicKey, an *ecdsa.PublicKey (on P-256) or an ed25519.PublicKey.
+	Public crypto.PublicKey
+}
+
+// fits tells whether the key can check signatures made with a given algorithm.
+func (k Key) fits(algorithm string) bool {
+	if k.Algorithm != "" && k.Algorithm != algorithm {
+		return false
+	}
+
+	switch k.Public.(type) {
+	case *rsa.PublicKey:
+		return algorithm == AlgorithmRS256
+	case *ecdsa.PublicKey:
+		return algorithm == AlgorithmES256
+	case ed25519.PublicKey:
+		return algorithm == AlgorithmEdDSA
+	}
+
+	return false
+}
+
+// KeySet is a set of keys that does not change.
+type KeySet []Key
+
+var _ KeySource = KeySet{}
+
+// Key returns the key with a given ID (the first one when empty) that fits an algorithm.
+func (ks KeySet) Key(_ context.Context, id string, algorithm string) (Key, error) {
+	for _, key := range ks {
+		if (id == "" || key.ID == id) && key.fits(algorithm) {
+			return key, nil
+		}
+	}
+
+	return Key{}, ErrUnknownKey
+}
+
+// jwk models a JSON Web Key; only the members of RSA, P-256 and Ed25519 public keys are read.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// ParseKeySet reads a JSON Web Key Set: {"keys": [...]}.
+//
+// Keys that are not meant for signatures, or that are not RSA (of at least MinRSABits), P-256 or Ed25519 keys, are
+// left out; malformed ones are errors.
+func ParseKeySet(data []byte) (KeySet, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal key set: %w", err)
+	}
+
+	result := make(KeySet, 0, len(set.Keys))
+
+	for _, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		public, err := raw.public()
+		if err != nil {
+			return nil, fmt.Errorf("could not read key %q: %w", raw.KeyID, err)
+		}
+
+		if public != nil {
+			result = append(result, Key{ID: raw.KeyID, Algorithm: raw.Algorithm, Public: public})
+		}
+	}
+
+	return result, nil
+}
+
+// public returns the public key, or nil when it is not supported.
+func (j jwk) public() (crypto.PublicKey, error) {
+	switch {
+	case j.KeyType == "RSA":
+		n, err := decodeInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(j.E)
+		if err != nil || !e.IsInt64() || e.Int64() < minRSAExponent || e.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: invalid exponent", ErrMalformed)
+		}
+
+		if n.BitLen() < MinRSABits {
+			return nil, nil
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case j.KeyType == "EC" && j.Curve == "P-256":
+		x, errX := base64.RawURLEncoding.DecodeString(j.X)
+		y, errY := base64.RawURLEncoding.DecodeString(j.Y)
+
+		if errX != nil || errY != nil || len(x) != p256Size || len(y) != p256Size {
+			return nil, fmt.Errorf("%w: invalid coordinates", ErrMalformed)
+		}
+
+		// ecdh checks that the point is on the curve
+		_, err := ecdh.P256().NewPublicKey(append(append([]byte{uncompressedPoint}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case j.KeyType == "OKP" && j.Curve == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid public key", ErrMalformed)
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, nil
+}
+
+// decodeInt decodes a base64url-encoded, big-endian, unsigned integer.
+func decodeInt(raw string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(decoded) == 0 {
+		return nil, fmt.Errorf("%w: invalid integer", ErrMalformed)
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// ReadKeySet reads the key set of a file.
+func ReadKeySet(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	return ParseKeySet(data)
+}
+
+// Remote is a key set fetched from a URL, and kept for a while.
+//
+// The keys are fetched again once they are older than the TTL, or when a token names a key they do not hold (the
+// keys were rotated); at most once per MinRefresh. When fetching fails, the previous keys are kept.
+type Remote struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	// mutex guards the fields below, and lets a single request fetch the keys at a time
+	mutex       sync.Mutex
+	keys        KeySet
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	now         func() time.Time
+}
+
+var _ KeySource = &Remote{}
+
+// NewRemote builds the key set of a URL, kept for ttl (DefaultCacheTTL when zero).
+func NewRemote(url string, client *http.Client, ttl time.Duration) *Remote {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return &Remote{url: url, client: client, ttl: ttl, now: time.Now}
+}
+
+// Key returns the key with a given ID (the first one when empty) that fits an algorithm, fetching the keys when
+// needed.
+func (r *Remote) Key(ctx context.Context, id string, algorithm string) (Key, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+
+	result, err := r.keys.Key(ctx, id, algorithm)
+
+	stale := r.keys == nil || now.Sub(r.fetchedAt) >= r.ttl
+	if (stale || err != nil) && now.Sub(r.attemptedAt) >= MinRefresh {
+		r.attemptedAt = now
+
+		keys, fetchErr := r.fetch(ctx)
+		if fetchErr != nil {
+			log.Default().Printf("could not fetch signing keys: %s", fetchErr)
+		} else {
+			r.keys, r.fetchedAt = keys, now
+			result, err = r.keys.Key(ctx, id, algorithm)
+		}
+	}
+
+	if r.keys == nil {
+		return Key{}, ErrKeysUnavailable
+	}
+
+	return result, err
+}
+
+// fetch the key set.
+func (r *Remote) fetch(ctx context.Context) (KeySet, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request: %w", err)
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(response.Body, maxKeySetBytes))
+	if err != nil {
+		return nil, fmt.Errorf("could not read response: %w", err)
+	}
+
+	return ParseKeySet(raw)
+}
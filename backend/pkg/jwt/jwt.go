@@ -0,0 +1,286 @@
+/*
+Package jwt verifies JSON Web Tokens (RFC 7519) signed with RS256, ES256 or EdDSA, with the keys of a JSON Web Key
+Set (RFC 7517) read from a file or fetched from a URL.
+*/
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// AlgorithmRS256 signs with RSASSA-PKCS1-v1_5 and SHA-256.
+	AlgorithmRS256 = "RS256"
+	// AlgorithmES256 signs with ECDSA, on the P-256 curve, and SHA-256.
+	AlgorithmES256 = "ES256"
+	// AlgorithmEdDSA signs with Ed25519.
+	AlgorithmEdDSA = "EdDSA"
+
+	// tokenParts is the number of dot-separated parts of a token: header, payload and signature.
+	tokenParts = 3
+	// es256Size is the number of bytes of each of the two halves (r and s) of an ES256 signature.
+	es256Size = 32
+)
+
+var (
+	// ErrMalformed for when a token cannot be decoded.
+	ErrMalformed = errors.New("malformed token")
+	// ErrUnsupportedAlgorithm for when a token is signed with another algorithm than RS256, ES256 or EdDSA.
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrUnknownKey for when no key of the set can check the signature of a token.
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrInvalidSignature for when the signature of a token does not match its content.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrMissingExpiry for when a token has no exp claim; tokens that never expire are refused.
+	ErrMissingExpiry = errors.New("missing expiry")
+	// ErrExpired for when a token expired.
+	ErrExpired = errors.New("token expired")
+	// ErrNotYetValid for when a token is used before its nbf claim.
+	ErrNotYetValid = errors.New("token not valid yet")
+	// ErrInvalidIssuer for when a token was issued by someone else.
+	ErrInvalidIssuer = errors.New("invalid issuer")
+	// ErrInvalidAudience for when a token was issued for someone else.
+	ErrInvalidAudience = errors.New("invalid audience")
+	// ErrKeysUnavailable for when the key set could not be read; the token may well be valid.
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// Claims of a verified token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	// NotBefore is zero when the token has no nbf claim.
+	NotBefore time.Time
+	// Raw holds every claim, as decoded from JSON.
+	Raw map[string]any
+}
+
+// KeySource finds the key to check the signature of a token with.
+type KeySource interface {
+	// Key returns the key with a given ID (any key when empty) that fits an algorithm. Returns ErrUnknownKey when
+	// there is none, and ErrKeysUnavailable when the keys could not be read.
+	Key(ctx context.Context, id string, algorithm string) (Key, error)
+}
+
+// Verifier checks the signature and the claims of tokens.
+type Verifier struct {
+	keys KeySource
+	// issuer and audience, when not empty, must match the iss and aud claims of tokens.
+	issuer   string
+	audience string
+	// leeway is the clock skew tolerated when checking exp and nbf.
+	leeway time.Duration
+	now    func() time.Time
+}
+
+// NewVerifier builds a verifier of the tokens signed with the keys of a source, and issued by issuer for audience
+// (when not empty).
+func NewVerifier(keys KeySource, issuer string, audience string, leeway time.Duration) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   leeway,
+		now:      time.Now,
+	}
+}
+
+// header models the header of a token.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// Critical lists extensions that must be understood; none are.
+	Critical []string `json:"crit"`
+}
+
+// registered models the registered claims of a token that are checked.
+type registered struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// audience is an aud claim: a single string, or an array of strings.
+type audience []string
+
+// UnmarshalJSON reads a string, or an array of strings.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+
+	if json.Unmarshal(data, &single) == nil {
+		*a = audience{single}
+
+		return nil
+	}
+
+	var several []string
+
+	err := json.Unmarshal(data, &several)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal audience: %w", err)
+	}
+
+	*a = several
+
+	return nil
+}
+
+// Verify checks the signature of a token, then its claims, and returns them.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != tokenParts {
+		return Claims{}, ErrMalformed
+	}
+
+	var head header
+
+	err := decodePart(parts[0], &head)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if head.Algorithm != AlgorithmRS256 && head.Algorithm != AlgorithmES256 && head.Algorithm != AlgorithmEdDSA {
+		return Claims{}, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, head.Algorithm)
+	}
+
+	if len(head.Critical) != 0 {
+		return Claims{}, fmt.Errorf("%w: unsupported critical extensions %v", ErrMalformed, head.Critical)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	key, err := v.keys.Key(ctx, head.KeyID, head.Algorithm)
+	if err != nil {
+		return Claims{}, fmt.Errorf("could not find signing key: %w", err)
+	}
+
+	if !key.verify(head.Algorithm, []byte(parts[0]+"."+parts[1]), signature) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	result, err := decodeClaims(parts[1])
+	if err != nil {
+		return Claims{}, err
+	}
+
+	err = v.check(result)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return result, nil
+}
+
+// check the time, issuer and audience claims of a token.
+func (v *Verifier) check(claims Claims) error {
+	now := v.now()
+
+	switch {
+	case !now.Before(claims.ExpiresAt.Add(v.leeway)):
+		return ErrExpired
+	case !claims.NotBefore.IsZero() && now.Before(claims.NotBefore.Add(-v.leeway)):
+		return ErrNotYetValid
+	case v.issuer != "" && claims.Issuer != v.issuer:
+		return ErrInvalidIssuer
+	case v.audience != "" && !slices.Contains(claims.Audience, v.audience):
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+// verify tells whether a signature of content matches the key, with a given algorithm.
+func (k Key) verify(algorithm string, content []byte, signature []byte) bool {
+	if !k.fits(algorithm) {
+		return false
+	}
+
+	sum := sha256.Sum256(content)
+
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, sum[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 2*es256Size {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(signature[:es256Size])
+		s := new(big.Int).SetBytes(signature[es256Size:])
+
+		return ecdsa.Verify(public, sum[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, content, signature)
+	}
+
+	return false
+}
+
+// decodeClaims decodes the payload of a token. Returns ErrMissingExpiry when it has no exp claim.
+func decodeClaims(payload string) (Claims, error) {
+	var claims registered
+
+	err := decodePart(payload, &claims)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	result := Claims{Subject: claims.Subject, Issuer: claims.Issuer, Audience: claims.Audience}
+
+	err = decodePart(payload, &result.Raw)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return Claims{}, ErrMissingExpiry
+	}
+
+	result.ExpiresAt = numericDate(*claims.ExpiresAt)
+
+	if claims.NotBefore != nil {
+		result.NotBefore = numericDate(*claims.NotBefore)
+	}
+
+	return result, nil
+}
+
+// decodePart decodes a base64url-encoded JSON part of a token.
+func decodePart(part string, result any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	err = json.Unmarshal(raw, result)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	return nil
+}
+
+// numericDate converts a number of seconds since the epoch (possibly with a fraction) to a time.
+func numericDate(seconds float64) time.Time {
+	return time.UnixMilli(int64(seconds * float64(time.Second/time.Millisecond)))
+}
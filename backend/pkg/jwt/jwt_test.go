@@ -0,0 +1,276 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// signer signs test tokens, and describes its key as a JWK.
+type signer struct {
+	id        string
+	algorithm string
+	private   crypto.Signer
+}
+
+// newSigners returns a signer for each supported algorithm.
+func newSigners(t *testing.T) []signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSABits)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	return []signer{
+		{id: "rsa", algorithm: AlgorithmRS256, private: rsaKey},
+		{id: "ec", algorithm: AlgorithmES256, private: ecKey},
+		{id: "ed", algorithm: AlgorithmEdDSA, private: edKey},
+	}
+}
+
+// sign returns a token holding claims.
+func (s signer) sign(t *testing.T, claims map[string]any) string {
+	head, err := json.Marshal(map[string]any{"alg": s.algorithm, "kid": s.id, "typ": "JWT"})
+	assert.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	content := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(content))
+
+	var signature []byte
+
+	switch private := s.private.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, sum[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+
+		r, s, err = ecdsa.Sign(rand.Reader, private, sum[:])
+		signature = append(r.FillBytes(make([]byte, es256Size)), s.FillBytes(make([]byte, es256Size))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(private, []byte(content))
+	}
+
+	assert.NoError(t, err)
+
+	return content + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// jwk describes the public key of the signer.
+func (s signer) jwk() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := s.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA", "kid": s.id, "use": "sig",
+			"n": encode(public.N.Bytes()), "e": encode(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		return map[string]string{
+			"kty": "EC", "kid": s.id, "crv": "P-256",
+			"x": encode(public.X.FillBytes(make([]byte, p256Size))), "y": encode(public.Y.FillBytes(make([]byte, p256Size))),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": s.id, "crv": "Ed25519", "alg": AlgorithmEdDSA, "x": encode(public)}
+	}
+
+	return nil
+}
+
+// keySet returns the JWKS of signers.
+func keySet(t *testing.T, signers ...signer) []byte {
+	keys := []map[string]string{{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"}}
+
+	for _, signer := range signers {
+		keys = append(keys, signer.jwk())
+	}
+
+	result, err := json.Marshal(map[string]any{"keys": keys})
+	assert.NoError(t, err)
+
+	return result
+}
+
+func Test_Verify(t *testing.T) {
+	signers := newSigners(t)
+
+	keys, err := ParseKeySet(keySet(t, signers...))
+	assert.NoError(t, err)
+	assert.Len(t, keys, len(signers), "the encryption key should be left out")
+
+	verifier := NewVerifier(keys, "https://issuer", "namless", time.Minute)
+	now := time.Now()
+
+	valid := map[string]any{
+		"sub": "user-1",
+		"iss": "https://issuer",
+		"aud": []string{"other", "namless"},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+
+	for _, signer := range signers {
+		claims, err := verifier.Verify(context.TODO(), signer.sign(t, valid))
+		assert.NoError(t, err, signer.algorithm)
+		assert.Equal(t, "user-1", claims.Subject)
+		assert.Equal(t, []string{"other", "namless"}, claims.Audience)
+		assert.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt.Unix())
+		assert.Equal(t, "user-1", claims.Raw["sub"])
+	}
+
+	invalid := map[error]map[string]any{
+		ErrExpired:         {"exp": now.Add(-2 * time.Minute).Unix()},
+		ErrNotYetValid:     {"nbf": now.Add(2 * time.Minute).Unix()},
+		ErrInvalidIssuer:   {"iss": "https://someone-else"},
+		ErrInvalidAudience: {"aud": "someone-else"},
+		ErrMissingExpiry:   {"exp": nil},
+	}
+
+	for expected, changes := range invalid {
+		claims := map[string]any{}
+
+		for name, value := range valid {
+			claims[name] = value
+		}
+
+		for name, value := range changes {
+			claims[name] = value
+			if value == nil {
+				delete(claims, name)
+			}
+		}
+
+		_, err := verifier.Verify(context.TODO(), signers[0].sign(t, claims))
+		assert.ErrorIs(t, err, expected)
+	}
+
+	// within the leeway
+	claims := map[string]any{"sub": "user-1", "iss": "https://issuer", "aud": "namless"}
+	claims["exp"] = now.Add(-time.Second).Unix()
+	_, err = verifier.Verify(context.TODO(), signers[1].sign(t, claims))
+	assert.NoError(t, err)
+
+	token := signers[2].sign(t, valid)
+
+	_, err = verifier.Verify(context.TODO(), token[:len(token)-4]+"AAAA")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = verifier.Verify(context.TODO(), "not.a-token")
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// a key only signs with its own algorithm, so an RSA key cannot pass for another one
+	impostor := signer{id: "rsa", algorithm: AlgorithmEdDSA, private: signers[2].private}
+	_, err = verifier.Verify(context.TODO(), impostor.sign(t, valid))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user-1"}`)) + "."
+	_, err = verifier.Verify(context.TODO(), none)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func Test_ParseKeySet(t *testing.T) {
+	_, err := ParseKeySet([]byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQAB", "y": "AQAB"}]}`))
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// a point that is not on the curve
+	point := base64.RawURLEncoding.EncodeToString(make([]byte, p256Size))
+	_, err = ParseKeySet([]byte(fmt.Sprintf(`{"keys": [{"kty": "EC", "crv": "P-256", "x": %q, "y": %q}]}`, point, point)))
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	small, err := rsa.GenerateKey(rand.Reader, MinRSABits/2)
+	assert.NoError(t, err)
+
+	keys, err := ParseKeySet(keySet(t, signer{id: "small", algorithm: AlgorithmRS256, private: small}))
+	assert.NoError(t, err)
+	assert.Empty(t, keys, "small RSA keys should be left out")
+}
+
+func Test_Remote(t *testing.T) {
+	signers := newSigners(t)
+
+	var (
+		fetches atomic.Int32
+		served  atomic.Value
+		failing atomic.Bool
+	)
+
+	served.Store(keySet(t, signers[0]))
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+
+		if failing.Load() {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		writer.Write(served.Load().([]byte))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+
+	remote := NewRemote(server.URL, server.Client(), time.Hour)
+	remote.now = func() time.Time { return now }
+
+	verifier := NewVerifier(remote, "", "", 0)
+	claims := map[string]any{"sub": "user-1", "exp": now.Add(time.Hour).Unix()}
+
+	for range 3 {
+		_, err := verifier.Verify(context.TODO(), signers[0].sign(t, claims))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), fetches.Load(), "keys should be cached")
+
+	// a rotated key is fetched, but not more than once per MinRefresh
+	served.Store(keySet(t, signers...))
+
+	_, err := verifier.Verify(context.TODO(), signers[1].sign(t, claims))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(MinRefresh)
+
+	_, err = verifier.Verify(context.TODO(), signers[1].sign(t, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// stale keys are kept when they cannot be fetched again
+	failing.Store(true)
+
+	now = now.Add(2 * time.Hour)
+	claims["exp"] = now.Add(time.Hour).Unix()
+
+	_, err = verifier.Verify(context.TODO(), signers[2].sign(t, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load())
+
+	unreachable := NewRemote(server.URL, server.Client(), 0)
+
+	_, err = NewVerifier(unreachable, "", "", 0).Verify(context.TODO(), signers[0].sign(t, claims))
+	assert.ErrorIs(t, err, ErrKeysUnavailable)
+}
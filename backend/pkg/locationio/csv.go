@@ -0,0 +1,253 @@
+package locationio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// locationFields is the number of fields of a location: ID, name, latitude, longitude and image.
+const locationFields = 5
+
+var (
+	// ErrInvalidColumns for when a column mapping is malformed, or a CSV header misses a required column.
+	ErrInvalidColumns = errors.New("invalid columns")
+)
+
+// Columns maps the fields of a location to the names of CSV columns. An empty name leaves the field out.
+type Columns struct {
+	ID        string
+	Location  string
+	Latitude  string
+	Longitude string
+	Image     string
+}
+
+// DefaultColumns names every CSV column after its field.
+var DefaultColumns = Columns{
+	ID:        "id",
+	Location:  "location",
+	Latitude:  "latitude",
+	Longitude: "longitude",
+	Image:     "image",
+}
+
+// ParseColumns parses a column mapping such as "location=name,latitude=lat,image=", which overrides DefaultColumns.
+func ParseColumns(raw string) (Columns, error) {
+	result := DefaultColumns
+
+	if strings.TrimSpace(raw) == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		field, column, found := strings.Cut(pair, "=")
+		if !found {
+			return Columns{}, fmt.Errorf("%w: expected field=column, got %q", ErrInvalidColumns, pair)
+		}
+
+		target := result.field(strings.TrimSpace(field))
+		if target == nil {
+			return Columns{}, fmt.Errorf("%w: unknown field %q", ErrInvalidColumns, field)
+		}
+
+		*target = strings.TrimSpace(column)
+	}
+
+	return result, nil
+}
+
+// field returns the column of a field with a given name; nil when there is no such field.
+func (c *Columns) field(name string) *string {
+	switch name {
+	case "id":
+		return &c.ID
+	case "location":
+		return &c.Location
+	case "latitude":
+		return &c.Latitude
+	case "longitude":
+		return &c.Longitude
+	case "image":
+		return &c.Image
+	}
+
+	return nil
+}
+
+// csvReader reads locations from CSV rows, after a header row.
+type csvReader struct {
+	reader *csv.Reader
+	// indices of the columns of the fields; -1 when a field is left out.
+	id, location, latitude, longitude, image int
+}
+
+// newCSVReader reads the header row, and builds a new csvReader. The name and the coordinates must have a column.
+func newCSVReader(input io.Reader, columns Columns) (*csvReader, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read CSV header: %w", ErrMalformed, err)
+	}
+
+	indices := make(map[string]int, len(header))
+
+	for index, name := range header {
+		// spreadsheets tend to start files with a byte order mark
+		indices[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = index
+	}
+
+	result := &csvReader{reader: reader}
+
+	for _, column := range []struct {
+		name     string
+		index    *int
+		required bool
+	}{
+		{name: columns.ID, index: &result.id},
+		{name: columns.Location, index: &result.location, required: true},
+		{name: columns.Latitude, index: &result.latitude, required: true},
+		{name: columns.Longitude, index: &result.longitude, required: true},
+		{name: columns.Image, index: &result.image},
+	} {
+		index, found := indices[column.name]
+
+		switch {
+		case found && column.name != "":
+			*column.index = index
+		case column.required:
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidColumns, column.name)
+		default:
+			*column.index = -1
+		}
+	}
+
+	return result, nil
+}
+
+// Read returns the location of the next row.
+func (cr *csvReader) Read() (types.LocationInput, int, error) {
+	record, err := cr.reader.Read()
+	line, _ := cr.reader.FieldPos(0)
+
+	var parseErr *csv.ParseError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return types.LocationInput{}, 0, io.EOF
+	case errors.As(err, &parseErr):
+		problems := &types.ValidationError{}
+		problems.Add("row", parseErr.Err.Error())
+
+		return types.LocationInput{}, parseErr.StartLine, problems
+	case err != nil:
+		return types.LocationInput{}, line, fmt.Errorf("could not read CSV: %w", err)
+	}
+
+	result := types.LocationInput{}
+	problems := &types.ValidationError{}
+
+	cell := func(index int) (string, bool) {
+		if index < 0 || index >= len(record) {
+			return "", false
+		}
+
+		return record[index], true
+	}
+
+	if raw, found := cell(cr.id); found {
+		result.ID = parseID(raw, problems)
+	}
+
+	if raw, found := cell(cr.location); found {
+		result.Location = &raw
+	}
+
+	if raw, found := cell(cr.latitude); found {
+		result.Latitude = parseCoordinate(raw, "latitude", problems)
+	}
+
+	if raw, found := cell(cr.longitude); found {
+		result.Longitude = parseCoordinate(raw, "longitude", problems)
+	}
+
+	if raw, found := cell(cr.image); found {
+		result.Image = &raw
+	}
+
+	return result, line, problems.OrNil()
+}
+
+// csvWriter writes locations as CSV rows, after a header row.
+type csvWriter struct {
+	writer  *csv.Writer
+	columns Columns
+}
+
+// newCSVWriter writes the header row, and builds a new csvWriter.
+func newCSVWriter(output io.Writer, columns Columns) (*csvWriter, error) {
+	result := &csvWriter{writer: csv.NewWriter(output), columns: columns}
+
+	err := result.row(columns.ID, columns.Location, columns.Latitude, columns.Longitude, columns.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Write a location as a row.
+func (cw *csvWriter) Write(location models.Location) error {
+	return cw.row(
+		strconv.Itoa(location.ID),
+		location.Location,
+		formatCoordinate(location.Latitude),
+		formatCoordinate(location.Longitude),
+		location.Image,
+	)
+}
+
+// Close flushes the rows.
+func (cw *csvWriter) Close() error {
+	cw.writer.Flush()
+
+	err := cw.writer.Error()
+	if err != nil {
+		return fmt.Errorf("could not write CSV: %w", err)
+	}
+
+	return nil
+}
+
+// row writes the cells of the fields that have a column, in the order of Columns.
+func (cw *csvWriter) row(id, location, latitude, longitude, image string) error {
+	record := make([]string, 0, locationFields)
+
+	for _, cell := range []struct{ column, value string }{
+		{column: cw.columns.ID, value: id},
+		{column: cw.columns.Location, value: location},
+		{column: cw.columns.Latitude, value: latitude},
+		{column: cw.columns.Longitude, value: longitude},
+		{column: cw.columns.Image, value: image},
+	} {
+		if cell.column != "" {
+			record = append(record, cell.value)
+		}
+	}
+
+	err := cw.writer.Write(record)
+	if err != nil {
+		return fmt.Errorf("could not write CSV: %w", err)
+	}
+
+	return nil
+}
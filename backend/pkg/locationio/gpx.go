@@ -0,0 +1,74 @@
+package locationio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	gpxHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<gpx version="1.1" creator="Namless" xmlns="http://www.topografix.com/GPX/1/1">` + "\n"
+	gpxFooter = "\n</gpx>\n"
+)
+
+// gpxWaypoint models a GPX waypoint. The image is its link, and the ID is kept in its extensions.
+type gpxWaypoint struct {
+	XMLName   xml.Name `xml:"wpt"`
+	Latitude  string   `xml:"lat,attr"`
+	Longitude string   `xml:"lon,attr"`
+	Name      *string  `xml:"name"`
+	Link      *gpxLink `xml:"link,omitempty"`
+	ID        string   `xml:"extensions>id,omitempty"`
+}
+
+// gpxLink models a link of a GPX waypoint.
+type gpxLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// readWaypoint decodes a GPX waypoint.
+func readWaypoint(
+	decoder *xml.Decoder,
+	start *xml.StartElement,
+	problems *types.ValidationError,
+) (types.LocationInput, error) {
+	waypoint := gpxWaypoint{}
+
+	err := decoder.DecodeElement(&waypoint, start)
+	if err != nil {
+		return types.LocationInput{}, fmt.Errorf("could not decode waypoint: %w", err)
+	}
+
+	result := types.LocationInput{
+		ID:        parseID(waypoint.ID, problems),
+		Location:  waypoint.Name,
+		Latitude:  parseCoordinate(waypoint.Latitude, "latitude", problems),
+		Longitude: parseCoordinate(waypoint.Longitude, "longitude", problems),
+	}
+
+	if waypoint.Link != nil {
+		result.Image = &waypoint.Link.Href
+	}
+
+	return result, nil
+}
+
+// writeWaypoint returns a location as a GPX waypoint.
+func writeWaypoint(location models.Location) any {
+	result := gpxWaypoint{
+		Latitude:  formatCoordinate(location.Latitude),
+		Longitude: formatCoordinate(location.Longitude),
+		Name:      &location.Location,
+		ID:        strconv.Itoa(location.ID),
+	}
+
+	if location.Image != "" {
+		result.Link = &gpxLink{Href: location.Image}
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package locationio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	kmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<kml xmlns="http://www.opengis.net/kml/2.2">` + "\n" + `<Document>` + "\n"
+	kmlFooter = "\n</Document>\n</kml>\n"
+
+	// kmlID and kmlImage name the extended data of placemarks holding the ID and the image.
+	kmlID    = "id"
+	kmlImage = "image"
+
+	// pointCoordinates is the number of coordinates of a point, before the optional altitude.
+	pointCoordinates = 2
+)
+
+// kmlPlacemark models a KML placemark. The ID and the image are kept in its extended data.
+type kmlPlacemark struct {
+	XMLName xml.Name  `xml:"Placemark"`
+	Name    *string   `xml:"name"`
+	Data    []kmlData `xml:"ExtendedData>Data"`
+	// Coordinates are "longitude,latitude[,altitude]".
+	Coordinates *string `xml:"Point>coordinates"`
+}
+
+// kmlData models a named value of the extended data of a KML placemark.
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// readPlacemark decodes a KML placemark.
+func readPlacemark(
+	decoder *xml.Decoder,
+	start *xml.StartElement,
+	problems *types.ValidationError,
+) (types.LocationInput, error) {
+	placemark := kmlPlacemark{}
+
+	err := decoder.DecodeElement(&placemark, start)
+	if err != nil {
+		return types.LocationInput{}, fmt.Errorf("could not decode placemark: %w", err)
+	}
+
+	result := types.LocationInput{Location: placemark.Name}
+
+	for _, data := range placemark.Data {
+		switch data.Name {
+		case kmlID:
+			result.ID = parseID(data.Value, problems)
+		case kmlImage:
+			value := data.Value
+			result.Image = &value
+		}
+	}
+
+	if placemark.Coordinates == nil {
+		problems.Add("geometry", "must be a Point")
+
+		return result, nil
+	}
+
+	coordinates := strings.Split(strings.TrimSpace(*placemark.Coordinates), ",")
+	if len(coordinates) < pointCoordinates {
+		problems.Add("geometry.coordinates", "must be longitude,latitude")
+
+		return result, nil
+	}
+
+	result.Longitude = parseCoordinate(coordinates[0], "longitude", problems)
+	result.Latitude = parseCoordinate(coordinates[1], "latitude", problems)
+
+	return result, nil
+}
+
+// writePlacemark returns a location as a KML placemark.
+func writePlacemark(location models.Location) any {
+	coordinates := formatCoordinate(location.Longitude) + "," + formatCoordinate(location.Latitude)
+	result := kmlPlacemark{
+		Name:        &location.Location,
+		Data:        []kmlData{{Name: kmlID, Value: strconv.Itoa(location.ID)}},
+		Coordinates: &coordinates,
+	}
+
+	if location.Image != "" {
+		result.Data = append(result.Data, kmlData{Name: kmlImage, Value: location.Image})
+	}
+
+	return result
+}
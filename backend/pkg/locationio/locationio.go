@@ -0,0 +1,133 @@
+/*
+Package locationio offers streaming readers and writers of locations in the formats of spreadsheets and GPS tools:
+CSV, GPX waypoints and KML placemarks.
+
+Readers and writers hold a single location at a time, so inputs and outputs of any size can be processed.
+*/
+package locationio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// FormatCSV is comma-separated values, with a header row (see Columns).
+	FormatCSV = "csv"
+	// FormatGPX is GPX 1.1; locations are waypoints.
+	FormatGPX = "gpx"
+	// FormatKML is KML 2.2; locations are placemarks with a point.
+	FormatKML = "kml"
+)
+
+var (
+	// ErrUnknownFormat for when a format is not one of FormatCSV, FormatGPX or FormatKML.
+	ErrUnknownFormat = errors.New("unknown format, expected csv, gpx or kml")
+	// ErrMalformed for when an input cannot be read any further, as it does not follow its format.
+	ErrMalformed = errors.New("malformed input")
+)
+
+// Reader reads locations, one at a time.
+type Reader interface {
+	// Read returns the next location, and the line it starts on.
+	//
+	// A *types.ValidationError reports a bad record, after which reading can go on; io.EOF reports the end of the
+	// input. Any other error is fatal: ErrMalformed when the input does not follow its format.
+	Read() (types.LocationInput, int, error)
+}
+
+// Writer writes locations, one at a time.
+type Writer interface {
+	// Write a location.
+	Write(location models.Location) error
+	// Close writes what is left, such as closing tags. It does not close the underlying writer.
+	Close() error
+}
+
+// NewReader builds a new Reader of a given format. Columns only apply to FormatCSV.
+func NewReader(format string, input io.Reader, columns Columns) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(input, columns)
+	case FormatGPX:
+		return newXMLReader(input, "wpt", readWaypoint), nil
+	case FormatKML:
+		return newXMLReader(input, "Placemark", readPlacemark), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// NewWriter builds a new Writer of a given format. Columns only apply to FormatCSV.
+func NewWriter(format string, output io.Writer, columns Columns) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(output, columns)
+	case FormatGPX:
+		return newXMLWriter(output, gpxHeader, gpxFooter, writeWaypoint)
+	case FormatKML:
+		return newXMLWriter(output, kmlHeader, kmlFooter, writePlacemark)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// IsFormat tells whether a format is one of FormatCSV, FormatGPX or FormatKML.
+func IsFormat(format string) bool {
+	return format == FormatCSV || format == FormatGPX || format == FormatKML
+}
+
+// ContentType returns the media type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatGPX:
+		return "application/gpx+xml"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	}
+
+	return "text/csv"
+}
+
+// parseCoordinate parses a latitude or a longitude; problems gets an error for field when it is not a finite number.
+func parseCoordinate(raw string, field string, problems *types.ValidationError) *float32 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		problems.Add(field, "must be a number")
+
+		return nil
+	}
+
+	result := float32(value)
+
+	return &result
+}
+
+// parseID parses the ID of a location; nil when it is empty. problems gets an error when it is not a number.
+func parseID(raw string, problems *types.ValidationError) *int {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	result, err := strconv.Atoi(raw)
+	if err != nil {
+		problems.Add("id", "must be an integer")
+
+		return nil
+	}
+
+	return &result
+}
+
+// formatCoordinate formats a latitude or a longitude, with no more digits than it holds.
+func formatCoordinate(value float32) string {
+	return strconv.FormatFloat(float64(value), 'g', -1, 32)
+}
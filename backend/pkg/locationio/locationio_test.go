@@ -0,0 +1,141 @@
+package locationio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_RoundTrip(t *testing.T) {
+	locations := []models.Location{
+		{ID: 1, Location: "Paris, \"Île\" <de> France", Latitude: 48.8566, Longitude: 2.3522, Image: "https://a.b/c.png"},
+		{ID: 2, Location: "Suva", Latitude: -18.1416, Longitude: 178.4419},
+	}
+
+	for _, format := range []string{FormatCSV, FormatGPX, FormatKML} {
+		t.Run(format, func(t *testing.T) {
+			output := &bytes.Buffer{}
+
+			writer, err := NewWriter(format, output, DefaultColumns)
+			assert.NoError(t, err)
+
+			for _, location := range locations {
+				assert.NoError(t, writer.Write(location))
+			}
+
+			assert.NoError(t, writer.Close())
+
+			reader, err := NewReader(format, output, DefaultColumns)
+			assert.NoError(t, err)
+
+			assert.Equal(t, locations, readAll(t, reader))
+		})
+	}
+}
+
+func Test_BadRecords(t *testing.T) {
+	columns, err := ParseColumns("location=name, latitude=lat,longitude=lng,id=")
+	assert.NoError(t, err)
+
+	reader, err := NewReader(FormatCSV, strings.NewReader(
+		"name,lat,lng,image\n"+
+			"Paris,48.85,2.35,\n"+
+			"Nowhere,north,2.35,\n"+
+			"\"Multi\nline\",1,2,https://a.b/c.png\n"+
+			"Short,1\n"+
+			"Void,NaN,2.35,\n"+
+			"Beyond,1,+Infinity,\n",
+	), columns)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[int]string{
+		3: "latitude: must be a number",
+		6: "longitude: is required",
+		7: "latitude: must be a number",
+		8: "longitude: must be a number",
+	}, badLines(t, reader))
+
+	reader, err = NewReader(FormatGPX, strings.NewReader(`<gpx>
+		<wpt lat="nan" lon="2"><name>A</name></wpt>
+		<wpt lat="1" lon="-Inf"><name>B</name></wpt>
+	</gpx>`), DefaultColumns)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{2: "latitude: must be a number", 3: "longitude: must be a number"}, badLines(t, reader))
+
+	reader, err = NewReader(FormatKML, strings.NewReader(`<kml><Document>
+		<Placemark><name>Fine</name><Point><coordinates>2.35,48.85,0</coordinates></Point></Placemark>
+		<Placemark><name>Line</name><LineString><coordinates>0,0 1,1</coordinates></LineString></Placemark>
+		<Folder>
+			<Placemark><name>Bad</name><Point><coordinates>2.35</coordinates></Point></Placemark>
+		</Folder>
+	</Document></kml>`), DefaultColumns)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[int]string{3: "geometry: must be a Point", 5: "geometry.coordinates: must be longitude,latitude"},
+		badLines(t, reader))
+
+	_, err = NewReader(FormatCSV, strings.NewReader("name,lat\n"), DefaultColumns)
+	assert.ErrorIs(t, err, ErrInvalidColumns)
+
+	_, err = ParseColumns("elevation=ele")
+	assert.ErrorIs(t, err, ErrInvalidColumns)
+
+	_, err = NewReader("xlsx", strings.NewReader(""), DefaultColumns)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	// broken XML is fatal
+	reader, err = NewReader(FormatGPX, strings.NewReader(`<gpx><wpt lat="1" lon="2"><name>A</wpt></gpx>`), DefaultColumns)
+	assert.NoError(t, err)
+
+	_, _, err = reader.Read()
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+// readAll reads every location, and applies it, as the service would.
+func readAll(t *testing.T, reader Reader) []models.Location {
+	t.Helper()
+
+	var result []models.Location
+
+	for {
+		input, _, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+
+		assert.NoError(t, err)
+
+		location, err := input.Apply(models.Location{ID: *input.ID}, false)
+		assert.NoError(t, err)
+
+		result = append(result, location)
+	}
+}
+
+// badLines reads every location, and returns the line and the problems of the bad ones.
+func badLines(t *testing.T, reader Reader) map[int]string {
+	t.Helper()
+
+	result := make(map[int]string)
+
+	for {
+		input, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+
+		if err == nil {
+			_, err = input.Apply(models.Location{}, false)
+		}
+
+		if err != nil {
+			result[line] = strings.TrimPrefix(err.Error(), types.ErrInvalidInput.Error()+": ")
+		}
+	}
+}
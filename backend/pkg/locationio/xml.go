@@ -0,0 +1,112 @@
+package locationio
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// xmlReader reads locations from the XML elements with a given name, wherever they are.
+type xmlReader struct {
+	decoder *xml.Decoder
+	element string
+	// read decodes the element starting at start; problems gets the errors of its fields.
+	read func(decoder *xml.Decoder, start *xml.StartElement, problems *types.ValidationError) (types.LocationInput, error)
+}
+
+// newXMLReader builds a new xmlReader.
+func newXMLReader(
+	input io.Reader,
+	element string,
+	read func(decoder *xml.Decoder, start *xml.StartElement, problems *types.ValidationError) (types.LocationInput, error),
+) *xmlReader {
+	return &xmlReader{decoder: xml.NewDecoder(input), element: element, read: read}
+}
+
+// Read returns the location of the next element.
+func (xr *xmlReader) Read() (types.LocationInput, int, error) {
+	for {
+		token, err := xr.decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return types.LocationInput{}, 0, io.EOF
+		}
+
+		line, _ := xr.decoder.InputPos()
+
+		if err != nil {
+			return types.LocationInput{}, line, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+
+		start, isStart := token.(xml.StartElement)
+		if !isStart || start.Name.Local != xr.element {
+			continue
+		}
+
+		problems := &types.ValidationError{}
+
+		result, err := xr.read(xr.decoder, &start, problems)
+		if err != nil {
+			return types.LocationInput{}, line, fmt.Errorf("%w: could not read %s: %w", ErrMalformed, xr.element, err)
+		}
+
+		return result, line, problems.OrNil()
+	}
+}
+
+// xmlWriter writes locations as XML elements, between a header and a footer.
+type xmlWriter struct {
+	output  *bufio.Writer
+	encoder *xml.Encoder
+	footer  string
+	// element returns the element of a location, to be encoded.
+	element func(location models.Location) any
+}
+
+// newXMLWriter writes the header, and builds a new xmlWriter.
+func newXMLWriter(
+	output io.Writer,
+	header string,
+	footer string,
+	element func(models.Location) any,
+) (*xmlWriter, error) {
+	buffered := bufio.NewWriter(output)
+
+	_, err := buffered.WriteString(header)
+	if err != nil {
+		return nil, fmt.Errorf("could not write XML: %w", err)
+	}
+
+	encoder := xml.NewEncoder(buffered)
+	encoder.Indent("  ", "  ")
+
+	return &xmlWriter{output: buffered, encoder: encoder, footer: footer, element: element}, nil
+}
+
+// Write a location as an element.
+func (xw *xmlWriter) Write(location models.Location) error {
+	err := xw.encoder.Encode(xw.element(location))
+	if err != nil {
+		return fmt.Errorf("could not write XML: %w", err)
+	}
+
+	return nil
+}
+
+// Close writes the footer, and flushes the elements.
+func (xw *xmlWriter) Close() error {
+	_, err := xw.output.WriteString(xw.footer)
+	if err == nil {
+		err = xw.output.Flush()
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not write XML: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,397 @@
+/*
+Package migrations offers the versioned schema of the postgres DB, and a runner that applies it.
+
+Migrations are numbered SQL files embedded in the binary: NNNN_name.up.sql applies a change, and NNNN_name.down.sql
+takes it back. The applied ones are tracked in the schema_migrations table.
+*/
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// lockID keys the advisory lock that keeps concurrent runners from applying the same migration twice.
+const lockID = 7_143_621
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// fileName matches the names of migration files: version, name and direction.
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	// ErrInvalidMigration for when the embedded migrations are malformed.
+	ErrInvalidMigration = errors.New("invalid migration")
+	// ErrPendingMigrations for when the DB schema is behind the one this binary expects.
+	ErrPendingMigrations = errors.New("pending migrations")
+	// ErrUnknownMigration for when rolling back a migration this binary does not know.
+	ErrUnknownMigration = errors.New("unknown migration")
+)
+
+// Migration models a single change of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String returns the file name of the migration, without the direction.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status models a migration and whether it was applied.
+type Status struct {
+	Migration
+	// AppliedAt is when the migration was applied; nil when it is pending.
+	AppliedAt *time.Time
+}
+
+// Load returns the embedded migrations, by ascending version.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("%w: unexpected file %q", ErrInvalidMigration, entry.Name())
+		}
+
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: bad version in %q", ErrInvalidMigration, entry.Name())
+		}
+
+		content, err := files.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("could not read migration %q: %w", entry.Name(), err)
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrInvalidMigration, version)
+		}
+
+		if parts[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: %s needs both an up and a down file", ErrInvalidMigration, migration)
+		}
+
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// Runner applies migrations to a postgres DB.
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// Open builds a new runner for the DB found at dsn.
+func Open(dsn string) (*Runner, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+
+	result := &Runner{migrations: migrations}
+
+	result.db, err = database.DB()
+	if err != nil {
+		return nil, fmt.Errorf("could not get DB: %w", err)
+	}
+
+	return result, nil
+}
+
+// Status returns every known migration, and whether it was applied.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(r.migrations))
+
+	for _, migration := range r.migrations {
+		status := Status{Migration: migration}
+
+		if appliedAt, found := applied[migration.Version]; found {
+			status.AppliedAt = &appliedAt
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// Check returns ErrPendingMigrations when some known migrations were not applied yet.
+func (r *Runner) Check(ctx context.Context) error {
+	statuses, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%w: %d of %d not applied", ErrPendingMigrations, pending, len(statuses))
+	}
+
+	return nil
+}
+
+// Up applies the pending migrations, in order, and returns them.
+//
+// Every migration is applied in its own transaction, along with its record in schema_migrations, so a failure leaves
+// the schema at the previous version.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	err := r.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+
+	for _, migration := range r.migrations {
+		applied := false
+
+		err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+			var found bool
+
+			err := tx.QueryRowContext(
+				ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1);", migration.Version,
+			).Scan(&found)
+			if err != nil || found {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, migration.Up)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(
+				ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);", migration.Version, migration.Name,
+			)
+			applied = err == nil
+
+			return err
+		})
+		if err != nil {
+			return result, fmt.Errorf("could not apply migration %s: %w", migration, err)
+		}
+
+		if applied {
+			result = append(result, migration)
+		}
+	}
+
+	return result, nil
+}
+
+// Down takes back (at most) the latest steps applied migrations, newest first, and returns them.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	err := r.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+
+	for range steps {
+		var migration *Migration
+
+		err := r.inTransaction(ctx, func(tx *sql.Tx) error {
+			var version int64
+
+			err := tx.QueryRowContext(ctx, "SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1;").
+				Scan(&version)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			migration = r.find(version)
+			if migration == nil {
+				return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+			}
+
+			_, err = tx.ExecContext(ctx, migration.Down)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1;", version)
+
+			return err
+		})
+		if err != nil {
+			return result, fmt.Errorf("could not take back migration: %w", err)
+		}
+
+		if migration == nil {
+			break
+		}
+
+		result = append(result, *migration)
+	}
+
+	return result, nil
+}
+
+// Close closes the DB connection.
+func (r *Runner) Close() error {
+	err := r.db.Close()
+	if err != nil {
+		return fmt.Errorf("could not close DB: %w", err)
+	}
+
+	return nil
+}
+
+// applied returns when every applied migration was applied, by version.
+func (r *Runner) applied(ctx context.Context) (map[int64]time.Time, error) {
+	err := r.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, fmt.Errorf("could not read applied migrations: %w", err)
+	}
+
+	defer rows.Close()
+
+	result := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not read applied migration: %w", err)
+		}
+
+		result[version] = appliedAt
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not read applied migrations: %w", err)
+	}
+
+	return result, nil
+}
+
+// ensureTable creates the schema_migrations table, if needed.
+func (r *Runner) ensureTable(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		);`,
+	)
+	if err != nil {
+		return fmt.Errorf("could not create schema_migrations: %w", err)
+	}
+
+	return nil
+}
+
+// inTransaction runs fn in a transaction holding the migration lock, and commits it when fn returns nil.
+func (r *Runner) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1);", lockID)
+	if err == nil {
+		err = fn(tx)
+	}
+
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("could not roll back: %w", rollbackErr))
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit: %w", err)
+	}
+
+	return nil
+}
+
+// find returns the known migration with a given version; nil when there is none.
+func (r *Runner) find(version int64) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+
+	return nil
+}
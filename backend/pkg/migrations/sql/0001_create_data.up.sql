@@ -0,0 +1,10 @@
+-- the schema AutoMigrate used to create; IF NOT EXISTS adopts the DBs it already created
+CREATE TABLE IF NOT EXISTS data (
+    id text PRIMARY KEY,
+    value text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_deleted_at ON data (deleted_at);
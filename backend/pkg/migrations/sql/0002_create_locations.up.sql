@@ -0,0 +1,8 @@
+-- the schema AutoMigrate used to create; IF NOT EXISTS adopts the DBs it already created
+CREATE TABLE IF NOT EXISTS locations (
+    latitude decimal,
+    longitutde decimal,
+    id bigserial PRIMARY KEY,
+    location text,
+    image text
+);
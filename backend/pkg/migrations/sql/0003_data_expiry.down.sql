@@ -0,0 +1 @@
+ALTER TABLE data DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,3 @@
+ALTER TABLE data ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_data_expires_at ON data (expires_at);
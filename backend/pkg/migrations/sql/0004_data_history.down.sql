@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS data_versions;
+
+ALTER TABLE data DROP COLUMN IF EXISTS version;
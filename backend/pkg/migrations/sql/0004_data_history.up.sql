@@ -0,0 +1,20 @@
+ALTER TABLE data ADD COLUMN IF NOT EXISTS version bigint;
+
+CREATE TABLE IF NOT EXISTS data_versions (
+    id text,
+    version bigint,
+    value text,
+    expires_at timestamptz,
+    deleted boolean,
+    created_at timestamptz,
+    PRIMARY KEY (id, version)
+);
+
+-- items written before history was kept get their first revision
+UPDATE data SET version = 1 WHERE version IS NULL OR version = 0;
+
+INSERT INTO data_versions (id, version, value, expires_at, deleted, created_at)
+SELECT id, version, CASE WHEN deleted_at IS NULL THEN value ELSE '' END, expires_at, deleted_at IS NOT NULL,
+    COALESCE(deleted_at, updated_at)
+FROM data
+ON CONFLICT DO NOTHING;
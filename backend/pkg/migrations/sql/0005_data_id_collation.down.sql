@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_data_id_c;
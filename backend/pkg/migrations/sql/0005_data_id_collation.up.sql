@@ -0,0 +1,2 @@
+-- key ranges compare IDs byte by byte
+CREATE INDEX IF NOT EXISTS idx_data_id_c ON data (id COLLATE "C");
@@ -0,0 +1 @@
+ALTER TABLE locations RENAME COLUMN longitude TO longitutde;
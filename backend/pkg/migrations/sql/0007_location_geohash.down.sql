@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS locations_geohash ON locations;
+
+DROP FUNCTION IF EXISTS locations_set_geohash();
+
+ALTER TABLE locations DROP COLUMN IF EXISTS geohash;
+
+DROP FUNCTION IF EXISTS geohash_encode(double precision, double precision, integer);
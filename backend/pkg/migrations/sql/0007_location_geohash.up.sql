@@ -0,0 +1,71 @@
+-- geohash_encode mirrors geo.Geohash: bits alternate between longitude and latitude, starting with longitude
+CREATE OR REPLACE FUNCTION geohash_encode(lat double precision, lon double precision, characters integer)
+RETURNS text AS $$
+DECLARE
+    alphabet constant text := '0123456789bcdefghjkmnpqrstuvwxyz';
+    min_lat double precision := -90;
+    max_lat double precision := 90;
+    min_lon double precision := -180;
+    max_lon double precision := 180;
+    middle double precision;
+    even boolean := true;
+    bits integer := 0;
+    bit_count integer := 0;
+    result text := '';
+BEGIN
+    IF lat IS NULL OR lon IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    WHILE length(result) < characters LOOP
+        IF even THEN
+            middle := (min_lon + max_lon) / 2;
+            IF lon >= middle THEN
+                bits := bits * 2 + 1;
+                min_lon := middle;
+            ELSE
+                bits := bits * 2;
+                max_lon := middle;
+            END IF;
+        ELSE
+            middle := (min_lat + max_lat) / 2;
+            IF lat >= middle THEN
+                bits := bits * 2 + 1;
+                min_lat := middle;
+            ELSE
+                bits := bits * 2;
+                max_lat := middle;
+            END IF;
+        END IF;
+
+        even := NOT even;
+        bit_count := bit_count + 1;
+
+        IF bit_count = 5 THEN
+            result := result || substr(alphabet, bits + 1, 1);
+            bits := 0;
+            bit_count := 0;
+        END IF;
+    END LOOP;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE locations ADD COLUMN geohash text;
+
+-- 9 characters: geo.GeohashPrecision
+CREATE OR REPLACE FUNCTION locations_set_geohash() RETURNS trigger AS $$
+BEGIN
+    NEW.geohash := geohash_encode(NEW.latitude::double precision, NEW.longitude::double precision, 9);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER locations_geohash BEFORE INSERT OR UPDATE ON locations
+FOR EACH ROW EXECUTE FUNCTION locations_set_geohash();
+
+UPDATE locations SET geohash = geohash_encode(latitude::double precision, longitude::double precision, 9);
+
+-- cells are scanned as byte ranges of geohash prefixes
+CREATE INDEX idx_locations_geohash ON locations (geohash COLLATE "C");
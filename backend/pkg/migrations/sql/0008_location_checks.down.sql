@@ -0,0 +1,4 @@
+ALTER TABLE locations
+    DROP CONSTRAINT IF EXISTS locations_latitude_range,
+    DROP CONSTRAINT IF EXISTS locations_longitude_range,
+    DROP CONSTRAINT IF EXISTS locations_location_not_blank;
@@ -0,0 +1,5 @@
+-- NOT VALID: existing rows are left as they are, new and updated ones are checked
+ALTER TABLE locations
+    ADD CONSTRAINT locations_latitude_range CHECK (latitude BETWEEN -90 AND 90) NOT VALID,
+    ADD CONSTRAINT locations_longitude_range CHECK (longitude BETWEEN -180 AND 180) NOT VALID,
+    ADD CONSTRAINT locations_location_not_blank CHECK (btrim(location) <> '') NOT VALID;
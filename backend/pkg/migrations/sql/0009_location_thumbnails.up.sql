@@ -0,0 +1 @@
+ALTER TABLE locations ADD COLUMN thumbnails jsonb;
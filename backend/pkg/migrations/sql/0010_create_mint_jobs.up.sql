@@ -0,0 +1,18 @@
+CREATE TABLE mint_jobs (
+    id text PRIMARY KEY,
+    state text NOT NULL,
+    token jsonb,
+    receiver text NOT NULL DEFAULT '',
+    uid text NOT NULL DEFAULT '',
+    mint_id text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    next_attempt_at timestamptz NOT NULL,
+    lease_until timestamptz,
+    version bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+-- workers claim the oldest due jobs that are not finished
+CREATE INDEX mint_jobs_due ON mint_jobs (next_attempt_at, id) WHERE state NOT IN ('done', 'failed');
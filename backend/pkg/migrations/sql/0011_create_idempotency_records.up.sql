@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_records (
+    key text PRIMARY KEY,
+    method text NOT NULL,
+    target text NOT NULL,
+    fingerprint text NOT NULL DEFAULT '',
+    status_code integer NOT NULL DEFAULT 0,
+    header jsonb,
+    body bytea,
+    created_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_idempotency_records_expires_at ON idempotency_records (expires_at);
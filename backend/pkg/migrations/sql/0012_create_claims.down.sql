@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS claims;
+ALTER TABLE locations DROP COLUMN IF EXISTS radius;
@@ -0,0 +1,19 @@
+-- check-in radius of locations, in meters; zero for the default one
+ALTER TABLE locations ADD COLUMN radius double precision NOT NULL DEFAULT 0
+    CONSTRAINT locations_radius_range CHECK (radius BETWEEN 0 AND 10000);
+
+-- IF NOT EXISTS adopts the claims table AutoMigrate may have created
+CREATE TABLE IF NOT EXISTS claims (
+    id text PRIMARY KEY,
+    location_id bigint NOT NULL,
+    wallet text NOT NULL,
+    latitude double precision NOT NULL,
+    longitude double precision NOT NULL,
+    distance double precision NOT NULL,
+    job_id text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+
+-- a wallet claims a location once
+CREATE UNIQUE INDEX IF NOT EXISTS claims_location_wallet ON claims (location_id, wallet);
+CREATE INDEX IF NOT EXISTS claims_wallet ON claims (wallet);
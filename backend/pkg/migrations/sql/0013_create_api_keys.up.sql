@@ -0,0 +1,9 @@
+-- IF NOT EXISTS adopts the api_keys table AutoMigrate may have created, along with the keys it holds
+CREATE TABLE IF NOT EXISTS api_keys (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    hash text NOT NULL,
+    scopes jsonb NOT NULL,
+    created_at timestamptz NOT NULL,
+    revoked_at timestamptz
+);
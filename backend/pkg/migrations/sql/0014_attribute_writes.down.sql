@@ -0,0 +1,3 @@
+ALTER TABLE locations DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE data_versions DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE data DROP COLUMN IF EXISTS updated_by;
@@ -0,0 +1,3 @@
+ALTER TABLE data ADD COLUMN updated_by text NOT NULL DEFAULT '';
+ALTER TABLE data_versions ADD COLUMN updated_by text NOT NULL DEFAULT '';
+ALTER TABLE locations ADD COLUMN updated_by text NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE locations DROP COLUMN IF EXISTS grants;
+ALTER TABLE locations DROP COLUMN IF EXISTS owner;
+
+DROP INDEX IF EXISTS idx_data_owner;
+ALTER TABLE data DROP COLUMN IF EXISTS grants;
+ALTER TABLE data DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,6 @@
+ALTER TABLE data ADD COLUMN owner text NOT NULL DEFAULT '';
+ALTER TABLE data ADD COLUMN grants jsonb;
+CREATE INDEX idx_data_owner ON data (owner);
+
+ALTER TABLE locations ADD COLUMN owner text NOT NULL DEFAULT '';
+ALTER TABLE locations ADD COLUMN grants jsonb;
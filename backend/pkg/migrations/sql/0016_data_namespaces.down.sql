@@ -0,0 +1,13 @@
+-- keys are only unique in a single namespace: the entries of the others are dropped
+DELETE FROM data_versions WHERE namespace <> 'default';
+ALTER TABLE data_versions DROP CONSTRAINT data_versions_pkey;
+ALTER TABLE data_versions ADD PRIMARY KEY (id, version);
+ALTER TABLE data_versions DROP COLUMN namespace;
+
+DROP INDEX IF EXISTS idx_data_namespace_id_c;
+CREATE INDEX idx_data_id_c ON data (id COLLATE "C");
+
+DELETE FROM data WHERE namespace <> 'default';
+ALTER TABLE data DROP CONSTRAINT data_pkey;
+ALTER TABLE data ADD PRIMARY KEY (id);
+ALTER TABLE data DROP COLUMN namespace;
@@ -0,0 +1,11 @@
+-- keys are unique within their namespace; the entries written so far go to the default one
+ALTER TABLE data ADD COLUMN namespace text NOT NULL DEFAULT 'default';
+ALTER TABLE data DROP CONSTRAINT data_pkey;
+ALTER TABLE data ADD PRIMARY KEY (namespace, id);
+
+DROP INDEX IF EXISTS idx_data_id_c;
+CREATE INDEX idx_data_namespace_id_c ON data (namespace, id COLLATE "C");
+
+ALTER TABLE data_versions ADD COLUMN namespace text NOT NULL DEFAULT 'default';
+ALTER TABLE data_versions DROP CONSTRAINT data_versions_pkey;
+ALTER TABLE data_versions ADD PRIMARY KEY (namespace, id, version);
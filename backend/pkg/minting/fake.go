@@ -0,0 +1,174 @@
+package minting
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// FakeReceiver is the wallet the fake sends tokens to when a mint names none.
+const FakeReceiver = "addr_test1qpfakewa77etfakewa77etfakewa77etfakewa77etfakewa77etq0"
+
+// Fake is an in-process Minter, for tests and local runs: it keeps tokens in memory, and mints them at once.
+type Fake struct {
+	lock     sync.Mutex
+	tokens   map[string]*fakeToken
+	names    map[string]string
+	failures []error
+	calls    int
+}
+
+// fakeToken models a token kept by the fake.
+type fakeToken struct {
+	token  Token
+	status Status
+}
+
+// NewFake builds a new fake minter.
+func NewFake() *Fake {
+	return &Fake{
+		tokens: make(map[string]*fakeToken),
+		names:  make(map[string]string),
+	}
+}
+
+// Fail makes the next calls fail with the given errors, in order; a nil error lets a call through.
+func (f *Fake) Fail(errs ...error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.failures = append(f.failures, errs...)
+}
+
+// Calls returns the number of calls received so far.
+func (f *Fake) Calls() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.calls
+}
+
+// Token returns the uploaded token with a given UID, and whether it was found.
+func (f *Fake) Token(uid string) (Token, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	found, ok := f.tokens[uid]
+	if !ok {
+		return Token{}, false
+	}
+
+	return found.token, true
+}
+
+// Upload a token; token names are unique, like in a project of NMKR Studio.
+func (f *Fake) Upload(ctx context.Context, token Token) (Upload, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.call(ctx)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	if _, ok := f.names[token.Name]; ok {
+		return Upload{}, fmt.Errorf("%w: token name %q already used", ErrRejected, token.Name)
+	}
+
+	uid := fmt.Sprintf("00000000-0000-4000-8000-%012d", len(f.tokens)+1)
+	assetID := fmt.Sprintf("asset%d", len(f.tokens)+1)
+
+	f.names[token.Name] = uid
+	f.tokens[uid] = &fakeToken{
+		token:  token,
+		status: Status{UID: uid, State: StateFree, AssetID: assetID},
+	}
+
+	return Upload{UID: uid, AssetID: assetID}, nil
+}
+
+// Find returns the uploaded token with a given name.
+func (f *Fake) Find(ctx context.Context, name string) (Upload, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.call(ctx)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	uid, ok := f.names[name]
+	if !ok {
+		return Upload{}, fmt.Errorf("%w: token name %q", ErrNotFound, name)
+	}
+
+	return Upload{UID: uid, AssetID: f.tokens[uid].status.AssetID}, nil
+}
+
+// MintAndSend mints an uploaded token at once; a token is only minted once.
+func (f *Fake) MintAndSend(ctx context.Context, uid string, receiver string) (Mint, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.call(ctx)
+	if err != nil {
+		return Mint{}, err
+	}
+
+	found, ok := f.tokens[uid]
+	if !ok {
+		return Mint{}, fmt.Errorf("%w: %q", ErrNotFound, uid)
+	}
+
+	if found.status.Minted {
+		return Mint{}, fmt.Errorf("%w: token %q already minted", ErrRejected, uid)
+	}
+
+	if receiver == "" {
+		receiver = FakeReceiver
+	}
+
+	found.status.State = StateSold
+	found.status.Minted = true
+	found.status.PolicyID = "fakepolicy"
+	found.status.TransactionID = fmt.Sprintf("%064d", f.calls)
+
+	return Mint{ID: strconv.Itoa(f.calls), UID: uid, Receiver: receiver}, nil
+}
+
+// Status returns the state of an uploaded token.
+func (f *Fake) Status(ctx context.Context, uid string) (Status, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.call(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	found, ok := f.tokens[uid]
+	if !ok {
+		return Status{}, fmt.Errorf("%w: %q", ErrNotFound, uid)
+	}
+
+	return found.status, nil
+}
+
+// call counts a call, and returns the next injected failure (if any). The lock must be held.
+func (f *Fake) call(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("could not call: %w", ctx.Err())
+	}
+
+	f.calls++
+
+	if len(f.failures) == 0 {
+		return nil
+	}
+
+	err := f.failures[0]
+	f.failures = f.failures[1:]
+
+	return err
+}
@@ -0,0 +1,113 @@
+/*
+Package minting offers clients of NFT minting services: NMKR Studio, and an in-process fake for tests and local runs.
+*/
+package minting
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrNotFound for when the minting service does not know a token.
+	ErrNotFound = errors.New("token not found")
+	// ErrRejected for when the minting service refuses a request; sending it again will not help.
+	ErrRejected = errors.New("rejected by the minting service")
+	// ErrUnavailable for when the minting service could not be reached, or failed; the request can be sent again.
+	ErrUnavailable = errors.New("minting service unavailable")
+)
+
+// State of a token, as reported by the minting service.
+type State string
+
+const (
+	// StateFree tokens were uploaded, and can be minted.
+	StateFree State = "free"
+	// StateReserved tokens are being minted.
+	StateReserved State = "reserved"
+	// StateSold tokens were minted, and sent.
+	StateSold State = "sold"
+	// StateError tokens could not be minted.
+	StateError State = "error"
+)
+
+// Token models an NFT to upload.
+type Token struct {
+	// Name is the asset name, on chain.
+	Name        string
+	DisplayName string
+	Description string
+	Image       Image
+	// Metadata fills the placeholders of the metadata template of the project.
+	Metadata map[string]string
+}
+
+// Image models the image of a token: a file pinned on IPFS, or its content (base64-encoded).
+type Image struct {
+	MimeType string
+	IPFS     string
+	Base64   string
+}
+
+// Upload models a token uploaded to the minting service.
+type Upload struct {
+	// UID identifies the token in the minting service.
+	UID      string `json:"uid"`
+	AssetID  string `json:"asset_id,omitempty"`
+	IPFSHash string `json:"ipfs_hash,omitempty"`
+}
+
+// Mint models a token minted and sent to a wallet.
+type Mint struct {
+	// ID identifies the mint in the minting service.
+	ID       string `json:"id"`
+	UID      string `json:"uid"`
+	Receiver string `json:"receiver"`
+}
+
+// Status models the state of an uploaded token.
+type Status struct {
+	UID         string `json:"uid"`
+	State       State  `json:"state"`
+	Minted      bool   `json:"minted"`
+	PolicyID    string `json:"policy_id,omitempty"`
+	AssetID     string `json:"asset_id,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// TransactionID is the hash of the transaction that minted the token.
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// Minter uploads tokens to a minting service, mints them and sends them to wallets.
+//
+// Errors match ErrNotFound, ErrRejected or ErrUnavailable; only the latter are worth retrying.
+type Minter interface {
+	// Upload a token, ready to be minted.
+	Upload(ctx context.Context, token Token) (Upload, error)
+	// Find returns the uploaded token with a given name; token names are unique, so that an upload that may have gone
+	// through can be found rather than sent again.
+	Find(ctx context.Context, name string) (Upload, error)
+	// MintAndSend mints the uploaded token with a given UID, and sends it to the wallet at receiver; an empty receiver
+	// sends it to the wallet of the project.
+	MintAndSend(ctx context.Context, uid string, receiver string) (Mint, error)
+	// Status returns the state of the uploaded token with a given UID.
+	Status(ctx context.Context, uid string) (Status, error)
+}
+
+// IsRetryable tells whether a request that failed with err can be sent again.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// metadataNames returns the names of the metadata placeholders of a token, sorted.
+func metadataNames(token Token) []string {
+	result := make([]string, 0, len(token.Metadata))
+
+	for name := range token.Metadata {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,144 @@
+package minting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NMKR(t *testing.T) {
+	var uploaded nmkrUpload
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+
+		switch req.URL.Path {
+		case "/v2/UploadNft/project":
+			assert.Equal(t, "namless", req.URL.Query().Get("uploadsource"))
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&uploaded))
+			writer.Write([]byte(`{"nftId": 1, "nftUid": "uid-1", "assetId": "asset1"}`))
+		case "/v2/MintAndSendSpecific/project/uid-1/1/addr_test1default":
+			writer.Write([]byte(`{"mintAndSendId": 42}`))
+		case "/v2/GetNftDetailsById/uid-1":
+			writer.Write([]byte(`{"uid": "uid-1", "state": "sold", "minted": true, "initialminttxhash": "tx"}`))
+		case "/v2/GetNftDetailsByTokenname/project/Token1":
+			writer.Write([]byte(`{"uid": "uid-1", "state": "free", "assetid": "asset1"}`))
+		case "/v2/GetNftDetailsById/busy":
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte(`{"errorCode": 404, "errorMessage": "Nft not found", "resultState": "Error"}`))
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewNMKR(NMKRConfig{BaseURL: server.URL})
+	assert.ErrorIs(t, err, ErrMissingConfig)
+
+	client, err := NewNMKR(NMKRConfig{
+		BaseURL:         server.URL + "/",
+		ProjectUID:      "project",
+		APIKey:          "secret",
+		ReceiverAddress: "addr_test1default",
+		UploadSource:    "namless",
+	})
+	assert.NoError(t, err)
+
+	upload, err := client.Upload(context.TODO(), Token{
+		Name:     "Token1",
+		Image:    Image{MimeType: "image/png", IPFS: "Qm"},
+		Metadata: map[string]string{"b": "2", "a": "1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Upload{UID: "uid-1", AssetID: "asset1"}, upload)
+	assert.Equal(t, "Token1", uploaded.TokenName)
+	assert.Equal(t, nmkrFile{MimeType: "image/png", FileFromIPFS: "Qm"}, uploaded.PreviewImage)
+	assert.Equal(t, []nmkrMetadataElement{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}, uploaded.MetadataPlaceholder)
+
+	found, err := client.Find(context.TODO(), "Token1")
+	assert.NoError(t, err)
+	assert.Equal(t, Upload{UID: "uid-1", AssetID: "asset1"}, found)
+
+	_, err = client.Find(context.TODO(), "Token2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mint, err := client.MintAndSend(context.TODO(), "uid-1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Mint{ID: "42", UID: "uid-1", Receiver: "addr_test1default"}, mint)
+
+	status, err := client.Status(context.TODO(), "uid-1")
+	assert.NoError(t, err)
+	assert.Equal(t, Status{UID: "uid-1", State: StateSold, Minted: true, TransactionID: "tx"}, status)
+
+	_, err = client.Status(context.TODO(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, "Nft not found")
+	assert.False(t, IsRetryable(err))
+
+	_, err = client.Status(context.TODO(), "busy")
+	assert.True(t, IsRetryable(err))
+
+	_, err = client.MintAndSend(context.TODO(), "other", "addr_test1other")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	server.Close()
+
+	_, err = client.Status(context.TODO(), "uid-1")
+	assert.True(t, IsRetryable(err), "unreachable services can be retried")
+}
+
+func Test_Fake(t *testing.T) {
+	fake := NewFake()
+	token := Token{Name: "Token1", Image: Image{MimeType: "image/png", IPFS: "Qm"}}
+
+	upload, err := fake.Upload(context.TODO(), token)
+	assert.NoError(t, err)
+
+	found, ok := fake.Token(upload.UID)
+	assert.True(t, ok)
+	assert.Equal(t, token, found)
+
+	_, err = fake.Upload(context.TODO(), token)
+	assert.ErrorIs(t, err, ErrRejected, "token names are unique")
+
+	again, err := fake.Find(context.TODO(), "Token1")
+	assert.NoError(t, err)
+	assert.Equal(t, upload, again)
+
+	_, err = fake.Find(context.TODO(), "Token2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	status, err := fake.Status(context.TODO(), upload.UID)
+	assert.NoError(t, err)
+	assert.Equal(t, StateFree, status.State)
+
+	failure := errors.New("boom")
+	fake.Fail(ErrUnavailable, failure)
+
+	_, err = fake.MintAndSend(context.TODO(), upload.UID, "")
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	_, err = fake.MintAndSend(context.TODO(), upload.UID, "")
+	assert.ErrorIs(t, err, failure)
+
+	mint, err := fake.MintAndSend(context.TODO(), upload.UID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, FakeReceiver, mint.Receiver)
+
+	_, err = fake.MintAndSend(context.TODO(), upload.UID, "")
+	assert.ErrorIs(t, err, ErrRejected, "tokens are only minted once")
+
+	status, err = fake.Status(context.TODO(), upload.UID)
+	assert.NoError(t, err)
+	assert.True(t, status.Minted)
+	assert.Equal(t, StateSold, status.State)
+
+	_, err = fake.Status(context.TODO(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 11, fake.Calls())
+}
@@ -0,0 +1,300 @@
+package minting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultNMKRURL is the base URL of the NMKR Studio API.
+	DefaultNMKRURL = "https://studio-api.nmkr.io"
+	// DefaultNMKRTimeout bounds every request to NMKR Studio.
+	DefaultNMKRTimeout = 30 * time.Second
+
+	// mintCount is the number of copies of a token minted at once.
+	mintCount = 1
+	// maxResponseBytes bounds the responses read from NMKR Studio.
+	maxResponseBytes = 1 << 20
+)
+
+// ErrMissingConfig for when the NMKR client is built without a project or an API key.
+var ErrMissingConfig = errors.New("missing NMKR config")
+
+// NMKRConfig configures the NMKR Studio client.
+type NMKRConfig struct {
+	// BaseURL of the API; DefaultNMKRURL when empty.
+	BaseURL string
+	// ProjectUID is the project tokens are uploaded to.
+	ProjectUID string
+	// APIKey authenticates requests.
+	APIKey string
+	// ReceiverAddress is the wallet tokens are sent to when a mint names none.
+	ReceiverAddress string
+	// UploadSource tags the uploaded tokens in NMKR Studio.
+	UploadSource string
+	// Timeout bounds every request; DefaultNMKRTimeout when zero.
+	Timeout time.Duration
+}
+
+// NMKR is a Minter backed by NMKR Studio.
+type NMKR struct {
+	config NMKRConfig
+	client *http.Client
+}
+
+// NewNMKR builds a new NMKR Studio client.
+func NewNMKR(config NMKRConfig) (*NMKR, error) {
+	if config.ProjectUID == "" || config.APIKey == "" {
+		return nil, fmt.Errorf("%w: expected a project UID and an API key", ErrMissingConfig)
+	}
+
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultNMKRURL
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultNMKRTimeout
+	}
+
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	return &NMKR{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+// nmkrUpload models the body of an upload request.
+type nmkrUpload struct {
+	TokenName           string                `json:"tokenname"`
+	DisplayName         string                `json:"displayname,omitempty"`
+	Description         string                `json:"description,omitempty"`
+	PreviewImage        nmkrFile              `json:"previewImageNft"`
+	MetadataPlaceholder []nmkrMetadataElement `json:"metadataPlaceholder,omitempty"`
+}
+
+// nmkrFile models a file of a token.
+type nmkrFile struct {
+	MimeType       string `json:"mimetype"`
+	FileFromIPFS   string `json:"fileFromIPFS,omitempty"`
+	FileFromBase64 string `json:"fileFromBase64,omitempty"`
+}
+
+// nmkrMetadataElement models the value of a metadata placeholder.
+type nmkrMetadataElement struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// nmkrUploaded models the response to an upload request.
+type nmkrUploaded struct {
+	NFTUID          string `json:"nftUid"`
+	AssetID         string `json:"assetId"`
+	IPFSHashMainNFT string `json:"ipfsHashMainnft"`
+}
+
+// nmkrMinted models the response to a mint request.
+type nmkrMinted struct {
+	MintAndSendID int64 `json:"mintAndSendId"`
+}
+
+// nmkrDetails models the response to a details request.
+type nmkrDetails struct {
+	UID               string `json:"uid"`
+	State             string `json:"state"`
+	Minted            bool   `json:"minted"`
+	PolicyID          string `json:"policyId"`
+	AssetID           string `json:"assetid"`
+	Fingerprint       string `json:"fingerprint"`
+	InitialMintTxHash string `json:"initialminttxhash"`
+}
+
+// nmkrError models the body of a failed request.
+type nmkrError struct {
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// Upload a token to the project.
+func (n *NMKR) Upload(ctx context.Context, token Token) (Upload, error) {
+	body := nmkrUpload{
+		TokenName:   token.Name,
+		DisplayName: token.DisplayName,
+		Description: token.Description,
+		PreviewImage: nmkrFile{
+			MimeType:       token.Image.MimeType,
+			FileFromIPFS:   token.Image.IPFS,
+			FileFromBase64: token.Image.Base64,
+		},
+	}
+
+	for _, name := range metadataNames(token) {
+		body.MetadataPlaceholder = append(
+			body.MetadataPlaceholder, nmkrMetadataElement{Name: name, Value: token.Metadata[name]},
+		)
+	}
+
+	query := url.Values{}
+	if n.config.UploadSource != "" {
+		query.Set("uploadsource", n.config.UploadSource)
+	}
+
+	uploaded := nmkrUploaded{}
+
+	err := n.do(ctx, http.MethodPost, n.path("UploadNft", n.config.ProjectUID)+"?"+query.Encode(), body, &uploaded)
+	if err != nil {
+		return Upload{}, fmt.Errorf("could not upload token: %w", err)
+	}
+
+	return Upload{UID: uploaded.NFTUID, AssetID: uploaded.AssetID, IPFSHash: uploaded.IPFSHashMainNFT}, nil
+}
+
+// Find returns the token of the project with a given name.
+func (n *NMKR) Find(ctx context.Context, name string) (Upload, error) {
+	details := nmkrDetails{}
+
+	err := n.do(ctx, http.MethodGet, n.path("GetNftDetailsByTokenname", n.config.ProjectUID, name), nil, &details)
+	if err != nil {
+		return Upload{}, fmt.Errorf("could not find token: %w", err)
+	}
+
+	return Upload{UID: details.UID, AssetID: details.AssetID}, nil
+}
+
+// MintAndSend mints an uploaded token, and sends it to a wallet.
+func (n *NMKR) MintAndSend(ctx context.Context, uid string, receiver string) (Mint, error) {
+	if receiver == "" {
+		receiver = n.config.ReceiverAddress
+	}
+
+	if receiver == "" {
+		return Mint{}, fmt.Errorf("%w: no receiver, and no ReceiverAddress configured", ErrRejected)
+	}
+
+	minted := nmkrMinted{}
+
+	err := n.do(
+		ctx,
+		http.MethodGet,
+		n.path("MintAndSendSpecific", n.config.ProjectUID, uid, strconv.Itoa(mintCount), receiver),
+		nil,
+		&minted,
+	)
+	if err != nil {
+		return Mint{}, fmt.Errorf("could not mint token: %w", err)
+	}
+
+	return Mint{ID: strconv.FormatInt(minted.MintAndSendID, 10), UID: uid, Receiver: receiver}, nil
+}
+
+// Status returns the state of an uploaded token.
+func (n *NMKR) Status(ctx context.Context, uid string) (Status, error) {
+	details := nmkrDetails{}
+
+	err := n.do(ctx, http.MethodGet, n.path("GetNftDetailsById", uid), nil, &details)
+	if err != nil {
+		return Status{}, fmt.Errorf("could not get token status: %w", err)
+	}
+
+	return Status{
+		UID:           uid,
+		State:         State(details.State),
+		Minted:        details.Minted,
+		PolicyID:      details.PolicyID,
+		AssetID:       details.AssetID,
+		Fingerprint:   details.Fingerprint,
+		TransactionID: details.InitialMintTxHash,
+	}, nil
+}
+
+// path returns the path of a v2 endpoint, with escaped parameters.
+func (n *NMKR) path(endpoint string, parameters ...string) string {
+	result := n.config.BaseURL + "/v2/" + endpoint
+
+	for _, parameter := range parameters {
+		result += "/" + url.PathEscape(parameter)
+	}
+
+	return result
+}
+
+// do sends a request with a JSON body (unless nil), and decodes the JSON response into result.
+//
+// Network failures, rate limits and server errors match ErrUnavailable; 404 matches ErrNotFound, and other failures
+// ErrRejected.
+func (n *NMKR) do(ctx context.Context, method string, target string, body any, result any) error {
+	var content io.Reader
+
+	if body != nil {
+		asJSON, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not marshal: %w", err)
+		}
+
+		content = bytes.NewReader(asJSON)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, content)
+	if err != nil {
+		return fmt.Errorf("could not build request: %w", err)
+	}
+
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "Bearer "+n.config.APIKey)
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := n.client.Do(request)
+	if ctx.Err() != nil {
+		return fmt.Errorf("could not send request: %w", ctx.Err())
+	}
+
+	if err != nil {
+		// the error holds the URL, which holds the receiver; the API key is only in the headers
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	defer response.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(response.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("%w: could not read response: %w", ErrUnavailable, err)
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return statusError(response.StatusCode, raw)
+	}
+
+	err = json.Unmarshal(raw, result)
+	if err != nil {
+		return fmt.Errorf("%w: could not unmarshal response: %w", ErrUnavailable, err)
+	}
+
+	return nil
+}
+
+// statusError returns the error matching the status of a failed response.
+func statusError(status int, body []byte) error {
+	upstream := nmkrError{}
+
+	message := http.StatusText(status)
+	if json.Unmarshal(body, &upstream) == nil && upstream.ErrorMessage != "" {
+		message = upstream.ErrorMessage
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, message)
+	case status == http.StatusTooManyRequests || status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %d %s", ErrUnavailable, status, message)
+	}
+
+	return fmt.Errorf("%w: %d %s", ErrRejected, status, message)
+}
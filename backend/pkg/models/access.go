@@ -0,0 +1,29 @@
+package models
+
+const (
+	// AccessRead lets a principal read an item.
+	AccessRead = "read"
+	// AccessWrite lets a principal read, change and delete an item.
+	AccessWrite = "write"
+)
+
+// Grants maps the IDs of principals to the access (AccessRead or AccessWrite) they were granted over an item, on
+// top of its owner.
+type Grants map[string]string
+
+// Allows tells whether an item with a given owner and grants gives an access to a principal. Items without an owner
+// are open to everyone.
+func Allows(owner string, grants Grants, principalID string, access string) bool {
+	if owner == "" || owner == principalID {
+		return true
+	}
+
+	switch grants[principalID] {
+	case AccessWrite:
+		return true
+	case AccessRead:
+		return access == AccessRead
+	}
+
+	return false
+}
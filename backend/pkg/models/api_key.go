@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// APIKey models a key callers authenticate with. Its secret is never stored, only its hash.
+type APIKey struct {
+	ID string `json:"id" gorm:"primaryKey"`
+	// Name tells what (or who) the key is for.
+	Name string `json:"name"`
+	// Hash is the SHA-256 of the secret of the key, hex-encoded.
+	Hash      string     `json:"hash,omitempty"`
+	Scopes    []string   `json:"scopes" gorm:"serializer:json"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsRevoked tells whether the key can no longer be used.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Claim models a check-in at a location, which earns the wallet a token. A wallet claims a location once.
+type Claim struct {
+	ID         string `json:"id" gorm:"primaryKey"`
+	LocationID int    `json:"location_id"`
+	Wallet     string `json:"wallet"`
+	// Latitude and Longitude are where the check-in was made from; Distance is how far from the location, in meters.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Distance  float64 `json:"distance"`
+	// JobID is the mint job of the earned token.
+	JobID     string    `json:"job_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
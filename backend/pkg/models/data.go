@@ -9,11 +9,70 @@ import (
 	"gorm.io/gorm"
 )
 
-// Data models a (key, value) data item.
+// Data models a (key, value) data item. Its key is unique within its namespace.
 type Data struct {
-	ID        string `json:"id,omitempty" gorm:"primary_key"`
+	// Namespace keeps apart the items of different tenants; see types.DefaultNamespace.
+	Namespace string `json:"namespace,omitempty" gorm:"primaryKey"`
+	ID        string `json:"id,omitempty" gorm:"primaryKey"`
 	Value     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
+	// ExpiresAt is when the item stops being visible; nil when it never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	// Version is the number of the item's latest revision; it grows with every write.
+	Version int64 `json:"version"`
+	// UpdatedBy is the ID of the caller that wrote the value (see types.Principal); empty when unknown.
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// Owner is the ID of the caller that created the item; empty for items open to everyone.
+	Owner string `json:"owner,omitempty" gorm:"index"`
+	// Grants gives other callers access to the item.
+	Grants Grants `json:"grants,omitempty" gorm:"serializer:json"`
+}
+
+// IsExpired tells whether the item expired at a given moment.
+func (d *Data) IsExpired(now time.Time) bool {
+	return d.ExpiresAt != nil && !d.ExpiresAt.After(now)
+}
+
+// Revision returns the revision recording the current state of the item.
+func (d *Data) Revision() DataVersion {
+	result := DataVersion{
+		Namespace: d.Namespace,
+		ID:        d.ID,
+		Version:   d.Version,
+		Value:     d.Value,
+		ExpiresAt: d.ExpiresAt,
+		CreatedAt: d.UpdatedAt,
+		UpdatedBy: d.UpdatedBy,
+	}
+
+	if d.DeletedAt.Valid {
+		result.Deleted = true
+		result.Value = ""
+		result.UpdatedBy = ""
+		result.CreatedAt = d.DeletedAt.Time
+	}
+
+	return result
+}
+
+// DataVersion models a revision of a data item: its state after a given write.
+type DataVersion struct {
+	Namespace string     `json:"namespace,omitempty" gorm:"primaryKey"`
+	ID        string     `json:"id" gorm:"primaryKey"`
+	Version   int64      `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Deleted marks the revision written by a delete.
+	Deleted bool `json:"deleted,omitempty"`
+	// CreatedAt is when the write happened.
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedBy is the ID of the caller that made the write; empty when unknown, and for deletes.
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// IsVisible tells whether the item had a value at a given moment, according to this revision.
+func (dv *DataVersion) IsVisible(now time.Time) bool {
+	return !dv.Deleted && (dv.ExpiresAt == nil || dv.ExpiresAt.After(now))
 }
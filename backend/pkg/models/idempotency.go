@@ -0,0 +1,29 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord models a request sent with an Idempotency-Key header, and the response it got.
+type IdempotencyRecord struct {
+	Key    string `json:"key" gorm:"primaryKey"`
+	Method string `json:"method"`
+	// Target is the path and the query of the request.
+	Target string `json:"target"`
+	// Fingerprint is the SHA-256 of the body of the request, hex-encoded; empty while the request is in flight.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// StatusCode of the response; zero while the request is in flight.
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header,omitempty" gorm:"serializer:json"`
+	Body       []byte      `json:"body,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	// ExpiresAt is when the key can be used again: the end of the replay window, or of the time a request in flight
+	// is waited for.
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// IsInFlight tells whether the request has not got its response yet.
+func (ir *IdempotencyRecord) IsInFlight() bool {
+	return ir.StatusCode == 0
+}
@@ -1,10 +1,59 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // Location models a location.
 type Location struct {
-	Latitude   float32 `json:"latitude"`
-	Longitutde float32 `json:"longitutde"`
-	ID         int     `json:"id"`
-	Location   string  `json:"location"`
-	Image      string  `json:"image"`
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+	ID        int     `json:"id"`
+	Location  string  `json:"location"`
+	Image     string  `json:"image"`
+	// Thumbnails are the URLs of smaller versions of an uploaded image, by size name; empty for other images.
+	Thumbnails map[string]string `json:"thumbnails,omitempty" gorm:"serializer:json"`
+	// Radius is the distance, in meters, within which a check-in at the location counts; the default one when zero.
+	Radius float64 `json:"radius,omitempty"`
+	// UpdatedBy is the ID of the caller that wrote the location last (see types.Principal); empty when unknown.
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// Owner is the ID of the caller that created the location; empty for locations anyone can change. Anyone can
+	// read locations.
+	Owner string `json:"owner,omitempty"`
+	// Grants lets other callers change the location; only AccessWrite makes sense.
+	Grants Grants `json:"grants,omitempty" gorm:"serializer:json"`
+}
+
+// UnmarshalJSON also accepts the misspelled "longitutde" key, which was used before it was renamed, so that older
+// clients and files keep working. "longitude" wins when both are there.
+func (l *Location) UnmarshalJSON(data []byte) error {
+	type plain Location
+
+	aux := struct {
+		*plain
+		Longitude  *float32 `json:"longitude"`
+		Longitutde *float32 `json:"longitutde"`
+	}{plain: (*plain)(l)}
+
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal location: %w", err)
+	}
+
+	switch {
+	case aux.Longitude != nil:
+		l.Longitude = *aux.Longitude
+	case aux.Longitutde != nil:
+		l.Longitude = *aux.Longitutde
+	}
+
+	return nil
+}
+
+// LocationDistance models a location, along with its distance from a point.
+type LocationDistance struct {
+	Location
+	// Distance from the point, in meters.
+	Distance float64 `json:"distance"`
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LocationUnmarshal(t *testing.T) {
+	var location Location
+
+	err := json.Unmarshal([]byte(`{"id": 1, "latitude": 1.5, "longitutde": 2.5}`), &location)
+	assert.NoError(t, err)
+	assert.Equal(t, Location{ID: 1, Latitude: 1.5, Longitude: 2.5}, location)
+
+	err = json.Unmarshal([]byte(`{"longitude": 3.5, "longitutde": 2.5}`), &location)
+	assert.NoError(t, err)
+	assert.InDelta(t, 3.5, location.Longitude, 0)
+
+	asJSON, err := json.Marshal(Location{Longitude: 3.5})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": 0, "latitude": 0, "longitude": 3.5, "location": "", "image": ""}`, string(asJSON))
+}
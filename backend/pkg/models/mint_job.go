@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+)
+
+// JobState is the step a mint job is at.
+type JobState string
+
+const (
+	// JobQueued jobs wait for a worker: they are new, or wait to be retried.
+	JobQueued JobState = "queued"
+	// JobUploading jobs upload their token to the minting service.
+	JobUploading JobState = "uploading"
+	// JobMinting jobs mint their uploaded token, and send it.
+	JobMinting JobState = "minting"
+	// JobDone jobs minted their token, and sent it.
+	JobDone JobState = "done"
+	// JobFailed jobs gave up; LastError tells why.
+	JobFailed JobState = "failed"
+)
+
+// MintJob models a request to mint a token, processed in the background.
+type MintJob struct {
+	ID    string   `json:"id" gorm:"primaryKey"`
+	State JobState `json:"state"`
+	// Token to upload; nil when the job mints a token uploaded beforehand.
+	Token *minting.Token `json:"token,omitempty" gorm:"serializer:json"`
+	// Receiver is the wallet the token is sent to; the one of the project when empty.
+	Receiver string `json:"receiver,omitempty"`
+	// UID of the uploaded token; set once it is uploaded.
+	UID string `json:"uid,omitempty"`
+	// MintID identifies the mint in the minting service; set once it is done.
+	MintID string `json:"mint_id,omitempty"`
+	// Attempts is the number of times a worker took the job.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// NextAttemptAt is when the job can be taken by a worker.
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// LeaseUntil is when the worker that took the job is deemed gone, so that another can take it; nil when no worker
+	// holds the job.
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+	// Version grows with every write, so that a worker that lost its lease cannot overwrite the job.
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsFinished tells whether the job is done, or failed.
+func (j *MintJob) IsFinished() bool {
+	return j.State == JobDone || j.State == JobFailed
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// sweepInterval is the time between two removals of the buckets that filled up again.
+const sweepInterval = time.Minute
+
+// Memory keeps buckets in memory, for a single instance of the service; they are lost on restart.
+//
+// Full buckets are removed every now and then, since they are no different from the ones never seen before.
+type Memory struct {
+	buckets map[string]*memoryBucket
+	mutex   sync.Mutex
+	sweptAt time.Time
+}
+
+// memoryBucket is a bucket along with its limit, which tells when it is full again.
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+// NewMemory builds a new, empty, Memory store.
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take a token from each of the buckets with the given keys, when they all have one.
+func (m *Memory) Take(ctx context.Context, keys []Key, now time.Time) ([]Decision, error) {
+	if ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if now.Sub(m.sweptAt) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	items := make([]*memoryBucket, len(keys))
+	allowed := true
+
+	for position, key := range keys {
+		item, found := m.buckets[key.Name]
+		if !found {
+			item = &memoryBucket{bucket: bucket{tokens: float64(key.Limit.Burst), updatedAt: now}}
+			m.buckets[key.Name] = item
+		}
+
+		item.limit = key.Limit
+		item.refill(key.Limit, now)
+		items[position] = item
+		allowed = allowed && item.tokens >= 1
+	}
+
+	result := make([]Decision, len(keys))
+
+	for position, item := range items {
+		result[position] = item.decide(item.limit, allowed)
+	}
+
+	return result, nil
+}
+
+// sweep removes the buckets that are full at a given moment.
+//
+// Callers must hold the lock.
+func (m *Memory) sweep(now time.Time) {
+	for key, item := range m.buckets {
+		item.refill(item.limit, now)
+
+		if item.tokens >= float64(item.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+
+	m.sweptAt = now
+}
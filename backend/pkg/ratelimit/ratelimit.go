@@ -0,0 +1,108 @@
+/*
+Package ratelimit offers token-bucket rate limiting: every key has a bucket holding up to Burst tokens, refilled with
+one token every Limit.Every, and each request takes one token, or is refused when there is none left.
+
+The buckets are kept by a Store, so that several instances of the service can share them.
+*/
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidLimit for when a limit lets no request through.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit of a token bucket.
+type Limit struct {
+	// Burst is the largest number of requests let through at once, when the bucket is full.
+	Burst int
+	// Every is the time it takes to get a token back.
+	Every time.Duration
+}
+
+// PerPeriod returns the limit letting requests through per period, with a given burst.
+func PerPeriod(requests int, period time.Duration, burst int) Limit {
+	if requests <= 0 {
+		return Limit{Burst: burst}
+	}
+
+	return Limit{Burst: burst, Every: period / time.Duration(requests)}
+}
+
+// Validate returns ErrInvalidLimit when the limit lets no request through.
+func (l Limit) Validate() error {
+	if l.Burst <= 0 || l.Every <= 0 {
+		return fmt.Errorf("%w: expected a positive burst and refill time, got %d every %s", ErrInvalidLimit, l.Burst, l.Every)
+	}
+
+	return nil
+}
+
+// Decision tells whether a request was let through, and how much of its limit is left.
+type Decision struct {
+	Allowed bool
+	// Limit is the burst of the limit.
+	Limit int
+	// Remaining is the number of requests that can be let through right away.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request can be let through, when this one was not.
+	RetryAfter time.Duration
+}
+
+// Key names a bucket, along with its limit.
+type Key struct {
+	Name  string
+	Limit Limit
+}
+
+// Store keeps the buckets, by key.
+type Store interface {
+	// Take a token from each of the buckets with the given keys, at a given moment, and tells whether there was one,
+	// in a decision per key. Tokens are only taken when every bucket has one, so that a request refused by one limit
+	// does not use up the others. Buckets never seen before are full.
+	Take(ctx context.Context, keys []Key, now time.Time) ([]Decision, error)
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	// tokens held at updatedAt; a fraction means that the next token is on its way.
+	tokens    float64
+	updatedAt time.Time
+}
+
+// decide tells whether the bucket, refilled up to now, has a token, and takes it when asked to.
+func (b *bucket) decide(limit Limit, take bool) Decision {
+	result := Decision{Limit: limit.Burst, Allowed: take}
+
+	if take {
+		b.tokens--
+	} else {
+		result.RetryAfter = max(limit.duration(1-b.tokens), 0)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = limit.duration(float64(limit.Burst) - b.tokens)
+
+	return result
+}
+
+// refill adds the tokens earned since the last update, up to the burst.
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if now.After(b.updatedAt) {
+		b.tokens += float64(now.Sub(b.updatedAt)) / float64(limit.Every)
+		b.updatedAt = now
+	}
+
+	b.tokens = min(b.tokens, float64(limit.Burst))
+}
+
+// duration returns the time it takes to earn a number of tokens.
+func (l Limit) duration(tokens float64) time.Duration {
+	return time.Duration(tokens * float64(l.Every))
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Limit(t *testing.T) {
+	assert.Equal(t, Limit{Burst: 5, Every: 6 * time.Second}, PerPeriod(10, time.Minute, 5))
+	assert.NoError(t, PerPeriod(10, time.Minute, 5).Validate())
+	assert.ErrorIs(t, PerPeriod(0, time.Minute, 5).Validate(), ErrInvalidLimit)
+	assert.ErrorIs(t, PerPeriod(10, time.Minute, 0).Validate(), ErrInvalidLimit)
+}
+
+func Test_Memory(t *testing.T) {
+	store := NewMemory()
+	limit := Limit{Burst: 3, Every: time.Second}
+	now := time.Now()
+
+	take := func(name string, at time.Time) Decision {
+		decisions, err := store.Take(context.TODO(), []Key{{Name: name, Limit: limit}}, at)
+		assert.NoError(t, err)
+		assert.Len(t, decisions, 1)
+
+		return decisions[0]
+	}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		reset := time.Duration(3-remaining) * time.Second
+		assert.Equal(t, Decision{Allowed: true, Limit: 3, Remaining: remaining, Reset: reset}, take("a", now))
+	}
+
+	decision := take("a", now)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+
+	// keys have buckets of their own
+	assert.True(t, take("b", now).Allowed)
+
+	// tokens come back over time
+	decision = take("a", now.Add(1500*time.Millisecond))
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+
+	decision = take("a", now.Add(1500*time.Millisecond))
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+
+	// full buckets are swept, and come back full
+	take("c", now.Add(time.Minute))
+	assert.Len(t, store.buckets, 1)
+	assert.Equal(t, 2, take("a", now.Add(time.Minute)).Remaining)
+
+	_, err := store.Take(cancelled(), []Key{{Name: "a", Limit: limit}}, now)
+	assert.Error(t, err)
+}
+
+func Test_MemoryAllOrNothing(t *testing.T) {
+	store := NewMemory()
+	now := time.Now()
+	keys := []Key{
+		{Name: "caller", Limit: Limit{Burst: 1, Every: time.Minute}},
+		{Name: "route", Limit: Limit{Burst: 3, Every: time.Minute}},
+	}
+
+	decisions, err := store.Take(context.TODO(), keys, now)
+	assert.NoError(t, err)
+	assert.True(t, decisions[0].Allowed)
+	assert.True(t, decisions[1].Allowed)
+
+	// the caller is out of tokens: the route keeps its own
+	for range 5 {
+		decisions, err = store.Take(context.TODO(), keys, now)
+		assert.NoError(t, err)
+		assert.Equal(t, Decision{Limit: 1, Reset: time.Minute, RetryAfter: time.Minute}, decisions[0])
+		assert.Equal(t, Decision{Limit: 3, Remaining: 2, Reset: time.Minute}, decisions[1])
+	}
+}
+
+// cancelled returns a context that is done.
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
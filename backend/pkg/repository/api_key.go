@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// apiKeyID is the unique field of API keys.
+var apiKeyID = sortField[models.APIKey]{
+	column: "id", value: func(item models.APIKey) any { return item.ID }, parse: parseString,
+}
+
+// apiKeyListing describes how API keys can be listed.
+var apiKeyListing = listing[models.APIKey]{
+	defaultSort: "created_at",
+	id:          apiKeyID,
+	sorts: map[string]sortField[models.APIKey]{
+		"id": apiKeyID,
+		"name": {
+			column: "name", value: func(item models.APIKey) any { return item.Name }, parse: parseString,
+		},
+		"created_at": {
+			column: "created_at", value: func(item models.APIKey) any { return item.CreatedAt }, parse: parseTime,
+		},
+	},
+	filters: map[string]filterField[models.APIKey]{
+		"name": {column: "name", value: func(item models.APIKey) string { return item.Name }},
+	},
+}
+
+// APIKey models the DB operations available for API keys.
+type APIKey struct {
+	db *gorm.DB
+}
+
+// NewAPIKey builds a new APIKey repository.
+//
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
+func NewAPIKey(dsn string, silent bool) (*APIKey, error) {
+	result := &APIKey{}
+
+	cfg := &gorm.Config{TranslateError: true}
+	if silent {
+		cfg.Logger = NewNoopLogger()
+	}
+
+	var err error
+
+	result.db, err = gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not open APIKey DB: %w", err)
+	}
+
+	return result, nil
+}
+
+// NewAPIKeyTruncate builds a new APIKey repo, and deletes its previous contents.
+//
+// Meant to be used in tests.
+func NewAPIKeyTruncate(dsn string, silent bool) (*APIKey, error) {
+	result, err := NewAPIKey(dsn, silent)
+
+	if err == nil {
+		success := result.db.Exec("TRUNCATE TABLE api_keys;")
+		if success.Error != nil {
+			return nil, fmt.Errorf("could not truncate: %w", success.Error)
+		}
+	}
+
+	return result, err
+}
+
+// Create a new API key.
+func (a *APIKey) Create(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	key.CreatedAt = time.Now().Truncate(time.Microsecond)
+	key.RevokedAt = nil
+
+	success := a.db.WithContext(ctx).Create(&key)
+	if errors.Is(success.Error, gorm.ErrDuplicatedKey) {
+		return models.APIKey{}, fmt.Errorf("could not create API key %q: %w", key.ID, ErrAlreadyExists)
+	}
+
+	if success.Error != nil {
+		return models.APIKey{}, fmt.Errorf("could not create API key: %w", success.Error)
+	}
+
+	return key, nil
+}
+
+// ByID returns the API key with a given ID.
+func (a *APIKey) ByID(ctx context.Context, keyID string) (models.APIKey, error) {
+	var result models.APIKey
+
+	success := a.db.WithContext(ctx).First(&result, "id = ?", keyID)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.APIKey{}, fmt.Errorf("could not find API key with ID %q: %w", keyID, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.APIKey{}, fmt.Errorf("could not find API key with ID %q: %w", keyID, success.Error)
+	}
+
+	return result, nil
+}
+
+// List returns a page of API keys.
+func (a *APIKey) List(ctx context.Context, opts types.ListOptions) (types.Page[models.APIKey], error) {
+	query, err := apiKeyListing.query(opts)
+	if err != nil {
+		return types.Page[models.APIKey]{}, err
+	}
+
+	result, err := query.gormPage(ctx, a.db)
+	if err != nil {
+		return types.Page[models.APIKey]{}, fmt.Errorf("could not list API keys: %w", err)
+	}
+
+	return result, nil
+}
+
+// Revoke the API key with a given ID, at a given moment; a key that was revoked already is left as it is.
+func (a *APIKey) Revoke(ctx context.Context, keyID string, at time.Time) (models.APIKey, error) {
+	at = at.Truncate(time.Microsecond)
+
+	success := a.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", at)
+	if success.Error != nil {
+		return models.APIKey{}, fmt.Errorf("could not revoke API key: %w", success.Error)
+	}
+
+	return a.ByID(ctx, keyID)
+}
+
+// Close closes the DB connection.
+func (a *APIKey) Close(ctx context.Context) error {
+	database, err := a.db.WithContext(ctx).DB()
+	if err != nil {
+		return fmt.Errorf("could not get DB: %w", err)
+	}
+
+	err = database.Close()
+	if err != nil {
+		return fmt.Errorf("could not close DB: %w", err)
+	}
+
+	return nil
+}
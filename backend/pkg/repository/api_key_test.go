@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_APIKeys(t *testing.T) {
+	for name, build := range apiKeyBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, key := range []models.APIKey{
+				{ID: "a", Name: "reader", Hash: "hash-a", Scopes: []string{"data:read"}},
+				{ID: "b", Name: "writer", Hash: "hash-b", Scopes: []string{"data:read", "data:write"}},
+			} {
+				_, err = repo.Create(context.TODO(), key)
+				assert.NoError(t, err)
+			}
+
+			_, err = repo.Create(context.TODO(), models.APIKey{ID: "a", Name: "again"})
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+
+			found, err := repo.ByID(context.TODO(), "b")
+			assert.NoError(t, err)
+			assert.Equal(t, "hash-b", found.Hash)
+			assert.Equal(t, []string{"data:read", "data:write"}, found.Scopes)
+			assert.False(t, found.IsRevoked())
+
+			_, err = repo.ByID(context.TODO(), "missing")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			revokedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+			revoked, err := repo.Revoke(context.TODO(), "a", revokedAt)
+			assert.NoError(t, err)
+			assert.True(t, revoked.IsRevoked())
+
+			// revoking again keeps the first moment
+			revoked, err = repo.Revoke(context.TODO(), "a", revokedAt.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.True(t, revokedAt.Equal(*revoked.RevokedAt))
+
+			_, err = repo.Revoke(context.TODO(), "missing", revokedAt)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			page, err := repo.List(context.TODO(), types.ListOptions{SortBy: "name", Descending: true})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			assert.Equal(t, "writer", page.Items[0].Name)
+		})
+	}
+}
+
+func Test_FileAPIKeyReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.log")
+
+	repo, err := NewFileAPIKey(path)
+	assert.NoError(t, err)
+
+	_, err = repo.Create(context.TODO(), models.APIKey{ID: "a", Name: "reader", Hash: "hash-a"})
+	assert.NoError(t, err)
+
+	_, err = repo.Revoke(context.TODO(), "a", time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	repo, err = NewFileAPIKey(path)
+	assert.NoError(t, err)
+
+	defer repo.Close(context.TODO())
+
+	found, err := repo.ByID(context.TODO(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "hash-a", found.Hash, "the hash is kept in the file")
+	assert.True(t, found.IsRevoked())
+}
+
+// apiKeyBackends returns builders for every APIKeyStore implementation that can be tested in this environment.
+func apiKeyBackends(t *testing.T) map[string]func() (APIKeyStore, error) {
+	t.Helper()
+
+	result := map[string]func() (APIKeyStore, error){
+		"memory": func() (APIKeyStore, error) {
+			return NewMemoryAPIKey(), nil
+		},
+		"file": func() (APIKeyStore, error) {
+			return NewFileAPIKey(filepath.Join(t.TempDir(), "api_keys.log"))
+		},
+	}
+
+	if dsn := os.Getenv(testDSNVariable); dsn != "" {
+		result["postgres"] = func() (APIKeyStore, error) {
+			err := migrateTestDB(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewAPIKeyTruncate(dsn, true)
+		}
+	}
+
+	return result
+}
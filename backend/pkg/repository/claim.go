@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// claimID is the unique field of claims.
+var claimID = sortField[models.Claim]{
+	column: "id", value: func(item models.Claim) any { return item.ID }, parse: parseString,
+}
+
+// claimListing describes how claims can be listed.
+var claimListing = listing[models.Claim]{
+	defaultSort: "created_at",
+	id:          claimID,
+	sorts: map[string]sortField[models.Claim]{
+		"id": claimID,
+		"created_at": {
+			column: "created_at", value: func(item models.Claim) any { return item.CreatedAt }, parse: parseTime,
+		},
+		"distance": {
+			column: "distance", value: func(item models.Claim) any { return item.Distance }, parse: parseFloat,
+		},
+	},
+	filters: map[string]filterField[models.Claim]{
+		"location_id": {
+			column: "location_id", value: func(item models.Claim) string { return strconv.Itoa(item.LocationID) },
+		},
+		"wallet": {column: "wallet", value: func(item models.Claim) string { return item.Wallet }},
+	},
+}
+
+// Claim models the DB operations available for claims.
+//
+// A unique index on the location and the wallet keeps a wallet from claiming a location twice, even from concurrent
+// requests.
+type Claim struct {
+	db *gorm.DB
+}
+
+// NewClaim builds a new Claim repository.
+//
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
+func NewClaim(dsn string, silent bool) (*Claim, error) {
+	result := &Claim{}
+
+	cfg := &gorm.Config{TranslateError: true}
+	if silent {
+		cfg.Logger = NewNoopLogger()
+	}
+
+	var err error
+
+	result.db, err = gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Claim DB: %w", err)
+	}
+
+	return result, nil
+}
+
+// NewClaimTruncate builds a new Claim repo, and deletes its previous contents.
+//
+// Meant to be used in tests.
+func NewClaimTruncate(dsn string, silent bool) (*Claim, error) {
+	result, err := NewClaim(dsn, silent)
+
+	if err == nil {
+		success := result.db.Exec("TRUNCATE TABLE claims;")
+		if success.Error != nil {
+			return nil, fmt.Errorf("could not truncate: %w", success.Error)
+		}
+	}
+
+	return result, err
+}
+
+// Create a new claim.
+func (c *Claim) Create(ctx context.Context, claim models.Claim) (models.Claim, error) {
+	claim.CreatedAt = time.Now().Truncate(time.Microsecond)
+
+	success := c.db.WithContext(ctx).Create(&claim)
+	if errors.Is(success.Error, gorm.ErrDuplicatedKey) {
+		return models.Claim{}, fmt.Errorf(
+			"could not create claim of location %d by %q: %w", claim.LocationID, claim.Wallet, ErrAlreadyExists)
+	}
+
+	if success.Error != nil {
+		return models.Claim{}, fmt.Errorf("could not create claim: %w", success.Error)
+	}
+
+	return claim, nil
+}
+
+// Find returns the claim of a location by a wallet.
+func (c *Claim) Find(ctx context.Context, locationID int, wallet string) (models.Claim, error) {
+	var result models.Claim
+
+	success := c.db.WithContext(ctx).First(&result, "location_id = ? AND wallet = ?", locationID, wallet)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.Claim{}, fmt.Errorf(
+			"could not find claim of location %d by %q: %w", locationID, wallet, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.Claim{}, fmt.Errorf("could not find claim: %w", success.Error)
+	}
+
+	return result, nil
+}
+
+// List returns a page of claims.
+func (c *Claim) List(ctx context.Context, opts types.ListOptions) (types.Page[models.Claim], error) {
+	query, err := claimListing.query(opts)
+	if err != nil {
+		return types.Page[models.Claim]{}, err
+	}
+
+	result, err := query.gormPage(ctx, c.db)
+	if err != nil {
+		return types.Page[models.Claim]{}, fmt.Errorf("could not list claims: %w", err)
+	}
+
+	return result, nil
+}
+
+// Delete the claim with a given ID.
+func (c *Claim) Delete(ctx context.Context, claimID string) error {
+	success := c.db.WithContext(ctx).Delete(&models.Claim{}, "id = ?", claimID)
+	if success.Error != nil {
+		return fmt.Errorf("could not delete claim: %w", success.Error)
+	}
+
+	if success.RowsAffected == 0 {
+		return fmt.Errorf("could not delete claim %q: %w", claimID, ErrDoesNotExist)
+	}
+
+	return nil
+}
+
+// Close closes the DB connection.
+func (c *Claim) Close(ctx context.Context) error {
+	database, err := c.db.WithContext(ctx).DB()
+	if err != nil {
+		return fmt.Errorf("could not get DB: %w", err)
+	}
+
+	err = database.Close()
+	if err != nil {
+		return fmt.Errorf("could not close DB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_Claims(t *testing.T) {
+	for name, build := range claimBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, claim := range []models.Claim{
+				{ID: "a", LocationID: 1, Wallet: "w1", Distance: 3},
+				{ID: "b", LocationID: 1, Wallet: "w2", Distance: 1},
+				{ID: "c", LocationID: 2, Wallet: "w1", Distance: 2},
+			} {
+				_, err = repo.Create(context.TODO(), claim)
+				assert.NoError(t, err)
+			}
+
+			// a wallet claims a location once
+			_, err = repo.Create(context.TODO(), models.Claim{ID: "d", LocationID: 1, Wallet: "w1"})
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+
+			found, err := repo.Find(context.TODO(), 2, "w1")
+			assert.NoError(t, err)
+			assert.Equal(t, "c", found.ID)
+
+			_, err = repo.Find(context.TODO(), 2, "w2")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			page, err := repo.List(context.TODO(), types.ListOptions{
+				SortBy: "distance", Filters: map[string]string{"location_id": "1"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			assert.Equal(t, "b", page.Items[0].ID)
+			assert.Equal(t, "a", page.Items[1].ID)
+
+			page, err = repo.List(context.TODO(), types.ListOptions{Filters: map[string]string{"wallet": "w1"}, Limit: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			assert.NotEmpty(t, page.NextCursor)
+
+			// once deleted, the location can be claimed again
+			assert.NoError(t, repo.Delete(context.TODO(), "a"))
+			assert.ErrorIs(t, repo.Delete(context.TODO(), "a"), ErrDoesNotExist)
+
+			_, err = repo.Create(context.TODO(), models.Claim{ID: "d", LocationID: 1, Wallet: "w1"})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_FileClaimReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claims.log")
+
+	repo, err := NewFileClaim(path)
+	assert.NoError(t, err)
+
+	_, err = repo.Create(context.TODO(), models.Claim{ID: "a", LocationID: 1, Wallet: "w1", JobID: "job"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	repo, err = NewFileClaim(path)
+	assert.NoError(t, err)
+
+	defer repo.Close(context.TODO())
+
+	found, err := repo.Find(context.TODO(), 1, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, "job", found.JobID)
+
+	_, err = repo.Create(context.TODO(), models.Claim{ID: "b", LocationID: 1, Wallet: "w1"})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+}
+
+// claimBackends returns builders for every ClaimStore implementation that can be tested in this environment.
+func claimBackends(t *testing.T) map[string]func() (ClaimStore, error) {
+	t.Helper()
+
+	result := map[string]func() (ClaimStore, error){
+		"memory": func() (ClaimStore, error) {
+			return NewMemoryClaim(), nil
+		},
+		"file": func() (ClaimStore, error) {
+			return NewFileClaim(filepath.Join(t.TempDir(), "claims.log"))
+		},
+	}
+
+	if dsn := os.Getenv(testDSNVariable); dsn != "" {
+		result["postgres"] = func() (ClaimStore, error) {
+			err := migrateTestDB(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewClaimTruncate(dsn, true)
+		}
+	}
+
+	return result
+}
@@ -11,28 +11,78 @@ import (
 	"time"
 
 	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
 	// ErrDoesNotExist for when we try to update/delete a non existing search.
 	ErrDoesNotExist = errors.New("item does not exit")
+	// ErrAlreadyExists for when we try to create an item with an ID that is already taken.
+	ErrAlreadyExists = errors.New("item already exists")
+	// ErrVersionMismatch for when we try to update/delete an item that is no longer at the expected version.
+	ErrVersionMismatch = errors.New("item version mismatch")
 )
 
+// dataID is the unique field of data items.
+//
+// IDs are compared byte by byte (the "C" collation), like Go strings, so that all repositories order them the same
+// way, and so that key ranges can use the idx_data_namespace_id_c index.
+var dataID = sortField[models.Data]{
+	column: `id COLLATE "C"`, value: func(item models.Data) any { return item.ID }, parse: parseString,
+}
+
+// dataListing describes how data items can be listed.
+var dataListing = listing[models.Data]{
+	defaultSort: "id",
+	id:          dataID,
+	keys:        &dataID,
+	sorts: map[string]sortField[models.Data]{
+		"id": dataID,
+		"created_at": {
+			column: "created_at", value: func(item models.Data) any { return item.CreatedAt }, parse: parseTime,
+		},
+		"updated_at": {
+			column: "updated_at", value: func(item models.Data) any { return item.UpdatedAt }, parse: parseTime,
+		},
+	},
+	filters: map[string]filterField[models.Data]{
+		"value": {column: "value", value: func(item models.Data) string { return item.Value }},
+	},
+	access: &dataAccess,
+}
+
+// dataAccess describes the owner and the grants of data items.
+var dataAccess = accessField[models.Data]{
+	ownerColumn:  "owner",
+	grantsColumn: "grants",
+	owner:        func(item models.Data) string { return item.Owner },
+	grants:       func(item models.Data) models.Grants { return item.Grants },
+}
+
+// notExpired is the condition that keeps expired data items out of queries.
+const notExpired = "(expires_at IS NULL OR expires_at > ?)"
+
 // Store models the DB operations available for search items.
 type Store struct {
 	db    *gorm.DB
 	mutex sync.RWMutex
+	// namespace is the one the items are read from and written to.
+	namespace string
+	// isView is true for the views handed out by Transaction and In, which must not close the DB.
+	isView bool
 }
 
 // New builds a new import repository.
 //
-// When silent is true, it will use a custom logger that does not output anything to the console.
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
 func New(dsn string, silent bool) (*Store, error) {
-	result := &Store{}
+	result := &Store{namespace: types.DefaultNamespace}
 
-	cfg := &gorm.Config{}
+	cfg := &gorm.Config{TranslateError: true}
 	if silent {
 		cfg.Logger = NewNoopLogger()
 	}
@@ -44,11 +94,6 @@ func New(dsn string, silent bool) (*Store, error) {
 		return nil, fmt.Errorf("could not open Import DB: %w", err)
 	}
 
-	err = result.db.AutoMigrate(&models.Data{})
-	if err != nil {
-		return nil, fmt.Errorf("could not auto migrate models.Data: %w", err)
-	}
-
 	return result, nil
 }
 
@@ -59,7 +104,7 @@ func NewTruncate(dsn string, silent bool) (*Store, error) {
 	result, err := New(dsn, silent)
 
 	if err == nil {
-		success := result.db.Exec("TRUNCATE TABLE data;")
+		success := result.db.Exec("TRUNCATE TABLE data, data_versions;")
 		if success.Error != nil {
 			return nil, fmt.Errorf("could not truncate: %w", err)
 		}
@@ -75,7 +120,7 @@ func (c *Store) GetAll(ctx context.Context) ([]models.Data, error) {
 
 	var result []models.Data
 
-	err := c.db.WithContext(ctx).Find(&result).Error
+	err := c.db.WithContext(ctx).Where(notExpired, time.Now()).Find(&result, "namespace = ?", c.namespace).Error
 	if err != nil {
 		return nil, fmt.Errorf("could not get all import items: %w", err)
 	}
@@ -83,48 +128,127 @@ func (c *Store) GetAll(ctx context.Context) ([]models.Data, error) {
 	return result, nil
 }
 
+// List returns a page of the data items that were not deleted.
+func (c *Store) List(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
+	query, err := dataListing.query(opts)
+	if err != nil {
+		return types.Page[models.Data]{}, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result, err := query.gormPage(ctx, c.db.Where("namespace = ?", c.namespace).Where(notExpired, time.Now()))
+	if err != nil {
+		return types.Page[models.Data]{}, fmt.Errorf("could not list data items: %w", err)
+	}
+
+	return result, nil
+}
+
 // Create a new data item.
 //
-// Sets the CreatedAt and UpdatedAt fields.
+// Sets the CreatedAt, UpdatedAt and Version fields, and records the first revision. A deleted or expired item with
+// the same ID, not purged yet, is replaced; revisions keep counting from the ones it left behind.
 func (c *Store) Create(ctx context.Context, item models.Data) (models.Data, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	item.Namespace = c.namespace
 	item.CreatedAt = time.Now()
 	item.UpdatedAt = item.CreatedAt
 
-	success := c.db.WithContext(ctx).Create(&item)
-	if success.Error != nil {
-		return models.Data{}, fmt.Errorf("could not create data item: %w", success.Error)
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		success := tx.Unscoped().
+			Where("namespace = ? AND id = ?", item.Namespace, item.ID).
+			Where("deleted_at IS NOT NULL OR expires_at <= ?", item.CreatedAt).
+			Delete(&models.Data{})
+		if success.Error != nil {
+			return success.Error
+		}
+
+		success = tx.Model(&models.DataVersion{}).
+			Where("namespace = ? AND id = ?", item.Namespace, item.ID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&item.Version)
+		if success.Error != nil {
+			return success.Error
+		}
+
+		item.Version++
+
+		success = tx.Create(&item)
+		if success.Error != nil {
+			return success.Error
+		}
+
+		revision := item.Revision()
+
+		return tx.Create(&revision).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return models.Data{}, fmt.Errorf("could not create data item %q: %w", item.ID, ErrAlreadyExists)
+	}
+
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not create data item: %w", err)
 	}
 
 	return item, nil
 }
 
-// Update a given data item.
-func (c *Store) Update(ctx context.Context, item models.Data) error {
+// Update a given data item, and returns it as stored.
+//
+// When item.Version is not zero, the item is only updated if it is still at that version; otherwise it returns
+// ErrVersionMismatch. The row is locked while checking, so this holds across processes sharing the DB.
+//
+// Bumps the Version field and records the new revision.
+func (c *Store) Update(ctx context.Context, item models.Data) (models.Data, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	if item.ID == "" {
-		return ErrDoesNotExist
+		return models.Data{}, ErrDoesNotExist
 	}
 
-	var result models.Data
+	item.Namespace = c.namespace
 
-	success := c.db.WithContext(ctx).First(&result, "id = ?", item.ID)
-	if success.Error != nil {
-		return ErrDoesNotExist
-	}
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var result models.Data
 
-	item.UpdatedAt = time.Now()
+		success := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(notExpired, time.Now()).
+			First(&result, "namespace = ? AND id = ?", item.Namespace, item.ID)
+		if success.Error != nil {
+			return ErrDoesNotExist
+		}
 
-	success = c.db.WithContext(ctx).Save(item)
-	if success.Error != nil {
-		return fmt.Errorf("could not update data item: %w", success.Error)
+		if item.Version != 0 && item.Version != result.Version {
+			return ErrVersionMismatch
+		}
+
+		item.CreatedAt = result.CreatedAt
+		item.UpdatedAt = time.Now()
+		item.Version = result.Version + 1
+
+		success = tx.Save(item)
+		if success.Error != nil {
+			return success.Error
+		}
+
+		revision := item.Revision()
+
+		return tx.Create(&revision).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) || errors.Is(err, ErrVersionMismatch) {
+		return models.Data{}, err
 	}
 
-	return nil
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not update data item: %w", err)
+	}
+
+	return item, nil
 }
 
 // ByID returns the data item with a given ID.
@@ -134,7 +258,12 @@ func (c *Store) ByID(ctx context.Context, itemID string) (models.Data, error) {
 
 	var result models.Data
 
-	success := c.db.WithContext(ctx).First(&result, "id = ?", itemID)
+	success := c.db.WithContext(ctx).
+		Where(notExpired, time.Now()).
+		First(&result, "namespace = ? AND id = ?", c.namespace, itemID)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.Data{}, fmt.Errorf("could not find data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
 
 	if success.Error != nil {
 		return models.Data{}, fmt.Errorf("could not find data item with ID %q: %w", itemID, success.Error)
@@ -145,8 +274,9 @@ func (c *Store) ByID(ctx context.Context, itemID string) (models.Data, error) {
 
 // Delete a given data item.
 //
-//nolint:dupls
-func (c *Store) Delete(ctx context.Context, dataID string) error {
+// When version is not zero, the item is only deleted if it is still at that version; otherwise it returns
+// ErrVersionMismatch. Bumps the Version field and records a revision marked as deleted.
+func (c *Store) Delete(ctx context.Context, dataID string, version int64) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -154,26 +284,135 @@ func (c *Store) Delete(ctx context.Context, dataID string) error {
 		return ErrDoesNotExist
 	}
 
-	var result models.Data
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var result models.Data
 
-	success := c.db.WithContext(ctx).First(&result, "id = ?", dataID)
-	if success.Error != nil {
-		return ErrDoesNotExist
+		success := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(notExpired, time.Now()).
+			First(&result, "namespace = ? AND id = ?", c.namespace, dataID)
+		if success.Error != nil {
+			return ErrDoesNotExist
+		}
+
+		if version != 0 && version != result.Version {
+			return ErrVersionMismatch
+		}
+
+		result.Version++
+		result.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+		success = tx.Model(&result).UpdateColumns(map[string]any{
+			"deleted_at": result.DeletedAt,
+			"version":    result.Version,
+		})
+		if success.Error != nil {
+			return success.Error
+		}
+
+		revision := result.Revision()
+
+		return tx.Create(&revision).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) || errors.Is(err, ErrVersionMismatch) {
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not delete data item %q: %w", dataID, err)
 	}
 
-	success = c.db.WithContext(ctx).Delete(&result)
+	return nil
+}
+
+// PurgeExpired deletes for good (at most limit) data items that expired before a given moment, in every namespace.
+//
+// Returns how many were deleted.
+func (c *Store) PurgeExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	success := c.db.WithContext(ctx).Exec(
+		"DELETE FROM data WHERE (namespace, id) IN (SELECT namespace, id FROM data WHERE expires_at <= ? LIMIT ?);",
+		before, limit,
+	)
 	if success.Error != nil {
-		return fmt.Errorf("could not delete data item %q: %w", dataID, success.Error)
+		return 0, fmt.Errorf("could not purge expired data items: %w", success.Error)
+	}
+
+	return success.RowsAffected, nil
+}
+
+// Transaction runs fn against a view of the repository bound to a single DB transaction, which is committed only
+// when fn returns nil.
+//
+// Row locks taken by the writes of fn are held until the transaction ends, so other processes sharing the DB cannot
+// interleave with it. Nested transactions use savepoints.
+func (c *Store) Transaction(ctx context.Context, fn func(tx DataStore) error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var fnErr error
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fnErr = fn(&Store{db: tx, namespace: c.namespace, isView: true})
+
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-// Close closes the DB connection.
+// In returns a view of the repository working in a given namespace.
+func (c *Store) In(namespace string) DataStore {
+	return &Store{db: c.db, namespace: namespace, isView: true}
+}
+
+// Usage returns the number of data items that were not deleted and did not expire, and the size of their IDs and
+// values.
+//
+// Inside a transaction, it takes a lock on the namespace, held until the transaction ends, so that the other
+// transactions that call it wait for this one to write.
+func (c *Store) Usage(ctx context.Context) (types.Usage, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// outside a transaction, the lock is released at once
+	success := c.db.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?));", "data:"+c.namespace)
+	if success.Error != nil {
+		return types.Usage{}, fmt.Errorf("could not lock namespace %q: %w", c.namespace, success.Error)
+	}
+
+	var result types.Usage
+
+	success = c.db.WithContext(ctx).
+		Model(&models.Data{}).
+		Select("COUNT(*) AS keys, COALESCE(SUM(octet_length(id) + octet_length(value)), 0) AS bytes").
+		Where("namespace = ?", c.namespace).
+		Where(notExpired, time.Now()).
+		Scan(&result)
+	if success.Error != nil {
+		return types.Usage{}, fmt.Errorf("could not measure namespace %q: %w", c.namespace, success.Error)
+	}
+
+	return result, nil
+}
+
+// Close closes the DB connection. It does nothing for the views handed out by Transaction and In.
 func (c *Store) Close(ctx context.Context) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.isView {
+		return nil
+	}
+
 	database, err := c.db.WithContext(ctx).DB()
 	if err != nil {
 		return fmt.Errorf("could not get DB: %w", err)
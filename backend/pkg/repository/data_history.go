@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/gorm"
+)
+
+// revisionVersion is the unique field of the revisions of a data item.
+var revisionVersion = sortField[models.DataVersion]{
+	column: "version", value: func(item models.DataVersion) any { return item.Version }, parse: parseInt64,
+}
+
+// revisionListing describes how the revisions of a data item can be listed.
+var revisionListing = listing[models.DataVersion]{
+	defaultSort: "version",
+	id:          revisionVersion,
+	sorts: map[string]sortField[models.DataVersion]{
+		"version": revisionVersion,
+	},
+}
+
+// History returns a page of the revisions of a data item, including the ones written by deletes.
+func (c *Store) History(
+	ctx context.Context,
+	itemID string,
+	opts types.ListOptions,
+) (types.Page[models.DataVersion], error) {
+	query, err := revisionListing.query(opts)
+	if err != nil {
+		return types.Page[models.DataVersion]{}, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result, err := query.gormPage(ctx, c.db.Where("namespace = ? AND id = ?", c.namespace, itemID))
+	if err != nil {
+		return types.Page[models.DataVersion]{}, fmt.Errorf("could not list revisions of %q: %w", itemID, err)
+	}
+
+	return result, nil
+}
+
+// Revision returns a given revision of a data item.
+func (c *Store) Revision(ctx context.Context, itemID string, version int64) (models.DataVersion, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var result models.DataVersion
+
+	success := c.db.WithContext(ctx).
+		First(&result, "namespace = ? AND id = ? AND version = ?", c.namespace, itemID, version)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.DataVersion{}, fmt.Errorf("could not find revision %d of %q: %w", version, itemID, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.DataVersion{}, fmt.Errorf("could not find revision %d of %q: %w", version, itemID, success.Error)
+	}
+
+	return result, nil
+}
+
+// RevisionAt returns the revision of a data item that was the latest one at a given moment.
+func (c *Store) RevisionAt(ctx context.Context, itemID string, at time.Time) (models.DataVersion, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var result models.DataVersion
+
+	success := c.db.WithContext(ctx).
+		Where("namespace = ? AND id = ? AND created_at <= ?", c.namespace, itemID, at).
+		Order("version DESC").
+		First(&result)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.DataVersion{}, fmt.Errorf("could not find a revision of %q at %s: %w", itemID, at, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.DataVersion{}, fmt.Errorf("could not find a revision of %q at %s: %w", itemID, at, success.Error)
+	}
+
+	return result, nil
+}
+
+// PruneHistory deletes (at most limit) revisions that are not among the newest keep ones of their data item (when
+// keep is positive), or that were written before a given moment, in every namespace.
+//
+// The latest revision of a data item is always kept. Returns how many were deleted.
+func (c *Store) PruneHistory(ctx context.Context, keep int, before time.Time, limit int) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	success := c.db.WithContext(ctx).Exec(`
+		DELETE FROM data_versions WHERE (namespace, id, version) IN (
+			SELECT namespace, id, version FROM (
+				SELECT namespace, id, version, created_at,
+					ROW_NUMBER() OVER (PARTITION BY namespace, id ORDER BY version DESC) AS rank
+				FROM data_versions
+			) AS ranked
+			WHERE rank > 1 AND ((? > 0 AND rank > ?) OR created_at < ?)
+			LIMIT ?
+		);`,
+		keep, keep, before, limit,
+	)
+	if success.Error != nil {
+		return 0, fmt.Errorf("could not prune revisions: %w", success.Error)
+	}
+
+	return success.RowsAffected, nil
+}
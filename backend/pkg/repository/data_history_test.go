@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_History(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			created, err := repo.Create(context.TODO(), models.Data{ID: "key", Value: "v1"})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1, created.Version)
+
+			_, err = repo.Update(context.TODO(), models.Data{ID: "key", Value: "v2"})
+			assert.NoError(t, err)
+
+			between := time.Now()
+
+			err = repo.Delete(context.TODO(), "key", 0)
+			assert.NoError(t, err)
+
+			history, err := repo.History(context.TODO(), "key", types.ListOptions{Descending: true})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 3, history.Total)
+			assert.EqualValues(t, 3, history.Items[0].Version)
+			assert.True(t, history.Items[0].Deleted)
+
+			revision, err := repo.Revision(context.TODO(), "key", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", revision.Value)
+
+			revision, err = repo.RevisionAt(context.TODO(), "key", between)
+			assert.NoError(t, err)
+			assert.Equal(t, "v2", revision.Value)
+
+			_, err = repo.Revision(context.TODO(), "key", 4)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			pruned, err := repo.PruneHistory(context.TODO(), 1, time.Time{}, 10)
+			assert.NoError(t, err)
+			assert.EqualValues(t, 2, pruned)
+
+			history, err = repo.History(context.TODO(), "key", types.ListOptions{})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1, history.Total)
+			assert.EqualValues(t, 3, history.Items[0].Version)
+		})
+	}
+}
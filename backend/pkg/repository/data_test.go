@@ -2,84 +2,531 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/migrations"
 	"github.com/wakka-2/Namless/backend/pkg/models"
 	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
+// testDSNVariable names the environment variable holding the DSN of the postgres DB used in tests.
+//
+// When it is not set, only the in-memory backends are tested.
+const testDSNVariable = "NAMLESS_TEST_DSN"
+
 func Test_Create(t *testing.T) {
-	repo, err := buildRepo(true)
-	assert.NoError(t, err)
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
 
-	defer func() {
-		err := repo.Close(context.TODO())
-		assert.NoError(t, err)
-	}()
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
 
-	Import := models.Data{
-		ID: "BBB171C4-00E8-4B0F-97EB-2F3EC3394A87",
-	}
+			Import := models.Data{
+				ID: "BBB171C4-00E8-4B0F-97EB-2F3EC3394A87",
+			}
+
+			got, err := repo.Create(context.TODO(), Import)
+			assert.NoError(t, err)
+			assert.Equal(t, "BBB171C4-00E8-4B0F-97EB-2F3EC3394A87", got.ID)
 
-	got, err := repo.Create(context.TODO(), Import)
-	assert.NoError(t, err)
-	assert.Equal(t, "BBB171C4-00E8-4B0F-97EB-2F3EC3394A87", got.ID)
+			_, err = repo.Create(context.TODO(), Import)
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+		})
+	}
 }
 
 func Test_Update(t *testing.T) {
-	repo, err := buildRepo(false)
-	assert.NoError(t, err)
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(false)
+			assert.NoError(t, err)
 
-	defer func() {
-		err := repo.Close(context.TODO())
-		assert.NoError(t, err)
-	}()
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
 
-	Import := models.Data{
-		ID: "AAA-00E8-4B0F-97EB-2F3EC3394A87",
-	}
+			Import := models.Data{
+				ID: "AAA-00E8-4B0F-97EB-2F3EC3394A87",
+			}
 
-	created, err := repo.Create(context.TODO(), Import)
-	assert.NoError(t, err)
-	assert.Equal(t, "AAA-00E8-4B0F-97EB-2F3EC3394A87", created.ID)
+			created, err := repo.Create(context.TODO(), Import)
+			assert.NoError(t, err)
+			assert.Equal(t, "AAA-00E8-4B0F-97EB-2F3EC3394A87", created.ID)
 
-	err = repo.Update(context.TODO(), created)
-	assert.NoError(t, err)
+			_, err = repo.Update(context.TODO(), models.Data{ID: created.ID, Value: "updated"})
+			assert.NoError(t, err)
 
-	updated, err := repo.ByID(context.TODO(), "AAA-00E8-4B0F-97EB-2F3EC3394A87")
-	assert.NoError(t, err)
-	assert.Equal(t, "AAA-00E8-4B0F-97EB-2F3EC3394A87", updated.ID)
+			updated, err := repo.ByID(context.TODO(), "AAA-00E8-4B0F-97EB-2F3EC3394A87")
+			assert.NoError(t, err)
+			assert.Equal(t, "AAA-00E8-4B0F-97EB-2F3EC3394A87", updated.ID)
+			assert.Equal(t, "updated", updated.Value)
+			assert.True(t, created.CreatedAt.Equal(updated.CreatedAt))
+
+			_, err = repo.Update(context.TODO(), models.Data{ID: "missing"})
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+		})
+	}
 }
 
 func Test_Delete(t *testing.T) {
-	repo, err := buildRepo(true)
-	assert.NoError(t, err)
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			Import := models.Data{
+				ID: "BBB-00E8-4B0F-97EB-2F3EC3394A87",
+			}
+
+			created, err := repo.Create(context.TODO(), Import)
+			assert.NoError(t, err)
+			assert.Equal(t, "BBB-00E8-4B0F-97EB-2F3EC3394A87", created.ID)
+
+			found, err := repo.ByID(context.TODO(), created.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, created.ID, found.ID)
+
+			err = repo.Delete(context.TODO(), found.ID, 0)
+			assert.NoError(t, err)
+
+			_, err = repo.ByID(context.TODO(), created.ID)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			err = repo.Delete(context.TODO(), found.ID, 0)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			all, err := repo.GetAll(context.TODO())
+			assert.NoError(t, err)
+			assert.Empty(t, all)
+		})
+	}
+}
+
+func Test_CompareAndSwap(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			created, err := repo.Create(context.TODO(), models.Data{ID: "cas", Value: "v1"})
+			assert.NoError(t, err)
+
+			updated, err := repo.Update(context.TODO(), models.Data{ID: "cas", Value: "v2", Version: created.Version})
+			assert.NoError(t, err)
+			assert.Greater(t, updated.Version, created.Version)
+			assert.Equal(t, "v2", updated.Value)
+
+			_, err = repo.Update(context.TODO(), models.Data{ID: "cas", Value: "v3", Version: created.Version})
+			assert.ErrorIs(t, err, ErrVersionMismatch)
+
+			err = repo.Delete(context.TODO(), "cas", created.Version)
+			assert.ErrorIs(t, err, ErrVersionMismatch)
+
+			found, err := repo.ByID(context.TODO(), "cas")
+			assert.NoError(t, err)
+			assert.Equal(t, "v2", found.Value)
+			assert.Equal(t, updated.Version, found.Version)
+
+			err = repo.Delete(context.TODO(), "cas", updated.Version)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_Transaction(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			_, err = repo.Create(context.TODO(), models.Data{ID: "kept", Value: "v1"})
+			assert.NoError(t, err)
+
+			errAborted := errors.New("aborted")
+
+			err = repo.Transaction(context.TODO(), func(tx DataStore) error {
+				_, err := tx.Create(context.TODO(), models.Data{ID: "rolled-back", Value: "v1"})
+				assert.NoError(t, err)
+
+				_, err = tx.Update(context.TODO(), models.Data{ID: "kept", Value: "v2"})
+				assert.NoError(t, err)
+
+				// the transaction sees its own writes
+				found, err := tx.ByID(context.TODO(), "kept")
+				assert.NoError(t, err)
+				assert.Equal(t, "v2", found.Value)
+
+				return errAborted
+			})
+			assert.ErrorIs(t, err, errAborted)
+
+			_, err = repo.ByID(context.TODO(), "rolled-back")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			found, err := repo.ByID(context.TODO(), "kept")
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", found.Value)
+			assert.Equal(t, int64(1), found.Version)
+
+			history, err := repo.History(context.TODO(), "kept", types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), history.Total)
+
+			err = repo.Transaction(context.TODO(), func(tx DataStore) error {
+				_, err := tx.Create(context.TODO(), models.Data{ID: "committed", Value: "v1"})
+				if err != nil {
+					return err
+				}
+
+				return tx.Delete(context.TODO(), "kept", found.Version)
+			})
+			assert.NoError(t, err)
+
+			_, err = repo.ByID(context.TODO(), "committed")
+			assert.NoError(t, err)
+
+			_, err = repo.ByID(context.TODO(), "kept")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+		})
+	}
+}
+
+func Test_List(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, key := range []string{"e", "a", "d", "b", "c"} {
+				value := "odd"
+				if key == "b" || key == "d" {
+					value = "even"
+				}
+
+				_, err = repo.Create(context.TODO(), models.Data{ID: key, Value: value})
+				assert.NoError(t, err)
+			}
+
+			first, err := repo.List(context.TODO(), types.ListOptions{Limit: 2})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a", "b"}, dataIDs(first.Items))
+			assert.EqualValues(t, 5, first.Total)
+			assert.NotEmpty(t, first.NextCursor)
+
+			second, err := repo.List(context.TODO(), types.ListOptions{Limit: 2, Cursor: first.NextCursor})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"c", "d"}, dataIDs(second.Items))
+
+			last, err := repo.List(context.TODO(), types.ListOptions{Limit: 2, Cursor: second.NextCursor})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"e"}, dataIDs(last.Items))
+			assert.Empty(t, last.NextCursor)
+
+			even, err := repo.List(context.TODO(), types.ListOptions{
+				Descending: true,
+				Filters:    map[string]string{"value": "even"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"d", "b"}, dataIDs(even.Items))
+			assert.EqualValues(t, 2, even.Total)
+
+			_, err = repo.List(context.TODO(), types.ListOptions{Cursor: first.NextCursor, Descending: true})
+			assert.ErrorIs(t, err, types.ErrInvalidCursor)
+
+			_, err = repo.List(context.TODO(), types.ListOptions{SortBy: "password"})
+			assert.ErrorIs(t, err, types.ErrInvalidSort)
+		})
+	}
+}
+
+func Test_ListRange(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, key := range []string{"user/42/settings", "user/42/avatar", "user/420", "user/43/settings", "users"} {
+				_, err = repo.Create(context.TODO(), models.Data{ID: key})
+				assert.NoError(t, err)
+			}
+
+			prefixed, err := repo.List(context.TODO(), types.ListOptions{Range: types.KeyRange{Prefix: "user/42/"}})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"user/42/avatar", "user/42/settings"}, dataIDs(prefixed.Items))
+			assert.EqualValues(t, 2, prefixed.Total)
+
+			ranged, err := repo.List(context.TODO(), types.ListOptions{
+				Limit: 1,
+				Range: types.KeyRange{Start: "user/420", End: "users"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"user/420"}, dataIDs(ranged.Items))
+
+			ranged, err = repo.List(context.TODO(), types.ListOptions{
+				Limit:  1,
+				Cursor: ranged.NextCursor,
+				Range:  types.KeyRange{Start: "user/420", End: "users"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"user/43/settings"}, dataIDs(ranged.Items))
+			assert.Empty(t, ranged.NextCursor)
+
+			_, err = repo.List(context.TODO(), types.ListOptions{Range: types.KeyRange{Start: "b", End: "a"}})
+			assert.ErrorIs(t, err, types.ErrInvalidRange)
+
+			// prefixes are bounded rune by rune, so that the bounds are valid UTF-8
+			for _, key := range []string{"ο/a", "οδός", "π", "\U0010ffff/a"} {
+				_, err = repo.Create(context.TODO(), models.Data{ID: key})
+				assert.NoError(t, err)
+			}
+
+			prefixed, err = repo.List(context.TODO(), types.ListOptions{Range: types.KeyRange{Prefix: "ο"}})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"ο/a", "οδός"}, dataIDs(prefixed.Items))
 
-	defer func() {
-		err := repo.Close(context.TODO())
-		assert.NoError(t, err)
-	}()
+			prefixed, err = repo.List(context.TODO(), types.ListOptions{Range: types.KeyRange{Prefix: "\U0010ffff"}})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"\U0010ffff/a"}, dataIDs(prefixed.Items))
 
-	Import := models.Data{
-		ID: "BBB-00E8-4B0F-97EB-2F3EC3394A87",
+			_, err = repo.List(context.TODO(), types.ListOptions{Range: types.KeyRange{Prefix: "\xce"}})
+			assert.ErrorIs(t, err, types.ErrInvalidRange)
+		})
 	}
+}
+
+func Test_PrefixEnd(t *testing.T) {
+	assert.Equal(t, "user/43", prefixEnd("user/42"))
+	assert.Equal(t, "π", prefixEnd("ο"))
+	assert.Equal(t, "b", prefixEnd("a\U0010ffff"))
+	assert.Equal(t, "\ue000", prefixEnd("\ud7ff"))
+	assert.Empty(t, prefixEnd("\U0010ffff"))
+	assert.Empty(t, prefixEnd(""))
+}
+
+func Test_ListViewer(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
 
-	created, err := repo.Create(context.TODO(), Import)
-	assert.NoError(t, err)
-	assert.Equal(t, "BBB-00E8-4B0F-97EB-2F3EC3394A87", created.ID)
+			for _, item := range []models.Data{
+				{ID: "open"},
+				{ID: "mine", Owner: "alice"},
+				{ID: "shared", Owner: "bob", Grants: models.Grants{"alice": models.AccessRead}},
+				{ID: "private", Owner: "bob", Grants: models.Grants{"carol": models.AccessWrite}},
+			} {
+				_, err = repo.Create(context.TODO(), item)
+				assert.NoError(t, err)
+			}
 
-	found, err := repo.ByID(context.TODO(), created.ID)
-	assert.NoError(t, err)
-	assert.Equal(t, created.ID, found.ID)
+			visible, err := repo.List(context.TODO(), types.ListOptions{Viewer: "alice"})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"mine", "open", "shared"}, dataIDs(visible.Items))
+			assert.EqualValues(t, 3, visible.Total)
 
-	err = repo.Delete(context.TODO(), found.ID)
-	assert.NoError(t, err)
+			stored, err := repo.ByID(context.TODO(), "shared")
+			assert.NoError(t, err)
+			assert.Equal(t, models.Grants{"alice": models.AccessRead}, stored.Grants)
 
-	found, err = repo.ByID(context.TODO(), created.ID)
-	assert.Error(t, err)
+			err = repo.Delete(context.TODO(), "mine", 0)
+			assert.NoError(t, err)
+
+			trash, err := repo.Trash(context.TODO(), types.ListOptions{Viewer: "carol"})
+			assert.NoError(t, err)
+			assert.Empty(t, trash.Items)
+		})
+	}
+}
+
+func Test_Expiry(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			past := time.Now().Add(-time.Minute)
+			future := time.Now().Add(time.Hour)
+
+			_, err = repo.Create(context.TODO(), models.Data{ID: "expired", ExpiresAt: &past})
+			assert.NoError(t, err)
+
+			_, err = repo.Create(context.TODO(), models.Data{ID: "alive", ExpiresAt: &future})
+			assert.NoError(t, err)
+
+			_, err = repo.ByID(context.TODO(), "expired")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			_, err = repo.Update(context.TODO(), models.Data{ID: "expired"})
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			all, err := repo.List(context.TODO(), types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"alive"}, dataIDs(all.Items))
+
+			purged, err := repo.PurgeExpired(context.TODO(), time.Now(), 10)
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1, purged)
+
+			_, err = repo.Create(context.TODO(), models.Data{ID: "reused", ExpiresAt: &past})
+			assert.NoError(t, err)
+
+			// an expired item not purged yet does not keep its ID taken
+			_, err = repo.Create(context.TODO(), models.Data{ID: "reused", Value: "new"})
+			assert.NoError(t, err)
+
+			found, err := repo.ByID(context.TODO(), "reused")
+			assert.NoError(t, err)
+			assert.Equal(t, "new", found.Value)
+		})
+	}
+}
+
+func Test_Namespaces(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			other := repo.In("team-b")
+
+			_, err = repo.Create(context.TODO(), models.Data{ID: "key", Value: "default"})
+			assert.NoError(t, err)
+
+			created, err := other.Create(context.TODO(), models.Data{ID: "key", Value: "team-b"})
+			assert.NoError(t, err, "IDs should only be unique within a namespace")
+			assert.Equal(t, "team-b", created.Namespace)
+			assert.EqualValues(t, 1, created.Version)
+
+			_, err = other.Create(context.TODO(), models.Data{ID: "only-b", Value: "b"})
+			assert.NoError(t, err)
+
+			found, err := repo.ByID(context.TODO(), "key")
+			assert.NoError(t, err)
+			assert.Equal(t, "default", found.Value)
+			assert.Equal(t, types.DefaultNamespace, found.Namespace)
+
+			_, err = repo.ByID(context.TODO(), "only-b")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			page, err := other.List(context.TODO(), types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"key", "only-b"}, dataIDs(page.Items))
+
+			usage, err := other.Usage(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, types.Usage{Keys: 2, Bytes: int64(len("key") + len("team-b") + len("only-b") + len("b"))}, usage)
+
+			err = other.Transaction(context.TODO(), func(tx DataStore) error {
+				_, err := tx.Update(context.TODO(), models.Data{ID: "key", Value: "team-b, again"})
+
+				return err
+			})
+			assert.NoError(t, err)
+
+			history, err := other.History(context.TODO(), "key", types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, history.Items, 2)
+
+			history, err = repo.History(context.TODO(), "key", types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, history.Items, 1)
+
+			assert.NoError(t, other.Delete(context.TODO(), "key", 0))
+
+			trash, err := repo.Trash(context.TODO(), types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Empty(t, trash.Items)
+
+			assert.NoError(t, other.Purge(context.TODO(), "key"))
+
+			found, err = repo.ByID(context.TODO(), "key")
+			assert.NoError(t, err, "purging a namespace should leave the others alone")
+			assert.Equal(t, "default", found.Value)
+		})
+	}
+}
+
+func dataIDs(items []models.Data) []string {
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+
+	return result
+}
+
+// dataBackends returns builders for every DataStore implementation that can be tested in this environment.
+func dataBackends(t *testing.T) map[string]func(silent bool) (DataStore, error) {
+	t.Helper()
+
+	result := map[string]func(silent bool) (DataStore, error){
+		"memory": func(bool) (DataStore, error) {
+			return NewMemory(), nil
+		},
+		"file": func(bool) (DataStore, error) {
+			return NewFile(filepath.Join(t.TempDir(), "data.log"))
+		},
+	}
+
+	if os.Getenv(testDSNVariable) != "" {
+		result["postgres"] = func(silent bool) (DataStore, error) {
+			return buildRepo(silent)
+		}
+	}
+
+	return result
 }
 
 func buildRepo(silent bool) (*Store, error) {
@@ -93,8 +540,27 @@ func buildRepo(silent bool) (*Store, error) {
 		return nil, fmt.Errorf("could not make dir: %w", err)
 	}
 
-	return NewTruncate(
-		"user=postgres password=postgres dbname=test_nameles host=127.0.0.1 port=5432 sslmode=disable",
-		silent,
-	)
+	err = migrateTestDB(os.Getenv(testDSNVariable))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTruncate(os.Getenv(testDSNVariable), silent)
+}
+
+// migrateTestDB brings the schema of the test DB up to date.
+func migrateTestDB(dsn string) error {
+	runner, err := migrations.Open(dsn)
+	if err != nil {
+		return fmt.Errorf("could not open migrations: %w", err)
+	}
+
+	defer runner.Close()
+
+	_, err = runner.Up(context.TODO())
+	if err != nil {
+		return fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return nil
 }
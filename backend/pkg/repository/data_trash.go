@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// trashListing describes how deleted data items can be listed.
+var trashListing = listing[models.Data]{
+	defaultSort: "id",
+	id:          dataID,
+	keys:        &dataID,
+	sorts: map[string]sortField[models.Data]{
+		"id": dataID,
+		"deleted_at": {
+			column: "deleted_at", value: func(item models.Data) any { return item.DeletedAt.Time }, parse: parseTime,
+		},
+	},
+	filters: dataListing.filters,
+	access:  &dataAccess,
+}
+
+// Trash returns a page of the data items that were deleted.
+func (c *Store) Trash(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
+	query, err := trashListing.query(opts)
+	if err != nil {
+		return types.Page[models.Data]{}, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result, err := query.gormPage(ctx, c.db.Unscoped().
+		Where("namespace = ? AND deleted_at IS NOT NULL", c.namespace).
+		Where(notExpired, time.Now()))
+	if err != nil {
+		return types.Page[models.Data]{}, fmt.Errorf("could not list deleted data items: %w", err)
+	}
+
+	return result, nil
+}
+
+// TrashedByID returns the deleted data item with a given ID.
+func (c *Store) TrashedByID(ctx context.Context, itemID string) (models.Data, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var result models.Data
+
+	success := c.db.WithContext(ctx).Unscoped().
+		Where("namespace = ? AND id = ? AND deleted_at IS NOT NULL", c.namespace, itemID).
+		Where(notExpired, time.Now()).
+		First(&result)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.Data{}, fmt.Errorf("could not find deleted data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.Data{}, fmt.Errorf("could not find deleted data item with ID %q: %w", itemID, success.Error)
+	}
+
+	return result, nil
+}
+
+// Restore a deleted data item, and returns it as stored.
+//
+// Bumps the Version field and records the new revision.
+func (c *Store) Restore(ctx context.Context, itemID string) (models.Data, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var result models.Data
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		success := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").
+			Where(notExpired, time.Now()).
+			First(&result, "namespace = ? AND id = ?", c.namespace, itemID)
+		if success.Error != nil {
+			return ErrDoesNotExist
+		}
+
+		result.Version++
+		result.UpdatedAt = time.Now()
+		result.DeletedAt = gorm.DeletedAt{}
+
+		success = tx.Unscoped().Model(&result).UpdateColumns(map[string]any{
+			"deleted_at": nil,
+			"updated_at": result.UpdatedAt,
+			"version":    result.Version,
+		})
+		if success.Error != nil {
+			return success.Error
+		}
+
+		revision := result.Revision()
+
+		return tx.Create(&revision).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) {
+		return models.Data{}, fmt.Errorf("could not find deleted data item with ID %q: %w", itemID, err)
+	}
+
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not restore data item %q: %w", itemID, err)
+	}
+
+	return result, nil
+}
+
+// Purge deletes for good a data item, deleted or not, along with its revisions.
+func (c *Store) Purge(ctx context.Context, itemID string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		success := tx.Unscoped().Where("namespace = ? AND id = ?", c.namespace, itemID).Delete(&models.Data{})
+		if success.Error != nil {
+			return success.Error
+		}
+
+		if success.RowsAffected == 0 {
+			return ErrDoesNotExist
+		}
+
+		return tx.Where("namespace = ? AND id = ?", c.namespace, itemID).Delete(&models.DataVersion{}).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) {
+		return fmt.Errorf("could not find data item with ID %q: %w", itemID, err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not purge data item %q: %w", itemID, err)
+	}
+
+	return nil
+}
+
+// PurgeDeleted deletes for good (at most limit) data items that were deleted before a given moment, in every
+// namespace.
+//
+// Returns how many were deleted.
+func (c *Store) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	success := c.db.WithContext(ctx).Exec(
+		"DELETE FROM data WHERE (namespace, id) IN (SELECT namespace, id FROM data WHERE deleted_at <= ? LIMIT ?);",
+		before, limit,
+	)
+	if success.Error != nil {
+		return 0, fmt.Errorf("could not purge deleted data items: %w", success.Error)
+	}
+
+	return success.RowsAffected, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_Trash(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, key := range []string{"restored", "resurrected", "purged", "old"} {
+				_, err = repo.Create(context.TODO(), models.Data{ID: key, Value: "v1"})
+				assert.NoError(t, err)
+
+				err = repo.Delete(context.TODO(), key, 0)
+				assert.NoError(t, err)
+			}
+
+			trash, err := repo.Trash(context.TODO(), types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"old", "purged", "restored", "resurrected"}, dataIDs(trash.Items))
+
+			restored, err := repo.Restore(context.TODO(), "restored")
+			assert.NoError(t, err)
+			assert.EqualValues(t, 3, restored.Version)
+			assert.False(t, restored.DeletedAt.Valid)
+
+			found, err := repo.ByID(context.TODO(), "restored")
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", found.Value)
+
+			_, err = repo.Restore(context.TODO(), "restored")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			// creating a deleted key replaces it, and its versions keep counting
+			resurrected, err := repo.Create(context.TODO(), models.Data{ID: "resurrected", Value: "v2"})
+			assert.NoError(t, err)
+			assert.EqualValues(t, 3, resurrected.Version)
+
+			err = repo.Purge(context.TODO(), "purged")
+			assert.NoError(t, err)
+
+			err = repo.Purge(context.TODO(), "purged")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			history, err := repo.History(context.TODO(), "purged", types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Empty(t, history.Items)
+
+			purged, err := repo.PurgeDeleted(context.TODO(), time.Now(), 10)
+			assert.NoError(t, err)
+			assert.EqualValues(t, 1, purged)
+
+			trash, err = repo.Trash(context.TODO(), types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Empty(t, trash.Items)
+
+			_, err = repo.Restore(context.TODO(), "old")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+		})
+	}
+}
+
+func Test_TrashedByID(t *testing.T) {
+	for name, build := range dataBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build(true)
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, key := range []string{"deleted", "deleted/child", "live"} {
+				_, err = repo.Create(context.TODO(), models.Data{ID: key, Value: "v1"})
+				assert.NoError(t, err)
+			}
+
+			for _, key := range []string{"deleted", "deleted/child"} {
+				err = repo.Delete(context.TODO(), key, 0)
+				assert.NoError(t, err)
+			}
+
+			found, err := repo.TrashedByID(context.TODO(), "deleted")
+			assert.NoError(t, err)
+			assert.Equal(t, "deleted", found.ID)
+			assert.True(t, found.DeletedAt.Valid)
+
+			for _, key := range []string{"live", "missing", "delete", "deleted/"} {
+				_, err = repo.TrashedByID(context.TODO(), key)
+				assert.ErrorIs(t, err, ErrDoesNotExist, key)
+			}
+
+			_, err = repo.In("other").TrashedByID(context.TODO(), "deleted")
+			assert.ErrorIs(t, err, ErrDoesNotExist, "namespaces have their own trash")
+		})
+	}
+}
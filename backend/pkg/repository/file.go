@@ -0,0 +1,472 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// File models a DataStore kept in a single append-only file, so it needs no DB server.
+//
+// The whole state is indexed in memory; every write is appended to the file before it is applied, and the file is
+// replayed on start. When the file holds too many outdated records, it is compacted to the live state.
+type File struct {
+	*Memory
+	journal *journal
+}
+
+// NewFile builds a new file-backed data repository, recovering the state kept at path (if any).
+func NewFile(path string) (*File, error) {
+	result := &File{
+		Memory: NewMemory(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []dataChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal data changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change.inDefaultNamespace())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open data file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Compact rewrites the file so that it only holds the current state.
+func (f *File) Compact(_ context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.compact()
+}
+
+// Close closes the file.
+func (f *File) Close(_ context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.journal.close()
+}
+
+// append changes to the file, as a single record, so that they are replayed all or nothing.
+//
+// Called with the write lock held, before the changes are applied.
+func (f *File) append(changes []dataChange) error {
+	if f.journal.shouldCompact(len(f.items) + f.revisions) {
+		err := f.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (f *File) compact() error {
+	snapshot := f.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []dataChange{change})
+	}
+
+	err := f.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact data file: %w", err)
+	}
+
+	return nil
+}
+
+// FileLocation models a LocationStore kept in a single append-only file. See File.
+type FileLocation struct {
+	*MemoryLocation
+	journal *journal
+}
+
+// NewFileLocation builds a new file-backed Location repository, recovering the state kept at path (if any).
+func NewFileLocation(path string) (*FileLocation, error) {
+	result := &FileLocation{
+		MemoryLocation: NewMemoryLocation(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []locationChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal Location changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open Location file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Compact rewrites the file so that it only holds the current state.
+func (l *FileLocation) Compact(_ context.Context) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.compact()
+}
+
+// Close closes the file.
+func (l *FileLocation) Close(_ context.Context) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.journal.close()
+}
+
+// append changes to the file, as a single record. Called with the write lock held, before the changes are applied.
+func (l *FileLocation) append(changes []locationChange) error {
+	if l.journal.shouldCompact(len(l.items)) {
+		err := l.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return l.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (l *FileLocation) compact() error {
+	snapshot := l.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []locationChange{change})
+	}
+
+	err := l.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact Location file: %w", err)
+	}
+
+	return nil
+}
+
+// FileMintJob models a MintJobStore kept in a single append-only file. See File.
+type FileMintJob struct {
+	*MemoryMintJob
+	journal *journal
+}
+
+// NewFileMintJob builds a new file-backed MintJob repository, recovering the state kept at path (if any).
+func NewFileMintJob(path string) (*FileMintJob, error) {
+	result := &FileMintJob{
+		MemoryMintJob: NewMemoryMintJob(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []mintJobChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal mint job changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open mint job file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Close closes the file.
+func (m *FileMintJob) Close(_ context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.journal.close()
+}
+
+// append changes to the file, as a single record. Called with the write lock held, before the changes are applied.
+func (m *FileMintJob) append(changes []mintJobChange) error {
+	if m.journal.shouldCompact(len(m.items)) {
+		err := m.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (m *FileMintJob) compact() error {
+	snapshot := m.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []mintJobChange{change})
+	}
+
+	err := m.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact mint job file: %w", err)
+	}
+
+	return nil
+}
+
+// FileIdempotency models an IdempotencyStore kept in a single append-only file. See File.
+type FileIdempotency struct {
+	*MemoryIdempotency
+	journal *journal
+}
+
+// NewFileIdempotency builds a new file-backed Idempotency repository, recovering the state kept at path (if any).
+func NewFileIdempotency(path string) (*FileIdempotency, error) {
+	result := &FileIdempotency{
+		MemoryIdempotency: NewMemoryIdempotency(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []idempotencyChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal idempotency changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open idempotency file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Close closes the file.
+func (i *FileIdempotency) Close(_ context.Context) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.journal.close()
+}
+
+// append changes to the file, as a single record. Called with the write lock held, before the changes are applied.
+func (i *FileIdempotency) append(changes []idempotencyChange) error {
+	if i.journal.shouldCompact(len(i.items)) {
+		err := i.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (i *FileIdempotency) compact() error {
+	snapshot := i.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []idempotencyChange{change})
+	}
+
+	err := i.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact idempotency file: %w", err)
+	}
+
+	return nil
+}
+
+// FileClaim models a ClaimStore kept in a single append-only file. See File.
+type FileClaim struct {
+	*MemoryClaim
+	journal *journal
+}
+
+// NewFileClaim builds a new file-backed Claim repository, recovering the state kept at path (if any).
+func NewFileClaim(path string) (*FileClaim, error) {
+	result := &FileClaim{
+		MemoryClaim: NewMemoryClaim(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []claimChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal claim changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open claim file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Close closes the file.
+func (c *FileClaim) Close(_ context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.journal.close()
+}
+
+// append changes to the file, as a single record. Called with the write lock held, before the changes are applied.
+func (c *FileClaim) append(changes []claimChange) error {
+	if c.journal.shouldCompact(len(c.items)) {
+		err := c.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (c *FileClaim) compact() error {
+	snapshot := c.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []claimChange{change})
+	}
+
+	err := c.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact claim file: %w", err)
+	}
+
+	return nil
+}
+
+// FileAPIKey models an APIKeyStore kept in a single append-only file. See File.
+type FileAPIKey struct {
+	*MemoryAPIKey
+	journal *journal
+}
+
+// NewFileAPIKey builds a new file-backed APIKey repository, recovering the state kept at path (if any).
+func NewFileAPIKey(path string) (*FileAPIKey, error) {
+	result := &FileAPIKey{
+		MemoryAPIKey: NewMemoryAPIKey(),
+	}
+
+	var err error
+
+	result.journal, err = openJournal(path, func(payload []byte) error {
+		var changes []apiKeyChange
+
+		err := json.Unmarshal(payload, &changes)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal API key changes: %w", err)
+		}
+
+		for _, change := range changes {
+			result.apply(change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open API key file: %w", err)
+	}
+
+	result.persist = result.append
+
+	return result, nil
+}
+
+// Close closes the file.
+func (a *FileAPIKey) Close(_ context.Context) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.journal.close()
+}
+
+// append changes to the file, as a single record. Called with the write lock held, before the changes are applied.
+func (a *FileAPIKey) append(changes []apiKeyChange) error {
+	if a.journal.shouldCompact(len(a.items)) {
+		err := a.compact()
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.journal.append(changes)
+}
+
+// compact the file. Callers must hold the write lock.
+func (a *FileAPIKey) compact() error {
+	snapshot := a.snapshot()
+	records := make([]any, 0, len(snapshot))
+
+	for _, change := range snapshot {
+		records = append(records, []apiKeyChange{change})
+	}
+
+	err := a.journal.compact(records)
+	if err != nil {
+		return fmt.Errorf("could not compact API key file: %w", err)
+	}
+
+	return nil
+}
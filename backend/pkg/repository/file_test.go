@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_FileRecovers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.log")
+
+	repo, err := NewFile(path)
+	assert.NoError(t, err)
+
+	_, err = repo.Create(context.TODO(), models.Data{ID: "kept", Value: "v1"})
+	assert.NoError(t, err)
+
+	_, err = repo.Update(context.TODO(), models.Data{ID: "kept", Value: "v2"})
+	assert.NoError(t, err)
+
+	_, err = repo.Create(context.TODO(), models.Data{ID: "deleted", Value: "v1"})
+	assert.NoError(t, err)
+
+	err = repo.Delete(context.TODO(), "deleted", 0)
+	assert.NoError(t, err)
+
+	err = repo.Transaction(context.TODO(), func(tx DataStore) error {
+		_, err := tx.Create(context.TODO(), models.Data{ID: "batched", Value: "v1"})
+
+		return err
+	})
+	assert.NoError(t, err)
+
+	err = repo.Close(context.TODO())
+	assert.NoError(t, err)
+
+	// simulate a crash in the middle of a write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, types.PermissionReadWrite)
+	assert.NoError(t, err)
+
+	_, err = file.WriteString(`1234abcd [{"kind":"put","data":{"id":"torn"`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	repo, err = NewFile(path)
+	assert.NoError(t, err)
+
+	found, err := repo.ByID(context.TODO(), "kept")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", found.Value)
+
+	_, err = repo.ByID(context.TODO(), "deleted")
+	assert.ErrorIs(t, err, ErrDoesNotExist)
+
+	_, err = repo.ByID(context.TODO(), "batched")
+	assert.NoError(t, err)
+
+	_, err = repo.ByID(context.TODO(), "torn")
+	assert.ErrorIs(t, err, ErrDoesNotExist)
+
+	// the torn record was dropped, so new writes are readable after a restart
+	_, err = repo.Create(context.TODO(), models.Data{ID: "after", Value: "v1"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	repo, err = NewFile(path)
+	assert.NoError(t, err)
+
+	_, err = repo.ByID(context.TODO(), "after")
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+}
+
+func Test_FileCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.log")
+
+	repo, err := NewFile(path)
+	assert.NoError(t, err)
+
+	_, err = repo.Create(context.TODO(), models.Data{ID: "key", Value: "0"})
+	assert.NoError(t, err)
+
+	for i := 1; i <= compactMinRecords; i++ {
+		_, err = repo.Update(context.TODO(), models.Data{ID: "key", Value: fmt.Sprint(i)})
+		assert.NoError(t, err)
+	}
+
+	// the history holds every revision, so there is nothing to compact yet
+	assert.Greater(t, repo.journal.records, compactMinRecords)
+
+	_, err = repo.PruneHistory(context.TODO(), 1, time.Time{}, 2*compactMinRecords)
+	assert.NoError(t, err)
+
+	// the next write finds the file large enough to be compacted first
+	_, err = repo.Update(context.TODO(), models.Data{ID: "key", Value: fmt.Sprint(compactMinRecords + 1)})
+	assert.NoError(t, err)
+	assert.Less(t, repo.journal.records, compactRatio)
+
+	err = repo.Compact(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, repo.journal.records) // the item and its last two revisions
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	repo, err = NewFile(path)
+	assert.NoError(t, err)
+
+	found, err := repo.ByID(context.TODO(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(compactMinRecords+1), found.Value)
+	assert.NoError(t, repo.Close(context.TODO()))
+}
+
+func Test_FileNamespaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.log")
+
+	// a record written before namespaces
+	legacy, err := encodeRecord([]dataChange{
+		{Kind: changePut, Data: models.Data{ID: "legacy", Value: "old", Version: 1}},
+		{Kind: changeRevision, Revision: &models.DataVersion{ID: "legacy", Version: 1, Value: "old"}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, legacy, types.PermissionReadWrite))
+
+	repo, err := NewFile(path)
+	assert.NoError(t, err)
+
+	_, err = repo.In("team-b").Create(context.TODO(), models.Data{ID: "legacy", Value: "team-b"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	repo, err = NewFile(path)
+	assert.NoError(t, err)
+
+	found, err := repo.ByID(context.TODO(), "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", found.Value)
+	assert.Equal(t, types.DefaultNamespace, found.Namespace)
+
+	_, err = repo.Revision(context.TODO(), "legacy", 1)
+	assert.NoError(t, err)
+
+	found, err = repo.In("team-b").ByID(context.TODO(), "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "team-b", found.Value)
+	assert.NoError(t, repo.Close(context.TODO()))
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Idempotency models the DB operations available for idempotency records.
+type Idempotency struct {
+	db *gorm.DB
+}
+
+// NewIdempotency builds a new Idempotency repository.
+//
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
+func NewIdempotency(dsn string, silent bool) (*Idempotency, error) {
+	result := &Idempotency{}
+
+	cfg := &gorm.Config{TranslateError: true}
+	if silent {
+		cfg.Logger = NewNoopLogger()
+	}
+
+	var err error
+
+	result.db, err = gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Idempotency DB: %w", err)
+	}
+
+	return result, nil
+}
+
+// NewIdempotencyTruncate builds a new Idempotency repo, and deletes its previous contents.
+//
+// Meant to be used in tests.
+func NewIdempotencyTruncate(dsn string, silent bool) (*Idempotency, error) {
+	result, err := NewIdempotency(dsn, silent)
+
+	if err == nil {
+		success := result.db.Exec("TRUNCATE TABLE idempotency_records;")
+		if success.Error != nil {
+			return nil, fmt.Errorf("could not truncate: %w", success.Error)
+		}
+	}
+
+	return result, err
+}
+
+// Begin creates a record, unless one with the same key has not expired.
+//
+// An expired record is replaced in the same statement, so that concurrent requests cannot both begin.
+func (i *Idempotency) Begin(
+	ctx context.Context,
+	record models.IdempotencyRecord,
+	now time.Time,
+) (models.IdempotencyRecord, error) {
+	record.CreatedAt = now.Truncate(time.Microsecond)
+
+	success := i.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		UpdateAll: true,
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_records.expires_at <= ?", Vars: []any{now}},
+		}},
+	}).Create(&record)
+	if success.Error != nil {
+		return models.IdempotencyRecord{}, fmt.Errorf("could not begin idempotency record: %w", success.Error)
+	}
+
+	if success.RowsAffected > 0 {
+		return record, nil
+	}
+
+	var existing models.IdempotencyRecord
+
+	success = i.db.WithContext(ctx).First(&existing, "key = ?", record.Key)
+	if success.Error != nil {
+		return models.IdempotencyRecord{}, fmt.Errorf("could not find idempotency record: %w", success.Error)
+	}
+
+	return existing, fmt.Errorf("could not begin idempotency record %q: %w", record.Key, ErrAlreadyExists)
+}
+
+// Complete replaces a record that is in flight.
+func (i *Idempotency) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	success := i.db.WithContext(ctx).
+		Where("key = ? AND created_at = ? AND status_code = 0", record.Key, record.CreatedAt).
+		Select("*").
+		Updates(&record)
+	if success.Error != nil {
+		return fmt.Errorf("could not complete idempotency record: %w", success.Error)
+	}
+
+	if success.RowsAffected == 0 {
+		return ErrDoesNotExist
+	}
+
+	return nil
+}
+
+// Release deletes a record.
+func (i *Idempotency) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	success := i.db.WithContext(ctx).
+		Where("key = ? AND created_at = ?", record.Key, record.CreatedAt).
+		Delete(&models.IdempotencyRecord{})
+	if success.Error != nil {
+		return fmt.Errorf("could not release idempotency record: %w", success.Error)
+	}
+
+	if success.RowsAffected == 0 {
+		return ErrDoesNotExist
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes (at most limit) records that expired before a given moment.
+func (i *Idempotency) PurgeExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	success := i.db.WithContext(ctx).Exec(
+		"DELETE FROM idempotency_records WHERE key IN "+
+			"(SELECT key FROM idempotency_records WHERE expires_at <= ? LIMIT ?);",
+		before, limit,
+	)
+	if success.Error != nil {
+		return 0, fmt.Errorf("could not purge expired idempotency records: %w", success.Error)
+	}
+
+	return success.RowsAffected, nil
+}
+
+// Close closes the DB connection.
+func (i *Idempotency) Close(ctx context.Context) error {
+	database, err := i.db.WithContext(ctx).DB()
+	if err != nil {
+		return fmt.Errorf("could not get DB: %w", err)
+	}
+
+	err = database.Close()
+	if err != nil {
+		return fmt.Errorf("could not close DB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+func Test_Idempotency(t *testing.T) {
+	for name, build := range idempotencyBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			now := time.Now()
+			record := models.IdempotencyRecord{
+				Key: "a", Method: http.MethodPost, Target: "/data", ExpiresAt: now.Add(time.Minute),
+			}
+
+			begun, err := repo.Begin(context.TODO(), record, now)
+			assert.NoError(t, err)
+			assert.True(t, begun.IsInFlight())
+
+			// a second request with the same key gets the first one
+			existing, err := repo.Begin(context.TODO(), record, now.Add(time.Second))
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+			assert.True(t, existing.IsInFlight())
+			assert.True(t, begun.CreatedAt.Equal(existing.CreatedAt))
+
+			begun.StatusCode = http.StatusCreated
+			begun.Header = http.Header{"Content-Type": {"application/json"}}
+			begun.Body = []byte(`{"id":"x"}`)
+			begun.Fingerprint = "f"
+			assert.NoError(t, repo.Complete(context.TODO(), begun))
+			assert.ErrorIs(t, repo.Complete(context.TODO(), begun), ErrDoesNotExist, "only requests in flight complete")
+
+			existing, err = repo.Begin(context.TODO(), record, now.Add(time.Second))
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+			assert.Equal(t, http.StatusCreated, existing.StatusCode)
+			assert.Equal(t, begun.Header, existing.Header)
+			assert.Equal(t, begun.Body, existing.Body)
+
+			// once expired, the key can be used again, and the previous request can no longer release it
+			again, err := repo.Begin(context.TODO(), record, now.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.True(t, again.IsInFlight())
+			assert.ErrorIs(t, repo.Release(context.TODO(), begun), ErrDoesNotExist)
+			assert.NoError(t, repo.Release(context.TODO(), again))
+
+			_, err = repo.Begin(context.TODO(), models.IdempotencyRecord{Key: "b", ExpiresAt: now}, now)
+			assert.NoError(t, err)
+
+			purged, err := repo.PurgeExpired(context.TODO(), now, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+		})
+	}
+}
+
+// idempotencyBackends returns builders for every IdempotencyStore implementation that can be tested in this
+// environment.
+func idempotencyBackends(t *testing.T) map[string]func() (IdempotencyStore, error) {
+	t.Helper()
+
+	result := map[string]func() (IdempotencyStore, error){
+		"memory": func() (IdempotencyStore, error) {
+			return NewMemoryIdempotency(), nil
+		},
+		"file": func() (IdempotencyStore, error) {
+			return NewFileIdempotency(filepath.Join(t.TempDir(), "idempotency.log"))
+		},
+	}
+
+	if dsn := os.Getenv(testDSNVariable); dsn != "" {
+		result["postgres"] = func() (IdempotencyStore, error) {
+			err := migrateTestDB(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewIdempotencyTruncate(dsn, true)
+		}
+	}
+
+	return result
+}
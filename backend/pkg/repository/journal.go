@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// compactMinRecords is the number of records a journal holds before compaction is considered.
+	compactMinRecords = 1024
+	// compactRatio triggers compaction when the journal holds this many records per live item.
+	compactRatio = 4
+	// checksumLength is the length of the hex-encoded CRC32 checksum that prefixes every record.
+	checksumLength = 8
+)
+
+var (
+	// errCorruptRecord for when a journal record cannot be trusted (torn write, bad checksum).
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// journal models an append-only log file.
+//
+// Every line holds one record: the CRC32 checksum of its JSON payload, a space and the payload. Records are synced
+// to disk before append returns, so a crash can only lose (part of) the record being written; openJournal drops it.
+type journal struct {
+	path    string
+	file    *os.File
+	records int
+}
+
+// openJournal opens (or creates) the journal at path and calls replay for every valid record, in order.
+//
+// A corrupt tail, left behind by a crash in the middle of a write, is truncated.
+func openJournal(path string, replay func(payload []byte) error) (*journal, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE, types.PermissionReadWrite)
+	if err != nil {
+		return nil, fmt.Errorf("could not open journal: %w", err)
+	}
+
+	result := &journal{
+		path: path,
+		file: file,
+	}
+
+	valid, err := result.replay(replay)
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	err = file.Truncate(valid)
+	if err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("could not truncate journal: %w", err)
+	}
+
+	_, err = file.Seek(valid, io.SeekStart)
+	if err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("could not seek journal: %w", err)
+	}
+
+	return result, nil
+}
+
+// replay reads the journal from the start and returns the offset right after the last valid record.
+func (j *journal) replay(replay func(payload []byte) error) (int64, error) {
+	reader := bufio.NewReader(j.file)
+
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				log.Default().Printf("dropping torn record at offset %d of %q", offset, j.path)
+			}
+
+			return offset, nil
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("could not read journal: %w", err)
+		}
+
+		payload, err := decodeRecord(line)
+		if err != nil {
+			log.Default().Printf("dropping journal %q from offset %d: %s", j.path, offset, err)
+
+			return offset, nil
+		}
+
+		err = replay(payload)
+		if err != nil {
+			return 0, fmt.Errorf("could not replay record at offset %d: %w", offset, err)
+		}
+
+		offset += int64(len(line))
+		j.records++
+	}
+}
+
+// append a record to the journal and sync it to disk.
+func (j *journal) append(record any) error {
+	line, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.file.Write(line)
+	if err != nil {
+		return fmt.Errorf("could not append to journal: %w", err)
+	}
+
+	err = j.file.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync journal: %w", err)
+	}
+
+	j.records++
+
+	return nil
+}
+
+// shouldCompact tells whether the journal grew large enough, compared to the live items, to be worth compacting.
+func (j *journal) shouldCompact(liveItems int) bool {
+	return j.records >= compactMinRecords && j.records >= compactRatio*liveItems
+}
+
+// compact replaces the journal with one holding only the given records.
+//
+// The new journal is fully written and synced aside, then renamed over the old one, so a crash leaves either of them.
+func (j *journal) compact(records []any) error {
+	temporary := j.path + ".compact"
+
+	file, err := os.OpenFile(filepath.Clean(temporary), os.O_RDWR|os.O_CREATE|os.O_TRUNC, types.PermissionReadWrite)
+	if err != nil {
+		return fmt.Errorf("could not create compacted journal: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	for _, record := range records {
+		line, err := encodeRecord(record)
+		if err != nil {
+			file.Close()
+
+			return err
+		}
+
+		_, err = writer.Write(line)
+		if err != nil {
+			file.Close()
+
+			return fmt.Errorf("could not write compacted journal: %w", err)
+		}
+	}
+
+	err = writer.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("could not sync compacted journal: %w", err)
+	}
+
+	err = os.Rename(temporary, j.path)
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("could not replace journal: %w", err)
+	}
+
+	syncDir(filepath.Dir(j.path))
+
+	j.file.Close()
+	j.file = file
+	j.records = len(records)
+
+	return nil
+}
+
+// close the journal file.
+func (j *journal) close() error {
+	err := j.file.Close()
+	if err != nil {
+		return fmt.Errorf("could not close journal: %w", err)
+	}
+
+	return nil
+}
+
+// encodeRecord builds a journal line out of a record.
+func encodeRecord(record any) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal record: %w", err)
+	}
+
+	line := make([]byte, 0, checksumLength+1+len(payload)+1)
+	line = fmt.Appendf(line, "%08x ", crc32.ChecksumIEEE(payload))
+	line = append(line, payload...)
+	line = append(line, '\n')
+
+	return line, nil
+}
+
+// decodeRecord validates a journal line and returns its payload.
+func decodeRecord(line []byte) ([]byte, error) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	if len(line) < checksumLength+1 || line[checksumLength] != ' ' {
+		return nil, errCorruptRecord
+	}
+
+	checksum, err := strconv.ParseUint(string(line[:checksumLength]), 16, 32)
+	if err != nil {
+		return nil, errCorruptRecord
+	}
+
+	payload := line[checksumLength+1:]
+	if crc32.ChecksumIEEE(payload) != uint32(checksum) {
+		return nil, errCorruptRecord
+	}
+
+	return payload, nil
+}
+
+// syncDir syncs a directory, so that a rename inside it survives a crash. Best effort: not all platforms support it.
+func syncDir(path string) {
+	dir, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+
+	_ = dir.Sync()
+}
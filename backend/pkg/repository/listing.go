@@ -0,0 +1,452 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/gorm"
+)
+
+// surrogateMin and surrogateMax bound the runes reserved for UTF-16, which UTF-8 cannot encode.
+const surrogateMin, surrogateMax = 0xd800, 0xdfff
+
+// sortField describes a field listings can be sorted by.
+type sortField[T any] struct {
+	column string
+	// value returns the field of an item; one of string, int, int64, float64 or time.Time.
+	value func(item T) any
+	// parse turns the formatted value kept in a cursor back into a value.
+	parse func(raw string) (any, error)
+}
+
+// filterField describes a field listings can be filtered by.
+type filterField[T any] struct {
+	column string
+	value  func(item T) string
+}
+
+// accessField describes the owner and the grants of the items of a listing.
+type accessField[T any] struct {
+	ownerColumn  string
+	grantsColumn string
+	owner        func(item T) string
+	grants       func(item T) models.Grants
+}
+
+// listing describes how the items of a repository can be listed.
+type listing[T any] struct {
+	sorts       map[string]sortField[T]
+	filters     map[string]filterField[T]
+	defaultSort string
+	// id is the unique field that breaks ties between items with the same sort value.
+	id sortField[T]
+	// keys is the string field key ranges apply to; nil when the listing does not support them.
+	keys *sortField[T]
+	// access restricts the items to the ones a viewer can see; nil when the listing does not support it.
+	access *accessField[T]
+}
+
+// cursor is the decoded form of types.Page.NextCursor: the position of the last item of a page.
+//
+// It also records the sort it was issued for, so that it cannot be replayed against another one.
+type cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         string `json:"i"`
+}
+
+// listQuery is a validated types.ListOptions.
+type listQuery[T any] struct {
+	listing    listing[T]
+	sortName   string
+	sort       sortField[T]
+	descending bool
+	limit      int
+	filters    map[string]string
+	// afterValue and afterID are the position of the last item of the previous page; nil for the first page.
+	afterValue any
+	afterID    any
+	// start and end bound the keys to [start, end); empty when unbounded.
+	start string
+	end   string
+	// viewer is the ID of the principal the items must be visible to; empty for every item.
+	viewer string
+}
+
+// query validates listing options.
+func (l listing[T]) query(opts types.ListOptions) (listQuery[T], error) {
+	result := listQuery[T]{
+		listing:    l,
+		sortName:   opts.SortBy,
+		descending: opts.Descending,
+		limit:      opts.Limit,
+		filters:    opts.Filters,
+	}
+
+	if result.sortName == "" {
+		result.sortName = l.defaultSort
+	}
+
+	var found bool
+
+	result.sort, found = l.sorts[result.sortName]
+	if !found {
+		return listQuery[T]{}, fmt.Errorf("%w: %q", types.ErrInvalidSort, result.sortName)
+	}
+
+	if result.limit <= 0 {
+		result.limit = types.DefaultLimit
+	}
+
+	result.limit = min(result.limit, types.MaxLimit)
+
+	for name := range opts.Filters {
+		if _, found := l.filters[name]; !found {
+			return listQuery[T]{}, fmt.Errorf("%w: %q", types.ErrInvalidFilter, name)
+		}
+	}
+
+	if opts.Viewer != "" {
+		if l.access == nil {
+			return listQuery[T]{}, fmt.Errorf("%w: viewer", types.ErrInvalidFilter)
+		}
+
+		result.viewer = opts.Viewer
+	}
+
+	if !opts.Range.IsEmpty() {
+		err := result.bound(opts.Range)
+		if err != nil {
+			return listQuery[T]{}, err
+		}
+	}
+
+	if opts.Cursor == "" {
+		return result, nil
+	}
+
+	err := result.decodeCursor(opts.Cursor)
+	if err != nil {
+		return listQuery[T]{}, err
+	}
+
+	return result, nil
+}
+
+// bound restricts the listing to a key range.
+//
+// A prefix is turned into the range of keys starting with it, and intersected with the other bounds.
+func (q *listQuery[T]) bound(keyRange types.KeyRange) error {
+	if q.listing.keys == nil {
+		return fmt.Errorf("%w: not supported", types.ErrInvalidRange)
+	}
+
+	for _, bound := range []string{keyRange.Prefix, keyRange.Start, keyRange.End} {
+		if !utf8.ValidString(bound) {
+			return fmt.Errorf("%w: %q is not valid UTF-8", types.ErrInvalidRange, bound)
+		}
+	}
+
+	if keyRange.End != "" && keyRange.Start > keyRange.End {
+		return fmt.Errorf("%w: %q is after %q", types.ErrInvalidRange, keyRange.Start, keyRange.End)
+	}
+
+	q.start, q.end = keyRange.Start, keyRange.End
+
+	if keyRange.Prefix == "" {
+		return nil
+	}
+
+	q.start = max(q.start, keyRange.Prefix)
+
+	if end := prefixEnd(keyRange.Prefix); end != "" && (q.end == "" || end < q.end) {
+		q.end = end
+	}
+
+	return nil
+}
+
+// decodeCursor sets the position to resume the listing from.
+func (q *listQuery[T]) decodeCursor(raw string) error {
+	asJSON, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return types.ErrInvalidCursor
+	}
+
+	var decoded cursor
+
+	err = json.Unmarshal(asJSON, &decoded)
+	if err != nil || decoded.Sort != q.sortName || decoded.Descending != q.descending {
+		return types.ErrInvalidCursor
+	}
+
+	q.afterValue, err = q.sort.parse(decoded.Value)
+	if err != nil {
+		return types.ErrInvalidCursor
+	}
+
+	q.afterID, err = q.listing.id.parse(decoded.ID)
+	if err != nil {
+		return types.ErrInvalidCursor
+	}
+
+	return nil
+}
+
+// encodeCursor returns the cursor that resumes the listing after a given item.
+func (q *listQuery[T]) encodeCursor(item T) string {
+	asJSON, _ := json.Marshal(cursor{
+		Sort:       q.sortName,
+		Descending: q.descending,
+		Value:      formatValue(q.sort.value(item)),
+		ID:         formatValue(q.listing.id.value(item)),
+	})
+
+	return base64.RawURLEncoding.EncodeToString(asJSON)
+}
+
+// compare two items in listing order.
+func (q *listQuery[T]) compare(first T, second T) int {
+	return q.compareTo(first, q.sort.value(second), q.listing.id.value(second))
+}
+
+// compareTo compares an item to a position, in listing order.
+func (q *listQuery[T]) compareTo(item T, value any, itemID any) int {
+	result := compareValues(q.sort.value(item), value)
+	if result == 0 {
+		result = compareValues(q.listing.id.value(item), itemID)
+	}
+
+	if q.descending {
+		return -result
+	}
+
+	return result
+}
+
+// page lists items held in memory.
+func (q *listQuery[T]) page(items []T) types.Page[T] {
+	matching := make([]T, 0, len(items))
+
+	for _, item := range items {
+		if q.matches(item) {
+			matching = append(matching, item)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool { return q.compare(matching[i], matching[j]) < 0 })
+
+	start := 0
+	if q.afterValue != nil {
+		start = sort.Search(len(matching), func(i int) bool {
+			return q.compareTo(matching[i], q.afterValue, q.afterID) > 0
+		})
+	}
+
+	end := min(start+q.limit, len(matching))
+
+	result := types.Page[T]{
+		Items: matching[start:end],
+		Total: int64(len(matching)),
+	}
+
+	if end < len(matching) {
+		result.NextCursor = q.encodeCursor(matching[end-1])
+	}
+
+	return result
+}
+
+// matches tells whether an item passes the filters and the key range.
+func (q *listQuery[T]) matches(item T) bool {
+	if q.start != "" || q.end != "" {
+		key, _ := q.listing.keys.value(item).(string)
+		if key < q.start || (q.end != "" && key >= q.end) {
+			return false
+		}
+	}
+
+	for name, value := range q.filters {
+		if q.listing.filters[name].value(item) != value {
+			return false
+		}
+	}
+
+	if q.viewer != "" {
+		access := q.listing.access
+
+		return models.Allows(access.owner(item), access.grants(item), q.viewer, models.AccessRead)
+	}
+
+	return true
+}
+
+// gormPage lists items from a DB, leaving the paging to it.
+func (q *listQuery[T]) gormPage(ctx context.Context, database *gorm.DB) (types.Page[T], error) {
+	query := database.WithContext(ctx).Model(new(T))
+
+	for name, value := range q.filters {
+		query = query.Where(q.listing.filters[name].column+" = ?", value)
+	}
+
+	if q.viewer != "" {
+		access := q.listing.access
+		query = query.Where(
+			fmt.Sprintf("(%s = '' OR %s = ? OR %s ->> ? IS NOT NULL)", access.ownerColumn, access.ownerColumn,
+				access.grantsColumn),
+			q.viewer, q.viewer,
+		)
+	}
+
+	if q.start != "" {
+		query = query.Where(q.listing.keys.column+" >= ?", q.start)
+	}
+
+	if q.end != "" {
+		query = query.Where(q.listing.keys.column+" < ?", q.end)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return types.Page[T]{}, fmt.Errorf("could not count: %w", err)
+	}
+
+	operator, direction := ">", "ASC"
+	if q.descending {
+		operator, direction = "<", "DESC"
+	}
+
+	if q.afterValue != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s, %s) %s (?, ?)", q.sort.column, q.listing.id.column, operator),
+			q.afterValue, q.afterID,
+		)
+	}
+
+	var items []T
+
+	err = query.
+		Order(q.sort.column + " " + direction).
+		Order(q.listing.id.column + " " + direction).
+		Limit(q.limit + 1).
+		Find(&items).Error
+	if err != nil {
+		return types.Page[T]{}, fmt.Errorf("could not list: %w", err)
+	}
+
+	result := types.Page[T]{
+		Items: items,
+		Total: total,
+	}
+
+	if len(items) > q.limit {
+		result.Items = items[:q.limit]
+		result.NextCursor = q.encodeCursor(result.Items[q.limit-1])
+	}
+
+	return result, nil
+}
+
+// prefixEnd returns the smallest key that is after all the keys starting with prefix, or "" if there is none.
+//
+// It bumps the last rune that can be, rather than the last byte, so that the result is valid UTF-8, like the keys it
+// is compared with; UTF-8 sorts byte by byte like the runes it encodes.
+func prefixEnd(prefix string) string {
+	end := []rune(prefix)
+
+	for i := len(end) - 1; i >= 0; i-- {
+		next := end[i] + 1
+		if next >= surrogateMin && next <= surrogateMax {
+			next = surrogateMax + 1
+		}
+
+		if next <= unicode.MaxRune {
+			end[i] = next
+
+			return string(end[:i+1])
+		}
+	}
+
+	return ""
+}
+
+// compareValues compares two values of the same type; one of string, int, int64, float64 or time.Time.
+func compareValues(first any, second any) int {
+	switch typed := first.(type) {
+	case string:
+		other, _ := second.(string)
+
+		return strings.Compare(typed, other)
+	case int:
+		other, _ := second.(int)
+
+		return cmp.Compare(typed, other)
+	case int64:
+		other, _ := second.(int64)
+
+		return cmp.Compare(typed, other)
+	case float64:
+		other, _ := second.(float64)
+
+		return cmp.Compare(typed, other)
+	case time.Time:
+		other, _ := second.(time.Time)
+
+		return typed.Compare(other)
+	}
+
+	return 0
+}
+
+// formatValue formats a value so that it can be kept in a cursor.
+func formatValue(value any) string {
+	switch typed := value.(type) {
+	case int:
+		return strconv.Itoa(typed)
+	case int64:
+		return strconv.FormatInt(typed, 10)
+	case float64:
+		return strconv.FormatFloat(typed, 'g', -1, 64)
+	case time.Time:
+		return typed.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// parseString, parseInt, parseInt64, parseFloat and parseTime undo formatValue.
+func parseString(raw string) (any, error) {
+	return raw, nil
+}
+
+func parseInt(raw string) (any, error) {
+	return strconv.Atoi(raw)
+}
+
+func parseInt64(raw string) (any, error) {
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+func parseFloat(raw string) (any, error) {
+	return strconv.ParseFloat(raw, 64)
+}
+
+func parseTime(raw string) (any, error) {
+	return time.Parse(time.RFC3339Nano, raw)
+}
@@ -2,14 +2,43 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// locationID is the unique field of locations.
+var locationID = sortField[models.Location]{
+	column: "id", value: func(item models.Location) any { return item.ID }, parse: parseInt,
+}
+
+// locationListing describes how locations can be listed.
+var locationListing = listing[models.Location]{
+	defaultSort: "id",
+	id:          locationID,
+	sorts: map[string]sortField[models.Location]{
+		"id": locationID,
+		"location": {
+			column: "location", value: func(item models.Location) any { return item.Location }, parse: parseString,
+		},
+		"latitude": {
+			column: "latitude", value: func(item models.Location) any { return float64(item.Latitude) }, parse: parseFloat,
+		},
+		"longitude": {
+			column: "longitude", value: func(item models.Location) any { return float64(item.Longitude) }, parse: parseFloat,
+		},
+	},
+	filters: map[string]filterField[models.Location]{
+		"location": {column: "location", value: func(item models.Location) string { return item.Location }},
+		"image":    {column: "image", value: func(item models.Location) string { return item.Image }},
+	},
+}
+
 // Location models the DB operations available for locations.
 type Location struct {
 	db    *gorm.DB
@@ -18,11 +47,12 @@ type Location struct {
 
 // NewLocation builds a new Location repository.
 //
-// When silent is true, it will use a custom logger that does not output anything to the console.
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
 func NewLocation(dsn string, silent bool) (*Location, error) {
 	result := &Location{}
 
-	cfg := &gorm.Config{}
+	cfg := &gorm.Config{TranslateError: true}
 	if silent {
 		cfg.Logger = NewNoopLogger()
 	}
@@ -34,11 +64,6 @@ func NewLocation(dsn string, silent bool) (*Location, error) {
 		return nil, fmt.Errorf("could not open Import DB: %w", err)
 	}
 
-	err = result.db.AutoMigrate(&models.Location{})
-	if err != nil {
-		return nil, fmt.Errorf("could not auto migrate models.Data: %w", err)
-	}
-
 	return result, nil
 }
 
@@ -73,12 +98,34 @@ func (l *Location) GetAll(ctx context.Context) ([]models.Location, error) {
 	return result, nil
 }
 
+// List returns a page of Location items.
+func (l *Location) List(ctx context.Context, opts types.ListOptions) (types.Page[models.Location], error) {
+	query, err := locationListing.query(opts)
+	if err != nil {
+		return types.Page[models.Location]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	result, err := query.gormPage(ctx, l.db)
+	if err != nil {
+		return types.Page[models.Location]{}, fmt.Errorf("could not list Location items: %w", err)
+	}
+
+	return result, nil
+}
+
 // Create a new Location item.
 func (l *Location) Create(ctx context.Context, item models.Location) (models.Location, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	success := l.db.WithContext(ctx).Create(&item)
+	if errors.Is(success.Error, gorm.ErrDuplicatedKey) {
+		return models.Location{}, fmt.Errorf("could not create Location item %d: %w", item.ID, ErrAlreadyExists)
+	}
+
 	if success.Error != nil {
 		return models.Location{}, fmt.Errorf("could not create Location item: %w", success.Error)
 	}
@@ -95,7 +142,7 @@ func (l *Location) Update(ctx context.Context, item models.Location) error {
 		return ErrDoesNotExist
 	}
 
-	var result models.Data
+	var result models.Location
 
 	success := l.db.WithContext(ctx).First(&result, "id = ?", item.ID)
 	if success.Error != nil {
@@ -118,9 +165,12 @@ func (l *Location) ByID(ctx context.Context, itemID int) (models.Location, error
 	var result models.Location
 
 	success := l.db.WithContext(ctx).First(&result, "id = ?", itemID)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.Location{}, fmt.Errorf("could not find Location item with ID %d: %w", itemID, ErrDoesNotExist)
+	}
 
 	if success.Error != nil {
-		return models.Location{}, fmt.Errorf("could not find Location item with ID %q: %w", itemID, success.Error)
+		return models.Location{}, fmt.Errorf("could not find Location item with ID %d: %w", itemID, success.Error)
 	}
 
 	return result, nil
@@ -137,7 +187,7 @@ func (l *Location) Delete(ctx context.Context, locationID int) error {
 		return ErrDoesNotExist
 	}
 
-	var result models.Data
+	var result models.Location
 
 	success := l.db.WithContext(ctx).First(&result, "id = ?", locationID)
 	if success.Error != nil {
@@ -146,7 +196,7 @@ func (l *Location) Delete(ctx context.Context, locationID int) error {
 
 	success = l.db.WithContext(ctx).Delete(&result)
 	if success.Error != nil {
-		return fmt.Errorf("could not delete Location item %q: %w", locationID, success.Error)
+		return fmt.Errorf("could not delete Location item %d: %w", locationID, success.Error)
 	}
 
 	return nil
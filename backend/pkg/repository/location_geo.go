@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/geo"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// maxCoverCells bounds the number of geohash cells scanned by a box query.
+const maxCoverCells = 16
+
+// nearListing describes how the locations near a point can be listed.
+var nearListing = listing[models.LocationDistance]{
+	defaultSort: "distance",
+	id: sortField[models.LocationDistance]{
+		column: "id", value: func(item models.LocationDistance) any { return item.ID }, parse: parseInt,
+	},
+	sorts: map[string]sortField[models.LocationDistance]{
+		"distance": {
+			column: "distance", value: func(item models.LocationDistance) any { return item.Distance }, parse: parseFloat,
+		},
+	},
+	filters: map[string]filterField[models.LocationDistance]{
+		"location": {column: "location", value: func(item models.LocationDistance) string { return item.Location.Location }},
+		"image":    {column: "image", value: func(item models.LocationDistance) string { return item.Image }},
+	},
+}
+
+// InBox returns a page of the locations inside a bounding box.
+func (l *Location) InBox(
+	ctx context.Context,
+	box geo.BBox,
+	opts types.ListOptions,
+) (types.Page[models.Location], error) {
+	query, err := locationListing.query(opts)
+	if err != nil {
+		return types.Page[models.Location]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	condition, args := boxCondition(box)
+
+	result, err := query.gormPage(ctx, l.db.Where(condition, args...))
+	if err != nil {
+		return types.Page[models.Location]{}, fmt.Errorf("could not list Location items in a box: %w", err)
+	}
+
+	return result, nil
+}
+
+// Near returns a page of the locations within radius meters of a point, with their distance, closest first.
+//
+// The DB only narrows the search down to the box around the circle; distances are computed here.
+func (l *Location) Near(
+	ctx context.Context,
+	center geo.Point,
+	radius float64,
+	opts types.ListOptions,
+) (types.Page[models.LocationDistance], error) {
+	query, err := nearListing.query(opts)
+	if err != nil {
+		return types.Page[models.LocationDistance]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	condition, args := boxCondition(geo.Around(center, radius))
+
+	var candidates []models.Location
+
+	err = l.db.WithContext(ctx).Where(condition, args...).Find(&candidates).Error
+	if err != nil {
+		return types.Page[models.LocationDistance]{}, fmt.Errorf("could not list Location items near a point: %w", err)
+	}
+
+	return nearPage(query, candidates, center, radius), nil
+}
+
+// boxCondition returns the SQL condition matching the locations inside a box.
+//
+// The geohash cells covering the box narrow the search down through idx_locations_geohash; the coordinates make it
+// exact.
+func boxCondition(box geo.BBox) (string, []any) {
+	var (
+		parts []string
+		args  []any
+	)
+
+	for _, part := range box.Split() {
+		cells := geo.Cover(part, maxCoverCells)
+		ranges := make([]string, 0, len(cells))
+
+		for _, cell := range cells {
+			if cell == "" {
+				ranges = append(ranges, "TRUE")
+
+				continue
+			}
+
+			ranges = append(ranges, `(geohash COLLATE "C" >= ? AND geohash COLLATE "C" < ?)`)
+			args = append(args, cell, prefixEnd(cell))
+		}
+
+		parts = append(parts, fmt.Sprintf(
+			"((%s) AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?)", strings.Join(ranges, " OR "),
+		))
+		args = append(args, part.MinLatitude, part.MaxLatitude, part.MinLongitude, part.MaxLongitude)
+	}
+
+	return strings.Join(parts, " OR "), args
+}
+
+// nearPage computes the distance of candidate locations from a point, and returns a page of the ones within radius
+// meters of it.
+func nearPage(
+	query listQuery[models.LocationDistance],
+	candidates []models.Location,
+	center geo.Point,
+	radius float64,
+) types.Page[models.LocationDistance] {
+	items := make([]models.LocationDistance, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		distance := geo.Distance(center, locationPoint(candidate))
+		if distance <= radius {
+			items = append(items, models.LocationDistance{Location: candidate, Distance: distance})
+		}
+	}
+
+	return query.page(items)
+}
+
+// locationPoint returns the position of a location.
+func locationPoint(item models.Location) geo.Point {
+	return geo.Point{Latitude: float64(item.Latitude), Longitude: float64(item.Longitude)}
+}
+
+// InBox returns a page of the locations inside a bounding box.
+func (l *MemoryLocation) InBox(
+	_ context.Context,
+	box geo.BBox,
+	opts types.ListOptions,
+) (types.Page[models.Location], error) {
+	query, err := locationListing.query(opts)
+	if err != nil {
+		return types.Page[models.Location]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return query.page(l.inBox(box)), nil
+}
+
+// Near returns a page of the locations within radius meters of a point, with their distance, closest first.
+func (l *MemoryLocation) Near(
+	_ context.Context,
+	center geo.Point,
+	radius float64,
+	opts types.ListOptions,
+) (types.Page[models.LocationDistance], error) {
+	query, err := nearListing.query(opts)
+	if err != nil {
+		return types.Page[models.LocationDistance]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return nearPage(query, l.inBox(geo.Around(center, radius)), center, radius), nil
+}
+
+// inBox returns the locations inside a bounding box, in no particular order.
+//
+// Callers must hold (at least) the read lock.
+func (l *MemoryLocation) inBox(box geo.BBox) []models.Location {
+	result := make([]models.Location, 0)
+
+	for _, item := range l.items {
+		if box.Contains(locationPoint(item)) {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
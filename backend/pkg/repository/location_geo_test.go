@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/geo"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_LocationGeo(t *testing.T) {
+	places := []models.Location{
+		{Location: "Paris", Latitude: 48.8566, Longitude: 2.3522},
+		{Location: "Versailles", Latitude: 48.8049, Longitude: 2.1204},
+		{Location: "London", Latitude: 51.5074, Longitude: -0.1278},
+		{Location: "Suva", Latitude: -18.1416, Longitude: 178.4419},
+		{Location: "Apia", Latitude: -13.8333, Longitude: -171.7667},
+	}
+
+	for name, build := range locationBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			for _, place := range places {
+				_, err = repo.Create(context.TODO(), place)
+				assert.NoError(t, err)
+			}
+
+			paris := geo.Point{Latitude: 48.8566, Longitude: 2.3522}
+
+			near, err := repo.Near(context.TODO(), paris, 50_000, types.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Paris", "Versailles"}, nearNames(near.Items))
+			assert.InDelta(t, 0, near.Items[0].Distance, 1)
+			assert.InDelta(t, 17_900, near.Items[1].Distance, 100)
+
+			near, err = repo.Near(context.TODO(), paris, 500_000, types.ListOptions{Descending: true, Limit: 2})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"London", "Versailles"}, nearNames(near.Items))
+			assert.NotEmpty(t, near.NextCursor)
+
+			near, err = repo.Near(context.TODO(), paris, 500_000, types.ListOptions{
+				Descending: true, Limit: 2, Cursor: near.NextCursor,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Paris"}, nearNames(near.Items))
+
+			// across the antimeridian
+			pacific := geo.BBox{MinLatitude: -20, MinLongitude: 170, MaxLatitude: -10, MaxLongitude: -170}
+
+			inBox, err := repo.InBox(context.TODO(), pacific, types.ListOptions{SortBy: "location"})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Apia", "Suva"}, locationNames(inBox.Items))
+
+			europe := geo.BBox{MinLatitude: 40, MinLongitude: -10, MaxLatitude: 60, MaxLongitude: 10}
+
+			inBox, err = repo.InBox(context.TODO(), europe, types.ListOptions{
+				SortBy: "location", Filters: map[string]string{"location": "London"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"London"}, locationNames(inBox.Items))
+		})
+	}
+}
+
+// nearNames returns the names of locations with their distance, in order.
+func nearNames(items []models.LocationDistance) []string {
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		result = append(result, item.Location.Location)
+	}
+
+	return result
+}
+
+// locationNames returns the names of locations, in order.
+func locationNames(items []models.Location) []string {
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		result = append(result, item.Location)
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+func Test_LocationCRUD(t *testing.T) {
+	for name, build := range locationBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			created, err := repo.Create(context.TODO(), models.Location{Location: "Old Town", Latitude: 44.43})
+			assert.NoError(t, err)
+			assert.NotZero(t, created.ID)
+
+			created.Location = "New Town"
+			err = repo.Update(context.TODO(), created)
+			assert.NoError(t, err)
+
+			found, err := repo.ByID(context.TODO(), created.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "New Town", found.Location)
+
+			err = repo.Delete(context.TODO(), created.ID)
+			assert.NoError(t, err)
+
+			_, err = repo.ByID(context.TODO(), created.ID)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			err = repo.Update(context.TODO(), created)
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+		})
+	}
+}
+
+// locationBackends returns builders for every LocationStore implementation that can be tested in this environment.
+func locationBackends(t *testing.T) map[string]func() (LocationStore, error) {
+	t.Helper()
+
+	result := map[string]func() (LocationStore, error){
+		"memory": func() (LocationStore, error) {
+			return NewMemoryLocation(), nil
+		},
+		"file": func() (LocationStore, error) {
+			return NewFileLocation(filepath.Join(t.TempDir(), "locations.log"))
+		},
+	}
+
+	if dsn := os.Getenv(testDSNVariable); dsn != "" {
+		result["postgres"] = func() (LocationStore, error) {
+			err := migrateTestDB(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewLocationTruncate(dsn, true)
+		}
+	}
+
+	return result
+}
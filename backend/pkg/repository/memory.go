@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/gorm"
+)
+
+const (
+	// changePut stores the whole state of an item, replacing the previous one.
+	changePut = "put"
+	// changeRemove removes an item for good.
+	changeRemove = "remove"
+	// changeRevision records a revision of a data item.
+	changeRevision = "revision"
+	// changeForget removes a revision of a data item for good.
+	changeForget = "forget"
+)
+
+// dataChange is a single mutation of the in-memory data state.
+//
+// Every write goes through one, so that File can persist it before it is applied.
+type dataChange struct {
+	Kind     string              `json:"kind"`
+	Data     models.Data         `json:"data"`
+	Revision *models.DataVersion `json:"revision,omitempty"`
+}
+
+// dataKey identifies a data item, or its revisions: its ID is unique within its namespace.
+type dataKey struct {
+	namespace string
+	id        string
+}
+
+// inDefaultNamespace returns the change, with its item and revision in types.DefaultNamespace when they name none, as
+// the ones recorded before namespaces.
+func (dc dataChange) inDefaultNamespace() dataChange {
+	if dc.Data.Namespace == "" {
+		dc.Data.Namespace = types.DefaultNamespace
+	}
+
+	if dc.Revision != nil && dc.Revision.Namespace == "" {
+		revision := *dc.Revision
+		revision.Namespace = types.DefaultNamespace
+		dc.Revision = &revision
+	}
+
+	return dc
+}
+
+// Memory models an in-memory implementation of DataStore.
+//
+// It mirrors Store: it sets the same timestamps and deletes are soft, so deleted items stay in the trash until they
+// are restored, purged or replaced.
+type Memory struct {
+	*memoryState
+	// namespace is the one the items are read from and written to.
+	namespace string
+}
+
+// memoryState is the state of Memory, shared by the views handed out by In.
+type memoryState struct {
+	items map[dataKey]models.Data
+	// history holds the revisions of every data item, by ascending version.
+	history map[dataKey][]models.DataVersion
+	// revisions counts the revisions in history.
+	revisions int
+	mutex     sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied. If it fails, the write
+	// fails and the state is left untouched.
+	persist func(changes []dataChange) error
+}
+
+// NewMemory builds a new, empty, in-memory data repository.
+func NewMemory() *Memory {
+	return &Memory{
+		memoryState: &memoryState{
+			items:   make(map[dataKey]models.Data),
+			history: make(map[dataKey][]models.DataVersion),
+		},
+		namespace: types.DefaultNamespace,
+	}
+}
+
+// In returns a view of the repository working in a given namespace.
+func (m *Memory) In(namespace string) DataStore {
+	return &Memory{memoryState: m.memoryState, namespace: namespace}
+}
+
+// Usage returns the number of data items that were not deleted and did not expire, and the size of their IDs and
+// values. Transactions hold the write lock, so nothing can change in between a call and the writes that follow it.
+func (m *Memory) Usage(_ context.Context) (types.Usage, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var result types.Usage
+
+	for _, item := range m.live() {
+		result.Keys++
+		result.Bytes += int64(len(item.ID) + len(item.Value))
+	}
+
+	return result, nil
+}
+
+// GetAll returns all data items that were not deleted, ordered by ID.
+func (m *Memory) GetAll(_ context.Context) ([]models.Data, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := m.live()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// List returns a page of the data items that were not deleted.
+func (m *Memory) List(_ context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
+	query, err := dataListing.query(opts)
+	if err != nil {
+		return types.Page[models.Data]{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return query.page(m.live()), nil
+}
+
+// Create a new data item.
+//
+// Sets the CreatedAt, UpdatedAt and Version fields, and records the first revision. A deleted or expired item with
+// the same ID is replaced.
+func (m *Memory) Create(_ context.Context, item models.Data) (models.Data, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	item.Namespace = m.namespace
+
+	if existing, found := m.items[keyOf(item)]; found && !existing.DeletedAt.Valid && !existing.IsExpired(now) {
+		return models.Data{}, fmt.Errorf("could not create data item %q: %w", item.ID, ErrAlreadyExists)
+	}
+
+	item.CreatedAt = now
+	item.UpdatedAt = item.CreatedAt
+	item.DeletedAt = gorm.DeletedAt{}
+	item.Version = m.latestVersion(keyOf(item)) + 1
+
+	err := m.commit(m.write(item)...)
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not create data item: %w", err)
+	}
+
+	return item, nil
+}
+
+// Update a given data item, and returns it as stored.
+//
+// When item.Version is not zero, the item is only updated if it is still at that version; otherwise it returns
+// ErrVersionMismatch. Bumps the Version field and records the new revision.
+func (m *Memory) Update(_ context.Context, item models.Data) (models.Data, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if item.ID == "" {
+		return models.Data{}, ErrDoesNotExist
+	}
+
+	now := time.Now()
+	item.Namespace = m.namespace
+
+	existing, found := m.items[keyOf(item)]
+	if !found || existing.DeletedAt.Valid || existing.IsExpired(now) {
+		return models.Data{}, ErrDoesNotExist
+	}
+
+	if item.Version != 0 && item.Version != existing.Version {
+		return models.Data{}, ErrVersionMismatch
+	}
+
+	item.CreatedAt = existing.CreatedAt
+	item.UpdatedAt = now
+	item.DeletedAt = gorm.DeletedAt{}
+	item.Version = existing.Version + 1
+
+	err := m.commit(m.write(item)...)
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not update data item: %w", err)
+	}
+
+	return item, nil
+}
+
+// ByID returns the data item with a given ID.
+func (m *Memory) ByID(_ context.Context, itemID string) (models.Data, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result, found := m.items[m.key(itemID)]
+	if !found || result.DeletedAt.Valid || result.IsExpired(time.Now()) {
+		return models.Data{}, fmt.Errorf("could not find data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// Delete a given data item.
+//
+// Like Store, it only marks the item as deleted, bumps the Version field and records a revision marked as deleted.
+// When version is not zero, the item is only deleted if it is still at that version; otherwise it returns
+// ErrVersionMismatch.
+func (m *Memory) Delete(_ context.Context, dataID string, version int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if dataID == "" {
+		return ErrDoesNotExist
+	}
+
+	now := time.Now()
+
+	existing, found := m.items[m.key(dataID)]
+	if !found || existing.DeletedAt.Valid || existing.IsExpired(now) {
+		return ErrDoesNotExist
+	}
+
+	if version != 0 && version != existing.Version {
+		return ErrVersionMismatch
+	}
+
+	existing.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	existing.Version++
+
+	err := m.commit(m.write(existing)...)
+	if err != nil {
+		return fmt.Errorf("could not delete data item %q: %w", dataID, err)
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes for good (at most limit) data items that expired before a given moment, in every namespace.
+//
+// Returns how many were deleted.
+func (m *Memory) PurgeExpired(_ context.Context, before time.Time, limit int) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changes := make([]dataChange, 0, limit)
+
+	for _, item := range m.items {
+		if len(changes) == limit {
+			break
+		}
+
+		if item.IsExpired(before) {
+			changes = append(changes, dataChange{Kind: changeRemove, Data: models.Data{Namespace: item.Namespace, ID: item.ID}})
+		}
+	}
+
+	err := m.commit(changes...)
+	if err != nil {
+		return 0, fmt.Errorf("could not purge expired data items: %w", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// Close does nothing; there are no resources to release.
+func (m *Memory) Close(_ context.Context) error {
+	return nil
+}
+
+// live returns the data items of the namespace that were not deleted and did not expire, in no particular order.
+//
+// Callers must hold (at least) the read lock.
+func (m *Memory) live() []models.Data {
+	now := time.Now()
+	result := make([]models.Data, 0, len(m.items))
+
+	for _, item := range m.items {
+		if item.Namespace == m.namespace && !item.DeletedAt.Valid && !item.IsExpired(now) {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// write returns the changes that store a data item and record its revision.
+func (m *Memory) write(item models.Data) []dataChange {
+	revision := item.Revision()
+
+	return []dataChange{
+		{Kind: changePut, Data: item},
+		{Kind: changeRevision, Revision: &revision},
+	}
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (m *Memory) commit(changes ...dataChange) error {
+	if m.persist != nil {
+		err := m.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		m.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (m *Memory) apply(change dataChange) {
+	switch change.Kind {
+	case changePut:
+		m.items[keyOf(change.Data)] = change.Data
+	case changeRemove:
+		delete(m.items, keyOf(change.Data))
+	case changeRevision:
+		m.record(*change.Revision)
+	case changeForget:
+		m.forget(revisionKey(*change.Revision), change.Revision.Version)
+	}
+}
+
+// key returns the key of the data item with a given ID in the namespace.
+func (m *Memory) key(itemID string) dataKey {
+	return dataKey{namespace: m.namespace, id: itemID}
+}
+
+// keyOf returns the key of a data item.
+func keyOf(item models.Data) dataKey {
+	return dataKey{namespace: item.Namespace, id: item.ID}
+}
+
+// revisionKey returns the key of the data item a revision belongs to.
+func revisionKey(revision models.DataVersion) dataKey {
+	return dataKey{namespace: revision.Namespace, id: revision.ID}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (m *Memory) snapshot() []dataChange {
+	result := make([]dataChange, 0, len(m.items))
+
+	for _, item := range m.items {
+		result = append(result, dataChange{Kind: changePut, Data: item})
+	}
+
+	for _, revisions := range m.history {
+		for _, revision := range revisions {
+			result = append(result, dataChange{Kind: changeRevision, Revision: &revision})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// apiKeyChange is a single mutation of the in-memory API key state.
+type apiKeyChange struct {
+	Kind string        `json:"kind"`
+	Key  models.APIKey `json:"key"`
+}
+
+// MemoryAPIKey models an in-memory implementation of APIKeyStore.
+type MemoryAPIKey struct {
+	items map[string]models.APIKey
+	mutex sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied.
+	persist func(changes []apiKeyChange) error
+}
+
+// NewMemoryAPIKey builds a new, empty, in-memory APIKey repository.
+func NewMemoryAPIKey() *MemoryAPIKey {
+	return &MemoryAPIKey{
+		items: make(map[string]models.APIKey),
+	}
+}
+
+// Create a new API key.
+func (m *MemoryAPIKey) Create(_ context.Context, key models.APIKey) (models.APIKey, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.items[key.ID]; found {
+		return models.APIKey{}, fmt.Errorf("could not create API key %q: %w", key.ID, ErrAlreadyExists)
+	}
+
+	key.CreatedAt = time.Now()
+	key.RevokedAt = nil
+
+	err := m.commit(apiKeyChange{Kind: changePut, Key: key})
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("could not create API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ByID returns the API key with a given ID.
+func (m *MemoryAPIKey) ByID(_ context.Context, keyID string) (models.APIKey, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result, found := m.items[keyID]
+	if !found {
+		return models.APIKey{}, fmt.Errorf("could not find API key with ID %q: %w", keyID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// List returns a page of API keys.
+func (m *MemoryAPIKey) List(_ context.Context, opts types.ListOptions) (types.Page[models.APIKey], error) {
+	query, err := apiKeyListing.query(opts)
+	if err != nil {
+		return types.Page[models.APIKey]{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	items := make([]models.APIKey, 0, len(m.items))
+
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+
+	return query.page(items), nil
+}
+
+// Revoke the API key with a given ID, at a given moment; a key that was revoked already is left as it is.
+func (m *MemoryAPIKey) Revoke(_ context.Context, keyID string, at time.Time) (models.APIKey, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key, found := m.items[keyID]
+	if !found {
+		return models.APIKey{}, fmt.Errorf("could not find API key with ID %q: %w", keyID, ErrDoesNotExist)
+	}
+
+	if key.IsRevoked() {
+		return key, nil
+	}
+
+	key.RevokedAt = &at
+
+	err := m.commit(apiKeyChange{Kind: changePut, Key: key})
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("could not revoke API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Close does nothing; there are no resources to release.
+func (m *MemoryAPIKey) Close(_ context.Context) error {
+	return nil
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (m *MemoryAPIKey) commit(changes ...apiKeyChange) error {
+	if m.persist != nil {
+		err := m.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		m.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (m *MemoryAPIKey) apply(change apiKeyChange) {
+	switch change.Kind {
+	case changePut:
+		m.items[change.Key.ID] = change.Key
+	case changeRemove:
+		delete(m.items, change.Key.ID)
+	}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (m *MemoryAPIKey) snapshot() []apiKeyChange {
+	result := make([]apiKeyChange, 0, len(m.items))
+
+	for _, key := range m.items {
+		result = append(result, apiKeyChange{Kind: changePut, Key: key})
+	}
+
+	return result
+}
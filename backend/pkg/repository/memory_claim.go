@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// claimChange is a single mutation of the in-memory claim state.
+type claimChange struct {
+	Kind  string       `json:"kind"`
+	Claim models.Claim `json:"claim"`
+}
+
+// claimKey identifies the claim of a location by a wallet.
+type claimKey struct {
+	locationID int
+	wallet     string
+}
+
+// MemoryClaim models an in-memory implementation of ClaimStore.
+type MemoryClaim struct {
+	items map[string]models.Claim
+	// byKey holds the IDs of the claims, by location and wallet.
+	byKey map[claimKey]string
+	mutex sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied.
+	persist func(changes []claimChange) error
+}
+
+// NewMemoryClaim builds a new, empty, in-memory Claim repository.
+func NewMemoryClaim() *MemoryClaim {
+	return &MemoryClaim{
+		items: make(map[string]models.Claim),
+		byKey: make(map[claimKey]string),
+	}
+}
+
+// Create a new claim.
+func (m *MemoryClaim) Create(_ context.Context, claim models.Claim) (models.Claim, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, taken := m.byKey[claimKey{locationID: claim.LocationID, wallet: claim.Wallet}]
+	if _, found := m.items[claim.ID]; found || taken {
+		return models.Claim{}, fmt.Errorf(
+			"could not create claim of location %d by %q: %w", claim.LocationID, claim.Wallet, ErrAlreadyExists)
+	}
+
+	claim.CreatedAt = time.Now()
+
+	err := m.commit(claimChange{Kind: changePut, Claim: claim})
+	if err != nil {
+		return models.Claim{}, fmt.Errorf("could not create claim: %w", err)
+	}
+
+	return claim, nil
+}
+
+// Find returns the claim of a location by a wallet.
+func (m *MemoryClaim) Find(_ context.Context, locationID int, wallet string) (models.Claim, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	claimID, found := m.byKey[claimKey{locationID: locationID, wallet: wallet}]
+	if !found {
+		return models.Claim{}, fmt.Errorf(
+			"could not find claim of location %d by %q: %w", locationID, wallet, ErrDoesNotExist)
+	}
+
+	return m.items[claimID], nil
+}
+
+// List returns a page of claims.
+func (m *MemoryClaim) List(_ context.Context, opts types.ListOptions) (types.Page[models.Claim], error) {
+	query, err := claimListing.query(opts)
+	if err != nil {
+		return types.Page[models.Claim]{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	items := make([]models.Claim, 0, len(m.items))
+
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+
+	return query.page(items), nil
+}
+
+// Delete the claim with a given ID.
+func (m *MemoryClaim) Delete(_ context.Context, claimID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	claim, found := m.items[claimID]
+	if !found {
+		return fmt.Errorf("could not delete claim %q: %w", claimID, ErrDoesNotExist)
+	}
+
+	err := m.commit(claimChange{Kind: changeRemove, Claim: claim})
+	if err != nil {
+		return fmt.Errorf("could not delete claim: %w", err)
+	}
+
+	return nil
+}
+
+// Close does nothing; there are no resources to release.
+func (m *MemoryClaim) Close(_ context.Context) error {
+	return nil
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (m *MemoryClaim) commit(changes ...claimChange) error {
+	if m.persist != nil {
+		err := m.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		m.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (m *MemoryClaim) apply(change claimChange) {
+	key := claimKey{locationID: change.Claim.LocationID, wallet: change.Claim.Wallet}
+
+	switch change.Kind {
+	case changePut:
+		m.items[change.Claim.ID] = change.Claim
+		m.byKey[key] = change.Claim.ID
+	case changeRemove:
+		delete(m.items, change.Claim.ID)
+		delete(m.byKey, key)
+	}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (m *MemoryClaim) snapshot() []claimChange {
+	result := make([]claimChange, 0, len(m.items))
+
+	for _, claim := range m.items {
+		result = append(result, claimChange{Kind: changePut, Claim: claim})
+	}
+
+	return result
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// History returns a page of the revisions of a data item, including the ones written by deletes.
+func (m *Memory) History(
+	_ context.Context,
+	itemID string,
+	opts types.ListOptions,
+) (types.Page[models.DataVersion], error) {
+	query, err := revisionListing.query(opts)
+	if err != nil {
+		return types.Page[models.DataVersion]{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return query.page(m.history[m.key(itemID)]), nil
+}
+
+// Revision returns a given revision of a data item.
+func (m *Memory) Revision(_ context.Context, itemID string, version int64) (models.DataVersion, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result, found := m.revision(m.key(itemID), version)
+	if !found {
+		return models.DataVersion{}, fmt.Errorf("could not find revision %d of %q: %w", version, itemID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// RevisionAt returns the revision of a data item that was the latest one at a given moment.
+func (m *Memory) RevisionAt(_ context.Context, itemID string, at time.Time) (models.DataVersion, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	revisions := m.history[m.key(itemID)]
+
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !revisions[i].CreatedAt.After(at) {
+			return revisions[i], nil
+		}
+	}
+
+	return models.DataVersion{}, fmt.Errorf("could not find a revision of %q at %s: %w", itemID, at, ErrDoesNotExist)
+}
+
+// PruneHistory deletes (at most limit) revisions that are not among the newest keep ones of their data item (when
+// keep is positive), or that were written before a given moment, in every namespace.
+//
+// The latest revision of a data item is always kept. Returns how many were deleted.
+func (m *Memory) PruneHistory(_ context.Context, keep int, before time.Time, limit int) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changes := make([]dataChange, 0, limit)
+
+	for _, revisions := range m.history {
+		// the latest revision is the last one, and it is always kept
+		for i := 0; i < len(revisions)-1 && len(changes) < limit; i++ {
+			rank := len(revisions) - i
+			if (keep > 0 && rank > keep) || revisions[i].CreatedAt.Before(before) {
+				revision := revisions[i]
+				changes = append(changes, dataChange{Kind: changeForget, Revision: &revision})
+			}
+		}
+	}
+
+	err := m.commit(changes...)
+	if err != nil {
+		return 0, fmt.Errorf("could not prune revisions: %w", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// latestVersion returns the version of the latest revision of a data item; zero when there is none.
+//
+// Callers must hold (at least) the read lock.
+func (m *Memory) latestVersion(key dataKey) int64 {
+	revisions := m.history[key]
+	if len(revisions) == 0 {
+		return 0
+	}
+
+	return revisions[len(revisions)-1].Version
+}
+
+// record a revision, keeping the history sorted by version.
+func (m *Memory) record(revision models.DataVersion) {
+	key := revisionKey(revision)
+	revisions := m.history[key]
+
+	position, found := sort.Find(len(revisions), func(i int) int {
+		return compareValues(revision.Version, revisions[i].Version)
+	})
+	if found {
+		revisions[position] = revision
+
+		return
+	}
+
+	m.history[key] = slices.Insert(revisions, position, revision)
+	m.revisions++
+}
+
+// forget a revision of the data item with a given key.
+func (m *Memory) forget(key dataKey, version int64) {
+	revisions := m.history[key]
+
+	position, found := sort.Find(len(revisions), func(i int) int {
+		return compareValues(version, revisions[i].Version)
+	})
+	if !found {
+		return
+	}
+
+	revisions = slices.Delete(revisions, position, position+1)
+	m.revisions--
+
+	if len(revisions) == 0 {
+		delete(m.history, key)
+
+		return
+	}
+
+	m.history[key] = revisions
+}
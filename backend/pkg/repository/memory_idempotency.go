@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+// idempotencyChange is a single mutation of the in-memory idempotency state.
+type idempotencyChange struct {
+	Kind   string                   `json:"kind"`
+	Record models.IdempotencyRecord `json:"record"`
+}
+
+// MemoryIdempotency models an in-memory implementation of IdempotencyStore.
+type MemoryIdempotency struct {
+	items map[string]models.IdempotencyRecord
+	mutex sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied.
+	persist func(changes []idempotencyChange) error
+}
+
+// NewMemoryIdempotency builds a new, empty, in-memory Idempotency repository.
+func NewMemoryIdempotency() *MemoryIdempotency {
+	return &MemoryIdempotency{
+		items: make(map[string]models.IdempotencyRecord),
+	}
+}
+
+// Begin creates a record, unless one with the same key has not expired.
+func (m *MemoryIdempotency) Begin(
+	_ context.Context,
+	record models.IdempotencyRecord,
+	now time.Time,
+) (models.IdempotencyRecord, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if existing, found := m.items[record.Key]; found && existing.ExpiresAt.After(now) {
+		return existing, fmt.Errorf("could not begin idempotency record %q: %w", record.Key, ErrAlreadyExists)
+	}
+
+	record.CreatedAt = now.Truncate(time.Microsecond)
+
+	err := m.commit(idempotencyChange{Kind: changePut, Record: record})
+	if err != nil {
+		return models.IdempotencyRecord{}, fmt.Errorf("could not begin idempotency record: %w", err)
+	}
+
+	return record, nil
+}
+
+// Complete replaces a record that is in flight.
+func (m *MemoryIdempotency) Complete(_ context.Context, record models.IdempotencyRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existing, found := m.items[record.Key]
+	if !found || !existing.CreatedAt.Equal(record.CreatedAt) || !existing.IsInFlight() {
+		return ErrDoesNotExist
+	}
+
+	err := m.commit(idempotencyChange{Kind: changePut, Record: record})
+	if err != nil {
+		return fmt.Errorf("could not complete idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// Release deletes a record.
+func (m *MemoryIdempotency) Release(_ context.Context, record models.IdempotencyRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existing, found := m.items[record.Key]
+	if !found || !existing.CreatedAt.Equal(record.CreatedAt) {
+		return ErrDoesNotExist
+	}
+
+	err := m.commit(idempotencyChange{Kind: changeRemove, Record: existing})
+	if err != nil {
+		return fmt.Errorf("could not release idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes (at most limit) records that expired before a given moment.
+func (m *MemoryIdempotency) PurgeExpired(_ context.Context, before time.Time, limit int) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changes := make([]idempotencyChange, 0, min(limit, len(m.items)))
+
+	for _, record := range m.items {
+		if len(changes) == limit {
+			break
+		}
+
+		if !record.ExpiresAt.After(before) {
+			changes = append(changes, idempotencyChange{Kind: changeRemove, Record: record})
+		}
+	}
+
+	err := m.commit(changes...)
+	if err != nil {
+		return 0, fmt.Errorf("could not purge expired idempotency records: %w", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// Close does nothing; there are no resources to release.
+func (m *MemoryIdempotency) Close(_ context.Context) error {
+	return nil
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (m *MemoryIdempotency) commit(changes ...idempotencyChange) error {
+	if m.persist != nil && len(changes) > 0 {
+		err := m.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		m.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (m *MemoryIdempotency) apply(change idempotencyChange) {
+	switch change.Kind {
+	case changePut:
+		m.items[change.Record.Key] = change.Record
+	case changeRemove:
+		delete(m.items, change.Record.Key)
+	}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (m *MemoryIdempotency) snapshot() []idempotencyChange {
+	result := make([]idempotencyChange, 0, len(m.items))
+
+	for _, record := range m.items {
+		result = append(result, idempotencyChange{Kind: changePut, Record: record})
+	}
+
+	return result
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// changeSequence moves the ID sequence forward, so that IDs of removed locations are not handed out again.
+const changeSequence = "sequence"
+
+// locationChange is a single mutation of the in-memory Location state.
+type locationChange struct {
+	Kind     string          `json:"kind"`
+	Location models.Location `json:"location"`
+	NextID   int             `json:"nextId,omitempty"`
+}
+
+// MemoryLocation models an in-memory implementation of LocationStore.
+type MemoryLocation struct {
+	items  map[int]models.Location
+	nextID int
+	mutex  sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied.
+	persist func(changes []locationChange) error
+}
+
+// NewMemoryLocation builds a new, empty, in-memory Location repository.
+func NewMemoryLocation() *MemoryLocation {
+	return &MemoryLocation{
+		items:  make(map[int]models.Location),
+		nextID: 1,
+	}
+}
+
+// GetAll returns all Location items, ordered by ID.
+func (l *MemoryLocation) GetAll(_ context.Context) ([]models.Location, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	result := make([]models.Location, 0, len(l.items))
+
+	for _, item := range l.items {
+		result = append(result, item)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// List returns a page of Location items.
+func (l *MemoryLocation) List(_ context.Context, opts types.ListOptions) (types.Page[models.Location], error) {
+	query, err := locationListing.query(opts)
+	if err != nil {
+		return types.Page[models.Location]{}, err
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	items := make([]models.Location, 0, len(l.items))
+
+	for _, item := range l.items {
+		items = append(items, item)
+	}
+
+	return query.page(items), nil
+}
+
+// Create a new Location item.
+//
+// Like the serial column used by Location, a zero ID is replaced with the next available one.
+func (l *MemoryLocation) Create(_ context.Context, item models.Location) (models.Location, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if item.ID == 0 {
+		item.ID = l.nextID
+	}
+
+	if _, found := l.items[item.ID]; found {
+		return models.Location{}, fmt.Errorf("could not create Location item %d: %w", item.ID, ErrAlreadyExists)
+	}
+
+	err := l.commit(locationChange{Kind: changePut, Location: item})
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not create Location item: %w", err)
+	}
+
+	return item, nil
+}
+
+// Update a given Location item.
+func (l *MemoryLocation) Update(_ context.Context, item models.Location) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if item.ID < 0 {
+		return ErrDoesNotExist
+	}
+
+	if _, found := l.items[item.ID]; !found {
+		return ErrDoesNotExist
+	}
+
+	err := l.commit(locationChange{Kind: changePut, Location: item})
+	if err != nil {
+		return fmt.Errorf("could not update Location item: %w", err)
+	}
+
+	return nil
+}
+
+// ByID returns the Location item with a given ID.
+func (l *MemoryLocation) ByID(_ context.Context, itemID int) (models.Location, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	result, found := l.items[itemID]
+	if !found {
+		return models.Location{}, fmt.Errorf("could not find Location item with ID %d: %w", itemID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// Delete a given Location item.
+func (l *MemoryLocation) Delete(_ context.Context, locationID int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	existing, found := l.items[locationID]
+	if !found {
+		return ErrDoesNotExist
+	}
+
+	err := l.commit(locationChange{Kind: changeRemove, Location: existing})
+	if err != nil {
+		return fmt.Errorf("could not delete Location item %d: %w", locationID, err)
+	}
+
+	return nil
+}
+
+// Close does nothing; there are no resources to release.
+func (l *MemoryLocation) Close(_ context.Context) error {
+	return nil
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (l *MemoryLocation) commit(changes ...locationChange) error {
+	if l.persist != nil {
+		err := l.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		l.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (l *MemoryLocation) apply(change locationChange) {
+	switch change.Kind {
+	case changePut:
+		l.items[change.Location.ID] = change.Location
+
+		if change.Location.ID >= l.nextID {
+			l.nextID = change.Location.ID + 1
+		}
+	case changeRemove:
+		delete(l.items, change.Location.ID)
+	case changeSequence:
+		l.nextID = max(l.nextID, change.NextID)
+	}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (l *MemoryLocation) snapshot() []locationChange {
+	result := make([]locationChange, 0, len(l.items)+1)
+	result = append(result, locationChange{Kind: changeSequence, NextID: l.nextID})
+
+	for _, item := range l.items {
+		result = append(result, locationChange{Kind: changePut, Location: item})
+	}
+
+	return result
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+// mintJobChange is a single mutation of the in-memory mint job state.
+type mintJobChange struct {
+	Kind string         `json:"kind"`
+	Job  models.MintJob `json:"job"`
+}
+
+// MemoryMintJob models an in-memory implementation of MintJobStore.
+type MemoryMintJob struct {
+	items map[string]models.MintJob
+	mutex sync.RWMutex
+	// persist, when set, is called with the changes of every write before they are applied.
+	persist func(changes []mintJobChange) error
+}
+
+// NewMemoryMintJob builds a new, empty, in-memory MintJob repository.
+func NewMemoryMintJob() *MemoryMintJob {
+	return &MemoryMintJob{
+		items: make(map[string]models.MintJob),
+	}
+}
+
+// Create a new mint job.
+func (m *MemoryMintJob) Create(_ context.Context, job models.MintJob) (models.MintJob, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.items[job.ID]; found {
+		return models.MintJob{}, fmt.Errorf("could not create mint job %q: %w", job.ID, ErrAlreadyExists)
+	}
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	job.Version = 1
+
+	err := m.commit(mintJobChange{Kind: changePut, Job: job})
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not create mint job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ByID returns the mint job with a given ID.
+func (m *MemoryMintJob) ByID(_ context.Context, jobID string) (models.MintJob, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result, found := m.items[jobID]
+	if !found {
+		return models.MintJob{}, fmt.Errorf("could not find mint job with ID %q: %w", jobID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// Claim leases the oldest claimable job until a given moment, and counts an attempt.
+func (m *MemoryMintJob) Claim(_ context.Context, now time.Time, leaseUntil time.Time) (models.MintJob, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var (
+		result models.MintJob
+		found  bool
+	)
+
+	for _, job := range m.items {
+		if job.IsFinished() || job.NextAttemptAt.After(now) || (job.LeaseUntil != nil && job.LeaseUntil.After(now)) {
+			continue
+		}
+
+		if !found || job.NextAttemptAt.Before(result.NextAttemptAt) ||
+			(job.NextAttemptAt.Equal(result.NextAttemptAt) && job.ID < result.ID) {
+			result, found = job, true
+		}
+	}
+
+	if !found {
+		return models.MintJob{}, fmt.Errorf("no mint job to claim: %w", ErrDoesNotExist)
+	}
+
+	result.LeaseUntil = &leaseUntil
+	result.Attempts++
+	result.Version++
+	result.UpdatedAt = now
+
+	err := m.commit(mintJobChange{Kind: changePut, Job: result})
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not claim mint job: %w", err)
+	}
+
+	return result, nil
+}
+
+// Update a given mint job, and returns it as stored.
+func (m *MemoryMintJob) Update(_ context.Context, job models.MintJob) (models.MintJob, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existing, found := m.items[job.ID]
+	if !found {
+		return models.MintJob{}, ErrDoesNotExist
+	}
+
+	if job.Version != existing.Version {
+		return models.MintJob{}, ErrVersionMismatch
+	}
+
+	job.CreatedAt = existing.CreatedAt
+	job.UpdatedAt = time.Now()
+	job.Version = existing.Version + 1
+
+	err := m.commit(mintJobChange{Kind: changePut, Job: job})
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not update mint job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Close does nothing; there are no resources to release.
+func (m *MemoryMintJob) Close(_ context.Context) error {
+	return nil
+}
+
+// commit persists (when needed) and applies the given changes.
+//
+// Callers must hold the write lock.
+func (m *MemoryMintJob) commit(changes ...mintJobChange) error {
+	if m.persist != nil {
+		err := m.persist(changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		m.apply(change)
+	}
+
+	return nil
+}
+
+// apply a change to the in-memory state, without persisting it.
+func (m *MemoryMintJob) apply(change mintJobChange) {
+	switch change.Kind {
+	case changePut:
+		m.items[change.Job.ID] = change.Job
+	case changeRemove:
+		delete(m.items, change.Job.ID)
+	}
+}
+
+// snapshot returns the changes that rebuild the current state from scratch.
+//
+// Callers must hold (at least) the read lock.
+func (m *MemoryMintJob) snapshot() []mintJobChange {
+	result := make([]mintJobChange, 0, len(m.items))
+
+	for _, job := range m.items {
+		result = append(result, mintJobChange{Kind: changePut, Job: job})
+	}
+
+	return result
+}
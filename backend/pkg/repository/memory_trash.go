@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Trash returns a page of the data items that were deleted.
+func (m *Memory) Trash(_ context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
+	query, err := trashListing.query(opts)
+	if err != nil {
+		return types.Page[models.Data]{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := time.Now()
+	result := make([]models.Data, 0)
+
+	for _, item := range m.items {
+		if item.Namespace == m.namespace && item.DeletedAt.Valid && !item.IsExpired(now) {
+			result = append(result, item)
+		}
+	}
+
+	return query.page(result), nil
+}
+
+// TrashedByID returns the deleted data item with a given ID.
+func (m *Memory) TrashedByID(_ context.Context, itemID string) (models.Data, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result, found := m.items[m.key(itemID)]
+	if !found || !result.DeletedAt.Valid || result.IsExpired(time.Now()) {
+		return models.Data{}, fmt.Errorf("could not find deleted data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// Restore a deleted data item, and returns it as stored.
+//
+// Bumps the Version field and records the new revision.
+func (m *Memory) Restore(_ context.Context, itemID string) (models.Data, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+
+	existing, found := m.items[m.key(itemID)]
+	if !found || !existing.DeletedAt.Valid || existing.IsExpired(now) {
+		return models.Data{}, fmt.Errorf("could not find deleted data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
+
+	existing.UpdatedAt = now
+	existing.DeletedAt = gorm.DeletedAt{}
+	existing.Version++
+
+	err := m.commit(m.write(existing)...)
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not restore data item %q: %w", itemID, err)
+	}
+
+	return existing, nil
+}
+
+// Purge deletes for good a data item, deleted or not, along with its revisions.
+func (m *Memory) Purge(_ context.Context, itemID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.items[m.key(itemID)]; !found {
+		return fmt.Errorf("could not find data item with ID %q: %w", itemID, ErrDoesNotExist)
+	}
+
+	changes := []dataChange{{Kind: changeRemove, Data: models.Data{Namespace: m.namespace, ID: itemID}}}
+
+	for _, revision := range m.history[m.key(itemID)] {
+		changes = append(changes, dataChange{Kind: changeForget, Revision: &revision})
+	}
+
+	err := m.commit(changes...)
+	if err != nil {
+		return fmt.Errorf("could not purge data item %q: %w", itemID, err)
+	}
+
+	return nil
+}
+
+// PurgeDeleted deletes for good (at most limit) data items that were deleted before a given moment, in every
+// namespace.
+//
+// Returns how many were deleted.
+func (m *Memory) PurgeDeleted(_ context.Context, before time.Time, limit int) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changes := make([]dataChange, 0, limit)
+
+	for _, item := range m.items {
+		if len(changes) == limit {
+			break
+		}
+
+		if item.DeletedAt.Valid && !item.DeletedAt.Time.After(before) {
+			changes = append(changes, dataChange{Kind: changeRemove, Data: models.Data{Namespace: item.Namespace, ID: item.ID}})
+		}
+	}
+
+	err := m.commit(changes...)
+	if err != nil {
+		return 0, fmt.Errorf("could not purge deleted data items: %w", err)
+	}
+
+	return int64(len(changes)), nil
+}
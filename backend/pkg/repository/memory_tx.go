@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+// dataUndo holds what a change overwrote, so that it can be put back.
+type dataUndo struct {
+	change dataChange
+	// item is the data item the change targets, when found is true.
+	item models.Data
+	// revision is the revision the change targets, when found is true.
+	revision models.DataVersion
+	found    bool
+}
+
+// memoryTx collects the changes done through the view handed out by Memory.Transaction.
+type memoryTx struct {
+	view    *Memory
+	changes []dataChange
+	undo    []dataUndo
+}
+
+// Transaction runs fn against a view of the repository, and keeps its writes only when fn returns nil.
+//
+// The view shares the state of the repository, so fn sees its own writes, and holds the write lock until fn returns.
+// Once fn succeeds, its writes are committed as one change set, so File persists them as a single record.
+func (m *Memory) Transaction(_ context.Context, fn func(tx DataStore) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tx := &memoryTx{}
+	tx.view = &Memory{
+		memoryState: &memoryState{
+			items:     m.items,
+			history:   m.history,
+			revisions: m.revisions,
+			persist:   tx.stage,
+		},
+		namespace: m.namespace,
+	}
+
+	err := fn(tx.view)
+
+	// the changes are taken back either way: when fn succeeds, commit applies them again, after persisting them
+	tx.rollback()
+
+	if err != nil {
+		return err
+	}
+
+	err = m.commit(tx.changes...)
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// stage is the persist hook of the view: it collects the changes, and what they overwrite.
+func (tx *memoryTx) stage(changes []dataChange) error {
+	for _, change := range changes {
+		undo := dataUndo{change: change}
+
+		switch change.Kind {
+		case changePut, changeRemove:
+			undo.item, undo.found = tx.view.items[keyOf(change.Data)]
+		case changeRevision, changeForget:
+			undo.revision, undo.found = tx.view.revision(revisionKey(*change.Revision), change.Revision.Version)
+		}
+
+		tx.undo = append(tx.undo, undo)
+	}
+
+	tx.changes = append(tx.changes, changes...)
+
+	return nil
+}
+
+// rollback puts back what the staged changes overwrote, newest first.
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		undo := tx.undo[i]
+
+		switch undo.change.Kind {
+		case changePut, changeRemove:
+			if undo.found {
+				tx.view.items[keyOf(undo.change.Data)] = undo.item
+			} else {
+				delete(tx.view.items, keyOf(undo.change.Data))
+			}
+		case changeRevision, changeForget:
+			if undo.found {
+				tx.view.record(undo.revision)
+			} else {
+				tx.view.forget(revisionKey(*undo.change.Revision), undo.change.Revision.Version)
+			}
+		}
+	}
+}
+
+// revision returns a given revision of the data item with a given key, and whether it was found.
+//
+// Callers must hold (at least) the read lock.
+func (m *Memory) revision(key dataKey, version int64) (models.DataVersion, bool) {
+	revisions := m.history[key]
+
+	position, found := sort.Find(len(revisions), func(i int) int {
+		return compareValues(version, revisions[i].Version)
+	})
+	if !found {
+		return models.DataVersion{}, false
+	}
+
+	return revisions[position], true
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// claimable is the condition of the jobs a worker can claim.
+const claimable = "state NOT IN ? AND next_attempt_at <= ? AND (lease_until IS NULL OR lease_until <= ?)"
+
+// finishedStates are the states of the jobs that are done with.
+var finishedStates = []models.JobState{models.JobDone, models.JobFailed}
+
+// MintJob models the DB operations available for mint jobs.
+//
+// Claims lock the claimed row, skipping the ones locked by other claims, so that concurrent workers (of this or
+// other processes) never take the same job.
+type MintJob struct {
+	db *gorm.DB
+}
+
+// NewMintJob builds a new MintJob repository.
+//
+// The schema is not created here; it must be migrated beforehand (see package migrations). When silent is true, it will
+// use a custom logger that does not output anything to the console.
+func NewMintJob(dsn string, silent bool) (*MintJob, error) {
+	result := &MintJob{}
+
+	cfg := &gorm.Config{TranslateError: true}
+	if silent {
+		cfg.Logger = NewNoopLogger()
+	}
+
+	var err error
+
+	result.db, err = gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not open MintJob DB: %w", err)
+	}
+
+	return result, nil
+}
+
+// NewMintJobTruncate builds a new MintJob repo, and deletes its previous contents.
+//
+// Meant to be used in tests.
+func NewMintJobTruncate(dsn string, silent bool) (*MintJob, error) {
+	result, err := NewMintJob(dsn, silent)
+
+	if err == nil {
+		success := result.db.Exec("TRUNCATE TABLE mint_jobs;")
+		if success.Error != nil {
+			return nil, fmt.Errorf("could not truncate: %w", success.Error)
+		}
+	}
+
+	return result, err
+}
+
+// Create a new mint job.
+func (m *MintJob) Create(ctx context.Context, job models.MintJob) (models.MintJob, error) {
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	job.Version = 1
+
+	success := m.db.WithContext(ctx).Create(&job)
+	if errors.Is(success.Error, gorm.ErrDuplicatedKey) {
+		return models.MintJob{}, fmt.Errorf("could not create mint job %q: %w", job.ID, ErrAlreadyExists)
+	}
+
+	if success.Error != nil {
+		return models.MintJob{}, fmt.Errorf("could not create mint job: %w", success.Error)
+	}
+
+	return job, nil
+}
+
+// ByID returns the mint job with a given ID.
+func (m *MintJob) ByID(ctx context.Context, jobID string) (models.MintJob, error) {
+	var result models.MintJob
+
+	success := m.db.WithContext(ctx).First(&result, "id = ?", jobID)
+	if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+		return models.MintJob{}, fmt.Errorf("could not find mint job with ID %q: %w", jobID, ErrDoesNotExist)
+	}
+
+	if success.Error != nil {
+		return models.MintJob{}, fmt.Errorf("could not find mint job with ID %q: %w", jobID, success.Error)
+	}
+
+	return result, nil
+}
+
+// Claim leases the oldest claimable job until a given moment, and counts an attempt.
+func (m *MintJob) Claim(ctx context.Context, now time.Time, leaseUntil time.Time) (models.MintJob, error) {
+	var result models.MintJob
+
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		success := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(claimable, finishedStates, now, now).
+			Order("next_attempt_at, id").
+			First(&result)
+		if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+			return ErrDoesNotExist
+		}
+
+		if success.Error != nil {
+			return success.Error
+		}
+
+		result.LeaseUntil = &leaseUntil
+		result.Attempts++
+		result.Version++
+		result.UpdatedAt = now
+
+		return tx.Save(&result).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) {
+		return models.MintJob{}, fmt.Errorf("no mint job to claim: %w", err)
+	}
+
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not claim mint job: %w", err)
+	}
+
+	return result, nil
+}
+
+// Update a given mint job, and returns it as stored.
+func (m *MintJob) Update(ctx context.Context, job models.MintJob) (models.MintJob, error) {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.MintJob
+
+		success := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "id = ?", job.ID)
+		if errors.Is(success.Error, gorm.ErrRecordNotFound) {
+			return ErrDoesNotExist
+		}
+
+		if success.Error != nil {
+			return success.Error
+		}
+
+		if job.Version != existing.Version {
+			return ErrVersionMismatch
+		}
+
+		job.CreatedAt = existing.CreatedAt
+		job.UpdatedAt = time.Now()
+		job.Version = existing.Version + 1
+
+		return tx.Save(&job).Error
+	})
+	if errors.Is(err, ErrDoesNotExist) || errors.Is(err, ErrVersionMismatch) {
+		return models.MintJob{}, err
+	}
+
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not update mint job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Close closes the DB connection.
+func (m *MintJob) Close(ctx context.Context) error {
+	database, err := m.db.WithContext(ctx).DB()
+	if err != nil {
+		return fmt.Errorf("could not get DB: %w", err)
+	}
+
+	err = database.Close()
+	if err != nil {
+		return fmt.Errorf("could not close DB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+func Test_MintJobClaim(t *testing.T) {
+	for name, build := range mintJobBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			assert.NoError(t, err)
+
+			defer func() {
+				err := repo.Close(context.TODO())
+				assert.NoError(t, err)
+			}()
+
+			now := time.Now().UTC().Truncate(time.Millisecond)
+
+			for _, job := range []models.MintJob{
+				{ID: "later", State: models.JobQueued, NextAttemptAt: now.Add(time.Minute)},
+				{ID: "first", State: models.JobQueued, NextAttemptAt: now.Add(-time.Minute), Token: &minting.Token{Name: "A"}},
+				{ID: "second", State: models.JobQueued, NextAttemptAt: now},
+				{ID: "done", State: models.JobDone, NextAttemptAt: now.Add(-time.Hour)},
+			} {
+				_, err = repo.Create(context.TODO(), job)
+				assert.NoError(t, err)
+			}
+
+			_, err = repo.Create(context.TODO(), models.MintJob{ID: "first"})
+			assert.ErrorIs(t, err, ErrAlreadyExists)
+
+			claimed, err := repo.Claim(context.TODO(), now, now.Add(time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, "first", claimed.ID)
+			assert.Equal(t, 1, claimed.Attempts)
+			assert.Equal(t, "A", claimed.Token.Name)
+
+			second, err := repo.Claim(context.TODO(), now, now.Add(time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, "second", second.ID)
+
+			// the others are leased, finished or not due yet
+			_, err = repo.Claim(context.TODO(), now, now.Add(time.Minute))
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			// once its lease ends, a job can be claimed again, and the previous worker can no longer write it
+			again, err := repo.Claim(context.TODO(), now.Add(time.Minute), now.Add(2*time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, "first", again.ID)
+			assert.Equal(t, 2, again.Attempts)
+
+			claimed.State = models.JobFailed
+			_, err = repo.Update(context.TODO(), claimed)
+			assert.ErrorIs(t, err, ErrVersionMismatch)
+
+			again.State = models.JobDone
+			again.LeaseUntil = nil
+
+			updated, err := repo.Update(context.TODO(), again)
+			assert.NoError(t, err)
+			assert.Equal(t, again.Version+1, updated.Version)
+
+			found, err := repo.ByID(context.TODO(), "first")
+			assert.NoError(t, err)
+			assert.Equal(t, models.JobDone, found.State)
+			assert.Nil(t, found.LeaseUntil)
+
+			_, err = repo.ByID(context.TODO(), "missing")
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+
+			_, err = repo.Update(context.TODO(), models.MintJob{ID: "missing"})
+			assert.ErrorIs(t, err, ErrDoesNotExist)
+		})
+	}
+}
+
+func Test_FileMintJobReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mint_jobs.log")
+
+	repo, err := NewFileMintJob(path)
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	_, err = repo.Create(context.TODO(), models.MintJob{ID: "a", State: models.JobQueued, NextAttemptAt: now})
+	assert.NoError(t, err)
+
+	claimed, err := repo.Claim(context.TODO(), now, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close(context.TODO()))
+
+	// a restart keeps the lease, so the job is only claimed again once it ends
+	repo, err = NewFileMintJob(path)
+	assert.NoError(t, err)
+
+	defer repo.Close(context.TODO())
+
+	found, err := repo.ByID(context.TODO(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, claimed.Version, found.Version)
+
+	_, err = repo.Claim(context.TODO(), now, now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrDoesNotExist)
+
+	_, err = repo.Claim(context.TODO(), now.Add(time.Minute), now.Add(2*time.Minute))
+	assert.NoError(t, err)
+}
+
+// mintJobBackends returns builders for every MintJobStore implementation that can be tested in this environment.
+func mintJobBackends(t *testing.T) map[string]func() (MintJobStore, error) {
+	t.Helper()
+
+	result := map[string]func() (MintJobStore, error){
+		"memory": func() (MintJobStore, error) {
+			return NewMemoryMintJob(), nil
+		},
+		"file": func() (MintJobStore, error) {
+			return NewFileMintJob(filepath.Join(t.TempDir(), "mint_jobs.log"))
+		},
+	}
+
+	if dsn := os.Getenv(testDSNVariable); dsn != "" {
+		result["postgres"] = func() (MintJobStore, error) {
+			err := migrateTestDB(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewMintJobTruncate(dsn, true)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/geo"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// DataStore models the operations available for (key, value) data items, regardless of the storage behind them.
+//
+// Expired items are invisible to all the methods but PurgeExpired and Purge, and their IDs can be reused. Deleted
+// items go to the trash, until they are restored, purged, or replaced by a new item with the same ID. Every write
+// records a revision of the item, numbered by its Version field.
+//
+// Items live in namespaces, each with its own IDs. A repository works in types.DefaultNamespace, and In returns views
+// working in the others; the methods purging or pruning for the reaper work across all of them.
+type DataStore interface {
+	// In returns a view of the repository working in a given namespace. It is valid as long as the repository is.
+	In(namespace string) DataStore
+	// Usage returns the number of data items that were not deleted and did not expire, and the size of their IDs and
+	// values. Inside a transaction, it also keeps the other transactions that call it in the same namespace waiting
+	// until this one ends, so that they can check a quota and write as one.
+	Usage(ctx context.Context) (types.Usage, error)
+	// GetAll returns all data items that were not deleted.
+	GetAll(ctx context.Context) ([]models.Data, error)
+	// List returns a page of the data items that were not deleted.
+	List(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error)
+	// Create a new data item. Sets the CreatedAt, UpdatedAt and Version fields. Returns ErrAlreadyExists when the ID
+	// is taken by an item that was not deleted.
+	Create(ctx context.Context, item models.Data) (models.Data, error)
+	// Update a given data item, and returns it as stored. Returns ErrDoesNotExist when there is nothing to update.
+	//
+	// When item.Version is not zero, it is the version the item must still be at, or ErrVersionMismatch is returned.
+	Update(ctx context.Context, item models.Data) (models.Data, error)
+	// ByID returns the data item with a given ID. Wraps ErrDoesNotExist when there is no such item.
+	ByID(ctx context.Context, itemID string) (models.Data, error)
+	// Delete (soft) a given data item. Returns ErrDoesNotExist when there is nothing to delete.
+	//
+	// When version is not zero, it is the version the item must still be at, or ErrVersionMismatch is returned.
+	Delete(ctx context.Context, dataID string, version int64) error
+	// PurgeExpired deletes for good (at most limit) data items that expired before a given moment.
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+	// Trash returns a page of the data items that were deleted.
+	Trash(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error)
+	// TrashedByID returns the deleted data item with a given ID. Wraps ErrDoesNotExist when it is not in the trash.
+	TrashedByID(ctx context.Context, itemID string) (models.Data, error)
+	// Restore a deleted data item, and returns it as stored. Returns ErrDoesNotExist when it is not in the trash.
+	Restore(ctx context.Context, itemID string) (models.Data, error)
+	// Purge deletes for good a data item, deleted or not, along with its revisions. Returns ErrDoesNotExist when
+	// there is nothing to purge.
+	Purge(ctx context.Context, itemID string) error
+	// PurgeDeleted deletes for good (at most limit) data items that were deleted before a given moment. Their
+	// revisions are left to PruneHistory.
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error)
+	// History returns a page of the revisions of a data item, including the ones written by deletes.
+	History(ctx context.Context, itemID string, opts types.ListOptions) (types.Page[models.DataVersion], error)
+	// Revision returns a given revision of a data item. Wraps ErrDoesNotExist when there is no such revision.
+	Revision(ctx context.Context, itemID string, version int64) (models.DataVersion, error)
+	// RevisionAt returns the revision of a data item that was the latest one at a given moment.
+	RevisionAt(ctx context.Context, itemID string, at time.Time) (models.DataVersion, error)
+	// PruneHistory deletes (at most limit) revisions beyond the newest keep ones of each data item (when keep is
+	// positive) or written before a given moment, always keeping the latest one.
+	PruneHistory(ctx context.Context, keep int, before time.Time, limit int) (int64, error)
+	// Transaction runs fn against a view of the repository, and keeps the writes done through it all or nothing: they
+	// are kept only when fn returns nil. Returns the error of fn as is.
+	//
+	// The view is only valid while fn runs, and fn must not use the repository itself meanwhile.
+	Transaction(ctx context.Context, fn func(tx DataStore) error) error
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+// LocationStore models the operations available for locations, regardless of the storage behind them.
+type LocationStore interface {
+	// GetAll returns all locations.
+	GetAll(ctx context.Context) ([]models.Location, error)
+	// List returns a page of locations.
+	List(ctx context.Context, opts types.ListOptions) (types.Page[models.Location], error)
+	// Create a new location. A zero ID is replaced with the next available one.
+	Create(ctx context.Context, item models.Location) (models.Location, error)
+	// Update a given location. Returns ErrDoesNotExist when there is nothing to update.
+	Update(ctx context.Context, item models.Location) error
+	// ByID returns the location with a given ID. Wraps ErrDoesNotExist when there is no such location.
+	ByID(ctx context.Context, itemID int) (models.Location, error)
+	// Delete a given location. Returns ErrDoesNotExist when there is nothing to delete.
+	Delete(ctx context.Context, locationID int) error
+	// InBox returns a page of the locations inside a bounding box.
+	InBox(ctx context.Context, box geo.BBox, opts types.ListOptions) (types.Page[models.Location], error)
+	// Near returns a page of the locations within radius meters of a point, with their distance, closest first.
+	Near(
+		ctx context.Context,
+		center geo.Point,
+		radius float64,
+		opts types.ListOptions,
+	) (types.Page[models.LocationDistance], error)
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+// MintJobStore models the operations available for mint jobs, regardless of the storage behind them.
+//
+// Workers take jobs with Claim, which leases them: a job is held by a single worker until its lease ends, and the
+// Version field keeps a worker that outlived its lease from overwriting the job.
+type MintJobStore interface {
+	// Create a new mint job. Sets the CreatedAt, UpdatedAt and Version fields. Returns ErrAlreadyExists when the ID
+	// is taken.
+	Create(ctx context.Context, job models.MintJob) (models.MintJob, error)
+	// ByID returns the mint job with a given ID. Wraps ErrDoesNotExist when there is no such job.
+	ByID(ctx context.Context, jobID string) (models.MintJob, error)
+	// Claim leases, until leaseUntil, the job that is due the earliest among the ones that are not finished, are due
+	// at now, and are not leased; it counts an attempt. Wraps ErrDoesNotExist when there is no such job.
+	Claim(ctx context.Context, now time.Time, leaseUntil time.Time) (models.MintJob, error)
+	// Update a given mint job, and returns it as stored. Returns ErrDoesNotExist when there is nothing to update,
+	// and ErrVersionMismatch when the job was written since job.Version (i.e.: claimed again).
+	Update(ctx context.Context, job models.MintJob) (models.MintJob, error)
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+// IdempotencyStore models the operations available for idempotency records, regardless of the storage behind them.
+//
+// A record is identified by its key and its CreatedAt field, so that a request that outlived its record cannot
+// complete (or release) the one of another request with the same key.
+type IdempotencyStore interface {
+	// Begin creates a record, created at now, unless one with the same key has not expired at now: then, it returns
+	// that one along with ErrAlreadyExists.
+	Begin(ctx context.Context, record models.IdempotencyRecord, now time.Time) (models.IdempotencyRecord, error)
+	// Complete replaces a record that is in flight with its response. Returns ErrDoesNotExist when there is no such
+	// record.
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+	// Release deletes a record, so that its key can be used again. Returns ErrDoesNotExist when there is no such
+	// record.
+	Release(ctx context.Context, record models.IdempotencyRecord) error
+	// PurgeExpired deletes for good (at most limit) records that expired before a given moment.
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+// ClaimStore models the operations available for claims, regardless of the storage behind them.
+type ClaimStore interface {
+	// Create a new claim. Sets the CreatedAt field. Returns ErrAlreadyExists when the ID is taken, or when the wallet
+	// already claimed the location.
+	Create(ctx context.Context, claim models.Claim) (models.Claim, error)
+	// Find returns the claim of a location by a wallet. Wraps ErrDoesNotExist when there is none.
+	Find(ctx context.Context, locationID int, wallet string) (models.Claim, error)
+	// List returns a page of claims; they can be filtered by location_id and wallet.
+	List(ctx context.Context, opts types.ListOptions) (types.Page[models.Claim], error)
+	// Delete the claim with a given ID. Wraps ErrDoesNotExist when there is none.
+	Delete(ctx context.Context, claimID string) error
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+// APIKeyStore models the operations available for API keys, regardless of the storage behind them.
+type APIKeyStore interface {
+	// Create a new API key. Sets the CreatedAt field. Returns ErrAlreadyExists when the ID is taken.
+	Create(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	// ByID returns the API key with a given ID. Wraps ErrDoesNotExist when there is no such key.
+	ByID(ctx context.Context, keyID string) (models.APIKey, error)
+	// List returns a page of API keys; they can be filtered by name.
+	List(ctx context.Context, opts types.ListOptions) (types.Page[models.APIKey], error)
+	// Revoke the API key with a given ID, at a given moment, and returns it; a key that was revoked already is left
+	// as it is. Wraps ErrDoesNotExist when there is no such key.
+	Revoke(ctx context.Context, keyID string, at time.Time) (models.APIKey, error)
+	// Close releases the underlying resources.
+	Close(ctx context.Context) error
+}
+
+var (
+	_ DataStore     = (*Store)(nil)
+	_ DataStore     = (*Memory)(nil)
+	_ DataStore     = (*File)(nil)
+	_ LocationStore = (*Location)(nil)
+	_ LocationStore = (*MemoryLocation)(nil)
+	_ LocationStore = (*FileLocation)(nil)
+	_ MintJobStore  = (*MintJob)(nil)
+	_ MintJobStore  = (*MemoryMintJob)(nil)
+	_ MintJobStore  = (*FileMintJob)(nil)
+
+	_ IdempotencyStore = (*Idempotency)(nil)
+	_ IdempotencyStore = (*MemoryIdempotency)(nil)
+	_ IdempotencyStore = (*FileIdempotency)(nil)
+
+	_ ClaimStore = (*Claim)(nil)
+	_ ClaimStore = (*MemoryClaim)(nil)
+	_ ClaimStore = (*FileClaim)(nil)
+
+	_ APIKeyStore = (*APIKey)(nil)
+	_ APIKeyStore = (*MemoryAPIKey)(nil)
+	_ APIKeyStore = (*FileAPIKey)(nil)
+)
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// author returns the ID of the caller of a request, which writes are attributed to; empty when unknown.
+func author(ctx context.Context) string {
+	principal, _ := types.PrincipalFrom(ctx)
+
+	return principal.ID
+}
+
+// viewer returns the ID of the caller of a request, whose listings only show what it may read; empty when the caller
+// is unknown or an admin, who see everything.
+func viewer(ctx context.Context) string {
+	principal, found := types.PrincipalFrom(ctx)
+	if !found || principal.HasScope(types.ScopeAdmin) {
+		return ""
+	}
+
+	return principal.ID
+}
+
+// permit returns nil when the caller of a request has an access to an item with a given owner and grants.
+//
+// Callers that may not even read the item are told it does not exist, with repository.ErrDoesNotExist, so that they
+// cannot probe for it; callers that may only read it get types.ErrForbidden. Unknown callers, when authentication is
+// disabled, and admins may do anything.
+func permit(ctx context.Context, owner string, grants models.Grants, access string) error {
+	principal, found := types.PrincipalFrom(ctx)
+	if !found || principal.HasScope(types.ScopeAdmin) || models.Allows(owner, grants, principal.ID, access) {
+		return nil
+	}
+
+	if !models.Allows(owner, grants, principal.ID, models.AccessRead) {
+		return repository.ErrDoesNotExist
+	}
+
+	return fmt.Errorf("%w: %s access required", types.ErrForbidden, access)
+}
+
+// own returns types.ErrForbidden unless the caller of a request may share an item with a given owner: when it owns
+// the item, or is an admin.
+func own(ctx context.Context, owner string) error {
+	principal, found := types.PrincipalFrom(ctx)
+	if !found || principal.HasScope(types.ScopeAdmin) || (owner != "" && owner == principal.ID) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: only the owner may share", types.ErrForbidden)
+}
+
+// share returns a copy of grants, with an access granted to a principal, or taken back when access is empty. Grants
+// are copied since stored items may share them.
+func share(grants models.Grants, principalID string, access string) models.Grants {
+	result := maps.Clone(grants)
+	if result == nil {
+		result = models.Grants{}
+	}
+
+	if access == "" {
+		delete(result, principalID)
+	} else {
+		result[principalID] = access
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_DataAccess(t *testing.T) {
+	background := context.Background()
+	owner := types.WithPrincipal(background, types.Principal{ID: "owner"})
+	reader := types.WithPrincipal(background, types.Principal{ID: "reader"})
+	stranger := types.WithPrincipal(background, types.Principal{ID: "stranger"})
+	admin := types.WithPrincipal(background, types.Principal{ID: "root", Scopes: []types.Scope{types.ScopeAdmin}})
+
+	data := New(background, repository.NewMemory(), Quotas{})
+
+	created, err := data.Add(owner, types.Pair{Key: "private", Value: "v1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", created.Owner)
+
+	_, err = data.Add(background, types.Pair{Key: "public", Value: "v1"})
+	assert.NoError(t, err)
+
+	_, err = data.Share(stranger, "private", "stranger", models.AccessWrite)
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist, "strangers should not learn that the key exists")
+
+	_, err = data.Share(owner, "private", "reader", "admin")
+	assert.ErrorIs(t, err, types.ErrInvalidInput)
+
+	shared, err := data.Share(owner, "private", "reader", models.AccessRead)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Grants{"reader": models.AccessRead}, shared.Grants)
+
+	_, err = data.Share(reader, "private", "reader", models.AccessWrite)
+	assert.ErrorIs(t, err, types.ErrForbidden)
+
+	// reads
+	_, err = data.Get(reader, "private")
+	assert.NoError(t, err)
+
+	_, err = data.Get(stranger, "private")
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	_, err = data.History(stranger, "private", types.ListOptions{})
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	for ctx, expected := range map[context.Context][]string{
+		reader:     {"private", "public"},
+		stranger:   {"public"},
+		admin:      {"private", "public"},
+		background: {"private", "public"},
+	} {
+		page, err := data.GetAll(ctx, types.ListOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, keys(page.Items))
+	}
+
+	// writes
+	_, err = data.Update(reader, types.Pair{Key: "private", Value: "v2"}, types.Precondition{})
+	assert.ErrorIs(t, err, types.ErrForbidden)
+
+	_, err = data.Update(stranger, types.Pair{Key: "private", Value: "v2"}, types.Precondition{})
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	assert.ErrorIs(t, data.Delete(reader, "private", types.Precondition{}), types.ErrForbidden)
+	assert.ErrorIs(t, data.Revert(reader, "private", 1), types.ErrForbidden)
+
+	_, err = data.Share(owner, "private", "reader", models.AccessWrite)
+	assert.NoError(t, err)
+
+	updated, err := data.Update(reader, types.Pair{Key: "private", Value: "v2"}, types.Precondition{})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", updated.Owner, "writes should not change the owner")
+	assert.Equal(t, models.Grants{"reader": models.AccessWrite}, updated.Grants)
+
+	_, err = data.Update(stranger, types.Pair{Key: "public", Value: "v2"}, types.Precondition{})
+	assert.NoError(t, err, "pairs without an owner are open to everyone")
+
+	// the trash
+	assert.NoError(t, data.Delete(owner, "private", types.Precondition{}))
+
+	_, err = data.Add(stranger, types.Pair{Key: "private", Value: "mine"})
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists, "strangers should not take over a key in the trash")
+
+	_, err = data.Restore(stranger, "private")
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	page, err := data.Trash(stranger, types.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	restored, err := data.Restore(reader, "private")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", restored.Owner)
+
+	_, err = data.Unshare(owner, "private", "reader")
+	assert.NoError(t, err)
+
+	_, err = data.Get(reader, "private")
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	assert.NoError(t, data.Purge(admin, "private", types.Precondition{}))
+}
+
+func Test_LocationAccess(t *testing.T) {
+	background := context.Background()
+	owner := types.WithPrincipal(background, types.Principal{ID: "owner"})
+	editor := types.WithPrincipal(background, types.Principal{ID: "editor"})
+
+	locations, err := NewLocation(background, repository.NewMemoryLocation(), blob.NewMemory())
+	assert.NoError(t, err)
+
+	name, latitude, longitude := "Old Town", float32(44.43), float32(26.1)
+
+	created, err := locations.Add(owner, types.LocationInput{Location: &name, Latitude: &latitude, Longitude: &longitude})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", created.Owner)
+
+	_, err = locations.Get(editor, created.ID)
+	assert.NoError(t, err, "locations should be readable by everyone")
+
+	_, err = locations.Patch(editor, created.ID, types.LocationInput{Location: &name})
+	assert.ErrorIs(t, err, types.ErrForbidden)
+	assert.ErrorIs(t, locations.Delete(editor, created.ID), types.ErrForbidden)
+
+	result, err := locations.Import(editor, types.LocationInput{
+		ID: &created.ID, Location: &name, Latitude: &latitude, Longitude: &longitude,
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ImportForbidden, result.Outcome)
+
+	_, err = locations.Share(owner, created.ID, "editor", models.AccessRead)
+	assert.ErrorIs(t, err, types.ErrInvalidInput, "there is no read access to grant")
+
+	_, err = locations.Share(editor, created.ID, "editor", models.AccessWrite)
+	assert.ErrorIs(t, err, types.ErrForbidden)
+
+	_, err = locations.Share(owner, created.ID, "editor", models.AccessWrite)
+	assert.NoError(t, err)
+
+	patched, err := locations.Replace(editor, created.ID, types.LocationInput{
+		Location: &name, Latitude: &latitude, Longitude: &longitude,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", patched.Owner, "writes should not change the owner")
+	assert.Equal(t, models.Grants{"editor": models.AccessWrite}, patched.Grants)
+}
+
+// keys returns the IDs of key-value pairs.
+func keys(items []models.Data) []string {
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+
+	return result
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// apiKeyPrefix starts every API key, so that leaked ones are easy to spot.
+	apiKeyPrefix = "nmls_"
+	// adminPrincipal is the name of the callers using the admin key of the configs, and adminPrincipalID their ID.
+	adminPrincipal   = "admin"
+	adminPrincipalID = "config:" + adminPrincipal
+	// apiKeyPrincipalPrefix starts the IDs of the callers using an issued key, followed by the ID of the key, so that
+	// they cannot be mistaken for the callers of another source (see tokenPrincipalPrefix).
+	apiKeyPrincipalPrefix = "key:"
+)
+
+// ErrInvalidAPIKey for when an API key is malformed, unknown or revoked.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey offers API key-related functionality: issuing keys, and authenticating callers with them.
+//
+// A key reads nmls_<ID>_<secret>; only the SHA-256 of the secret is stored, so keys cannot be recovered from the DB.
+// Secrets are random, hence a plain hash is enough to keep them safe.
+type APIKey struct {
+	db        repository.APIKeyStore
+	serverCtx context.Context
+	// adminKey, when set, is a key granting ScopeAdmin that is not stored, to issue the first keys with.
+	adminKey string
+}
+
+// NewAPIKey builds a new API key service; adminKey (when not empty) is a key granting ScopeAdmin.
+func NewAPIKey(ctx context.Context, db repository.APIKeyStore, adminKey string) *APIKey {
+	return &APIKey{
+		db:        db,
+		serverCtx: ctx,
+		adminKey:  adminKey,
+	}
+}
+
+// Issue a new API key, and returns it along with the key to authenticate with, which cannot be found again.
+//
+// Returns a types.ValidationError when the input is invalid.
+func (a *APIKey) Issue(ctx context.Context, input types.APIKeyInput) (models.APIKey, string, error) {
+	if a.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.APIKey{}, "", types.ErrCancelledContext
+	}
+
+	err := input.Validate()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("could not generate API key ID: %w", err)
+	}
+
+	secret, err := newID()
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("could not generate API key secret: %w", err)
+	}
+
+	key := models.APIKey{
+		ID:     id,
+		Name:   strings.TrimSpace(input.Name),
+		Hash:   hashSecret(secret),
+		Scopes: make([]string, 0, len(input.Scopes)),
+	}
+
+	for _, scope := range input.Scopes {
+		key.Scopes = append(key.Scopes, string(scope))
+	}
+
+	result, err := a.db.Create(ctx, key)
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("could not create API key: %w", err)
+	}
+
+	return result, apiKeyPrefix + id + "_" + secret, nil
+}
+
+// List returns a page of API keys.
+func (a *APIKey) List(ctx context.Context, opts types.ListOptions) (types.Page[models.APIKey], error) {
+	if a.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.APIKey]{}, types.ErrCancelledContext
+	}
+
+	result, err := a.db.List(ctx, opts)
+	if err != nil {
+		return types.Page[models.APIKey]{}, fmt.Errorf("could not retrieve API keys: %w", err)
+	}
+
+	return result, nil
+}
+
+// Revoke the API key with a given ID: it can no longer be used, at once.
+func (a *APIKey) Revoke(ctx context.Context, keyID string) (models.APIKey, error) {
+	if a.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.APIKey{}, types.ErrCancelledContext
+	}
+
+	result, err := a.db.Revoke(ctx, keyID, time.Now())
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("could not revoke API key: %w", err)
+	}
+
+	return result, nil
+}
+
+// Authenticate returns the caller using a given key. Returns ErrInvalidAPIKey when the key is malformed, unknown or
+// revoked.
+func (a *APIKey) Authenticate(ctx context.Context, key string) (types.Principal, error) {
+	if a.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Principal{}, types.ErrCancelledContext
+	}
+
+	if a.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(a.adminKey)) == 1 {
+		return types.Principal{ID: adminPrincipalID, Name: adminPrincipal, Scopes: []types.Scope{types.ScopeAdmin}}, nil
+	}
+
+	id, secret, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !found || !strings.HasPrefix(key, apiKeyPrefix) {
+		return types.Principal{}, ErrInvalidAPIKey
+	}
+
+	stored, err := a.db.ByID(ctx, id)
+	if errors.Is(err, repository.ErrDoesNotExist) {
+		return types.Principal{}, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return types.Principal{}, fmt.Errorf("could not find API key: %w", err)
+	}
+
+	if stored.IsRevoked() || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(stored.Hash)) != 1 {
+		return types.Principal{}, ErrInvalidAPIKey
+	}
+
+	result := types.Principal{
+		ID:     apiKeyPrincipalPrefix + stored.ID,
+		Name:   stored.Name,
+		Scopes: make([]types.Scope, 0, len(stored.Scopes)),
+	}
+
+	for _, scope := range stored.Scopes {
+		result.Scopes = append(result.Scopes, types.Scope(scope))
+	}
+
+	return result, nil
+}
+
+// hashSecret returns the SHA-256 of the secret of an API key, hex-encoded.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
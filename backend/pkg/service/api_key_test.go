@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := NewAPIKey(ctx, repository.NewMemoryAPIKey(), "")
+
+	issued, key, err := keys.Issue(ctx, types.APIKeyInput{Name: "reader", Scopes: []types.Scope{types.ScopeDataRead}})
+	assert.NoError(t, err)
+	assert.NotContains(t, issued.Hash, key[len(apiKeyPrefix)+len(issued.ID)+1:], "the secret is not stored")
+
+	_, _, err = keys.Issue(ctx, types.APIKeyInput{Name: " ", Scopes: []types.Scope{"data:delete"}})
+	assert.ErrorIs(t, err, types.ErrInvalidInput)
+
+	principal, err := keys.Authenticate(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "key:"+issued.ID, principal.ID)
+	assert.True(t, principal.HasScope(types.ScopeDataRead))
+	assert.False(t, principal.HasScope(types.ScopeDataWrite))
+
+	for _, invalid := range []string{"", "nmls_", key + "0", "x" + key, apiKeyPrefix + "missing_secret"} {
+		_, err = keys.Authenticate(ctx, invalid)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, invalid)
+	}
+
+	_, err = keys.Revoke(ctx, issued.ID)
+	assert.NoError(t, err)
+
+	_, err = keys.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey, "revoked keys are refused at once")
+
+	_, err = keys.Revoke(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	admin := NewAPIKey(ctx, repository.NewMemoryAPIKey(), "a-long-enough-admin-key-for-tests")
+
+	principal, err = admin.Authenticate(ctx, "a-long-enough-admin-key-for-tests")
+	assert.NoError(t, err)
+	assert.True(t, principal.HasScope(types.ScopeTokenMint), "admin grants every scope")
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/geo"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// tokenThumbnail is the thumbnail minted in place of the uploaded image of a location, when there is one.
+	tokenThumbnail = "medium"
+	// walletHashCharacters is the number of characters of the hash of the wallet in the names of earned tokens, so
+	// that they fit in 32 characters whatever the ID of the location.
+	walletHashCharacters = 11
+	// claimGrace is how long a claim without a mint job is deemed to be getting one; after that, the job was lost
+	// (i.e.: the process stopped in between), and checking in again enqueues it.
+	claimGrace = time.Minute
+)
+
+var (
+	// ErrTooFar for when a check-in is made from outside the radius of the location.
+	ErrTooFar = errors.New("too far from the location to check in")
+	// ErrAlreadyClaimed for when a wallet checks in at a location it already claimed.
+	ErrAlreadyClaimed = errors.New("this wallet already checked in at the location")
+	// ErrNoTokenImage for when a location has no uploaded image to make a token of.
+	ErrNoTokenImage = errors.New("the location has no uploaded image to mint")
+)
+
+// Checkin offers check-ins at locations: proofs of presence, which earn a token made of the location.
+type Checkin struct {
+	locations *Location
+	tokens    *Token
+	claims    repository.ClaimStore
+	serverCtx context.Context
+	// radius is the check-in radius of the locations that have none, in meters.
+	radius float64
+}
+
+// NewCheckin builds a new check-in service; radius is the check-in radius of the locations that have none, in meters.
+func NewCheckin(
+	ctx context.Context,
+	locations *Location,
+	tokens *Token,
+	claims repository.ClaimStore,
+	radius float64,
+) *Checkin {
+	return &Checkin{
+		locations: locations,
+		tokens:    tokens,
+		claims:    claims,
+		serverCtx: ctx,
+		radius:    radius,
+	}
+}
+
+// CheckIn claims a location for the wallet of the input, and enqueues a job minting the earned token and sending it
+// to the wallet. The token is made of the uploaded image of the location, and of its name and position.
+//
+// Returns a types.ValidationError when the input is invalid, ErrTooFar when its position is outside the radius of
+// the location, ErrAlreadyClaimed when the wallet already claimed the location, and ErrNoTokenImage when there is no
+// image to mint.
+func (c *Checkin) CheckIn(ctx context.Context, locationID int, input types.CheckinInput) (models.Claim, error) {
+	err := c.tokens.check(ctx)
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	err = input.Validate()
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	location, err := c.locations.Get(ctx, locationID)
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	from := geo.Point{Latitude: *input.Latitude, Longitude: *input.Longitude}
+	distance := geo.Distance(position(location), from)
+
+	radius := location.Radius
+	if radius == 0 {
+		radius = c.radius
+	}
+
+	if distance > radius {
+		return models.Claim{}, fmt.Errorf("%w: %.0f meters away, expected at most %.0f", ErrTooFar, distance, radius)
+	}
+
+	token, err := c.token(ctx, location, input.Wallet)
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	claim := models.Claim{
+		LocationID: location.ID,
+		Wallet:     input.Wallet,
+		Latitude:   from.Latitude,
+		Longitude:  from.Longitude,
+		Distance:   distance,
+	}
+
+	claim.ID, err = newID()
+	if err == nil {
+		claim.JobID, err = newID()
+	}
+
+	if err != nil {
+		return models.Claim{}, fmt.Errorf("could not generate claim: %w", err)
+	}
+
+	claim, err = c.claims.Create(ctx, claim)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return c.resume(ctx, location.ID, input.Wallet, token)
+	}
+
+	if err != nil {
+		return models.Claim{}, fmt.Errorf("could not claim location: %w", err)
+	}
+
+	_, err = c.tokens.enqueue(ctx, models.MintJob{ID: claim.JobID, Token: &token, Receiver: claim.Wallet})
+	if err != nil {
+		// without a job, the claim is taken back, so that the check-in can be made again
+		deleteErr := c.claims.Delete(context.WithoutCancel(ctx), claim.ID)
+		if deleteErr != nil {
+			log.Default().Printf("could not take back claim %s: %s", claim.ID, deleteErr)
+		}
+
+		return models.Claim{}, err
+	}
+
+	return claim, nil
+}
+
+// ByLocation returns a page of the claims of a location.
+func (c *Checkin) ByLocation(
+	ctx context.Context,
+	locationID int,
+	opts types.ListOptions,
+) (types.Page[models.Claim], error) {
+	return c.list(ctx, opts, "location_id", strconv.Itoa(locationID))
+}
+
+// ByWallet returns a page of the claims of a wallet.
+func (c *Checkin) ByWallet(
+	ctx context.Context,
+	wallet string,
+	opts types.ListOptions,
+) (types.Page[models.Claim], error) {
+	return c.list(ctx, opts, "wallet", wallet)
+}
+
+// list returns a page of the claims whose field has a given value.
+func (c *Checkin) list(
+	ctx context.Context,
+	opts types.ListOptions,
+	field string,
+	value string,
+) (types.Page[models.Claim], error) {
+	if c.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.Claim]{}, types.ErrCancelledContext
+	}
+
+	filters := make(map[string]string, len(opts.Filters)+1)
+
+	for name, filter := range opts.Filters {
+		filters[name] = filter
+	}
+
+	filters[field] = value
+	opts.Filters = filters
+
+	result, err := c.claims.List(ctx, opts)
+	if err != nil {
+		return types.Page[models.Claim]{}, fmt.Errorf("could not retrieve claims: %w", err)
+	}
+
+	return result, nil
+}
+
+// resume enqueues the lost job of the claim of a location by a wallet, and returns the claim; it returns
+// ErrAlreadyClaimed when the claim has its job, or may still get it.
+func (c *Checkin) resume(
+	ctx context.Context,
+	locationID int,
+	wallet string,
+	token minting.Token,
+) (models.Claim, error) {
+	claim, err := c.claims.Find(ctx, locationID, wallet)
+	if err != nil {
+		return models.Claim{}, fmt.Errorf("could not find claim: %w", err)
+	}
+
+	if time.Since(claim.CreatedAt) < claimGrace {
+		return models.Claim{}, fmt.Errorf("%w: claim %s", ErrAlreadyClaimed, claim.ID)
+	}
+
+	_, err = c.tokens.jobs.ByID(ctx, claim.JobID)
+	if err == nil {
+		return models.Claim{}, fmt.Errorf("%w: claim %s", ErrAlreadyClaimed, claim.ID)
+	}
+
+	if !errors.Is(err, repository.ErrDoesNotExist) {
+		return models.Claim{}, fmt.Errorf("could not find mint job of claim: %w", err)
+	}
+
+	_, err = c.tokens.enqueue(ctx, models.MintJob{ID: claim.JobID, Token: &token, Receiver: claim.Wallet})
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return models.Claim{}, fmt.Errorf("%w: claim %s", ErrAlreadyClaimed, claim.ID)
+	}
+
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	return claim, nil
+}
+
+// token returns the token earned by a wallet at a location: the uploaded image of the location (or its medium
+// thumbnail), named after the location and the wallet, with the name and the position of the location as metadata.
+func (c *Checkin) token(ctx context.Context, location models.Location, wallet string) (minting.Token, error) {
+	hash, isBlob := strings.CutPrefix(location.Image, types.BlobURLPrefix)
+	if !isBlob {
+		return minting.Token{}, fmt.Errorf("%w: location %d", ErrNoTokenImage, location.ID)
+	}
+
+	if thumbnail, found := strings.CutPrefix(location.Thumbnails[tokenThumbnail], types.BlobURLPrefix); found {
+		hash = thumbnail
+	}
+
+	image, err := c.locations.Blob(ctx, hash)
+	if err != nil {
+		return minting.Token{}, fmt.Errorf("could not read image of location: %w", err)
+	}
+
+	defer image.Close()
+
+	content, err := io.ReadAll(image)
+	if err != nil {
+		return minting.Token{}, fmt.Errorf("could not read image of location: %w", err)
+	}
+
+	walletHash := sha256.Sum256([]byte(wallet))
+
+	input := types.TokenInput{
+		Tokenname:      fmt.Sprintf("L%dW%s", location.ID, hex.EncodeToString(walletHash[:])[:walletHashCharacters]),
+		Displayname:    location.Location,
+		Description:    "Checked in at " + location.Location,
+		FileFromBase64: base64.StdEncoding.EncodeToString(content),
+		Receiver:       wallet,
+	}
+
+	result, err := input.Token()
+	if err != nil {
+		return minting.Token{}, fmt.Errorf("could not make token of location: %w", err)
+	}
+
+	result.Metadata = map[string]string{
+		"location":  location.Location,
+		"latitude":  strconv.FormatFloat(float64(location.Latitude), 'f', -1, 32),
+		"longitude": strconv.FormatFloat(float64(location.Longitude), 'f', -1, 32),
+	}
+
+	return result, nil
+}
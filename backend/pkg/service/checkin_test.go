@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_CheckIn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	locations, err := NewLocation(ctx, repository.NewMemoryLocation(), blob.NewMemory())
+	assert.NoError(t, err)
+
+	fake := minting.NewFake()
+	tokens := NewToken(ctx, fake, repository.NewMemoryMintJob())
+	checkins := NewCheckin(ctx, locations, tokens, repository.NewMemoryClaim(), 100)
+
+	name, latitude, longitude := "Old Town", float32(44.43), float32(26.1)
+
+	location, err := locations.Add(ctx, types.LocationInput{Location: &name, Latitude: &latitude, Longitude: &longitude})
+	assert.NoError(t, err)
+
+	here, there := 44.4305, 26.1
+	input := types.CheckinInput{Latitude: &here, Longitude: &there, Wallet: minting.FakeReceiver}
+
+	// without an uploaded image, there is nothing to mint
+	_, err = checkins.CheckIn(ctx, location.ID, input)
+	assert.ErrorIs(t, err, ErrNoTokenImage)
+
+	var content bytes.Buffer
+
+	assert.NoError(t, png.Encode(&content, image.NewRGBA(image.Rect(0, 0, 600, 600))))
+
+	_, err = locations.SetImage(ctx, location.ID, content.Bytes())
+	assert.NoError(t, err)
+
+	_, err = checkins.CheckIn(ctx, location.ID, types.CheckinInput{Latitude: &here, Wallet: "nope"})
+	assert.ErrorIs(t, err, types.ErrInvalidInput)
+
+	_, err = checkins.CheckIn(ctx, location.ID+1, input)
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	far := 44.44
+	fromFar := types.CheckinInput{Latitude: &far, Longitude: &there, Wallet: input.Wallet}
+
+	_, err = checkins.CheckIn(ctx, location.ID, fromFar)
+	assert.ErrorIs(t, err, ErrTooFar)
+
+	claim, err := checkins.CheckIn(ctx, location.ID, input)
+	assert.NoError(t, err)
+	assert.Equal(t, location.ID, claim.LocationID)
+	assert.InDelta(t, 55, claim.Distance, 5)
+
+	_, err = checkins.CheckIn(ctx, location.ID, input)
+	assert.ErrorIs(t, err, ErrAlreadyClaimed)
+
+	// a wider radius lets farther check-ins count
+	radius := 2_000.0
+
+	_, err = locations.Patch(ctx, location.ID, types.LocationInput{Radius: &radius})
+	assert.NoError(t, err)
+
+	other := "addr_test1qzfakewa77etfakewa77etfakewa77etfakewa77etfakewa77etfakewa77etq0"
+	fromFar.Wallet = other
+
+	claimed, err := checkins.CheckIn(ctx, location.ID, fromFar)
+	assert.NoError(t, err)
+
+	go tokens.RunWorkers(testMintConfig)
+
+	done := waitForJob(t, tokens, claim.JobID)
+	assert.Equal(t, models.JobDone, done.State, done.LastError)
+	assert.Equal(t, minting.FakeReceiver, done.Receiver)
+
+	token, found := fake.Token(done.UID)
+	assert.True(t, found)
+	assert.Equal(t, name, token.DisplayName)
+	assert.Equal(t, name, token.Metadata["location"])
+	assert.NotEqual(t, base64.StdEncoding.EncodeToString(content.Bytes()), token.Image.Base64,
+		"the medium thumbnail is minted")
+
+	page, err := checkins.ByLocation(ctx, location.ID, types.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+
+	page, err = checkins.ByWallet(ctx, other, types.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Claim{claimed}, page.Items)
+
+	noMinting := NewToken(ctx, nil, repository.NewMemoryMintJob())
+	disabled := NewCheckin(ctx, locations, noMinting, repository.NewMemoryClaim(), 0)
+
+	_, err = disabled.CheckIn(ctx, location.ID, input)
+	assert.ErrorIs(t, err, ErrMintingDisabled)
+}
@@ -5,101 +5,788 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/wakka-2/Namless/backend/pkg/models"
 	"github.com/wakka-2/Namless/backend/pkg/repository"
 	"github.com/wakka-2/Namless/backend/pkg/types"
 )
 
+// maxWriteAttempts is how many times a write without a precondition is tried, while concurrent writes beat it.
+const maxWriteAttempts = 3
+
+// ReaperConfig configures the background job that purges expired key-value pairs and prunes their history.
+type ReaperConfig struct {
+	// Interval between two runs.
+	Interval time.Duration
+	// BatchSize is the number of rows purged at once; a run purges batches until there is nothing left.
+	BatchSize int
+	// HistoryKeep is the number of revisions kept for every key; zero keeps them all.
+	HistoryKeep int
+	// HistoryMaxAge is how long revisions are kept for; zero keeps them forever.
+	//
+	// The latest revision of a key is kept regardless of HistoryKeep and HistoryMaxAge.
+	HistoryMaxAge time.Duration
+	// TrashMaxAge is how long deleted pairs can be restored for; zero keeps them forever.
+	TrashMaxAge time.Duration
+}
+
 // Data offers data-related functionality.
+//
+// Every call works in the namespace of its context (see types.NamespaceFrom), and writes that would make a namespace
+// outgrow its quota are refused with types.ErrValueTooLarge or types.ErrQuotaExceeded.
 type Data struct {
-	db        *repository.Store
+	db        repository.DataStore
 	serverCtx context.Context
+	quotas    Quotas
 }
 
 // New builds a new data service.
-func New(ctx context.Context, db *repository.Store) *Data {
+func New(ctx context.Context, db repository.DataStore, quotas Quotas) *Data {
 	return &Data{
 		db:        db,
 		serverCtx: ctx,
+		quotas:    quotas,
 	}
 }
 
-// Add a new key-value pair.
-func (d *Data) Add(ctx context.Context, key string, value string) error {
+// store returns the view of the repository over the namespace of a context.
+func (d *Data) store(ctx context.Context) repository.DataStore {
+	return d.db.In(types.NamespaceFrom(ctx))
+}
+
+// quota returns the quota of the namespace of a context.
+func (d *Data) quota(ctx context.Context) Quota {
+	return d.quotas.of(types.NamespaceFrom(ctx))
+}
+
+// Usage returns how many key-value pairs the namespace of the context holds, and their size.
+func (d *Data) Usage(ctx context.Context) (types.Usage, error) {
 	if d.serverCtx.Err() != nil || ctx.Err() != nil {
-		return types.ErrCancelledContext
+		return types.Usage{}, types.ErrCancelledContext
 	}
 
-	_, err := d.db.Create(ctx, models.Data{
-		ID:    key,
-		Value: value,
-	})
+	result, err := d.store(ctx).Usage(ctx)
+	if err != nil {
+		return types.Usage{}, fmt.Errorf("could not retrieve usage: %w", err)
+	}
+
+	return result, nil
+}
 
+// Add a new key-value pair, expiring after its TTL or at its expiry (if any). The caller becomes its owner.
+func (d *Data) Add(ctx context.Context, pair types.Pair) (models.Data, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Data{}, types.ErrCancelledContext
+	}
+
+	expiresAt, err := pair.Expiry(time.Now())
 	if err != nil {
-		return fmt.Errorf("could not create data entry: %w", err)
+		return models.Data{}, err
 	}
 
-	return nil
+	item := models.Data{
+		ID:        pair.Key,
+		Value:     pair.Value,
+		ExpiresAt: expiresAt,
+		UpdatedBy: author(ctx),
+		Owner:     author(ctx),
+	}
+
+	// the transaction keeps concurrent writes from slipping past the quota together
+	err = d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		err := vacant(ctx, tx, pair.Key)
+		if err == nil {
+			err = d.quota(ctx).admits(ctx, tx, item, nil)
+		}
+
+		if err == nil {
+			item, err = tx.Create(ctx, item)
+		}
+
+		return err
+	})
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not create data entry: %w", err)
+	}
+
+	return item, nil
 }
 
 // Get the key-value pair with a given key.
-func (d *Data) Get(ctx context.Context, key string) (string, error) {
+func (d *Data) Get(ctx context.Context, key string) (models.Data, error) {
 	if d.serverCtx.Err() != nil || ctx.Err() != nil {
-		return "", types.ErrCancelledContext
+		return models.Data{}, types.ErrCancelledContext
+	}
+
+	result, err := d.store(ctx).ByID(ctx, key)
+	if err == nil {
+		err = permit(ctx, result.Owner, result.Grants, models.AccessRead)
 	}
 
-	result, err := d.db.ByID(ctx, key)
 	if err != nil {
-		return "", fmt.Errorf("could not retrieve data entry: %w", err)
+		return models.Data{}, fmt.Errorf("could not retrieve data entry: %w", err)
 	}
 
-	return result.Value, nil
+	return result, nil
 }
 
-// Get the key-value pairs.
-func (d *Data) GetAll(ctx context.Context) ([]models.Data, error) {
+// GetAll returns a page of the key-value pairs the caller may read.
+func (d *Data) GetAll(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
 	if d.serverCtx.Err() != nil || ctx.Err() != nil {
-		return nil, types.ErrCancelledContext
+		return types.Page[models.Data]{}, types.ErrCancelledContext
 	}
 
-	result, err := d.db.GetAll(ctx)
+	opts.Viewer = viewer(ctx)
+
+	result, err := d.store(ctx).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve locations: %w", err)
+		return types.Page[models.Data]{}, fmt.Errorf("could not retrieve data entries: %w", err)
 	}
 
 	return result, nil
 }
 
-// Update a given key-value pair.
-func (d *Data) Update(ctx context.Context, key string, value string) error {
+// Update a given key-value pair, if it passes the precondition, and returns it as stored.
+//
+// The pair expires after its new TTL or at its new expiry (if any); a previous expiry does not carry over. A pair
+// that does not exist is only created when the precondition asks for that, with "If-None-Match: *".
+//
+// With a precondition, the pair is only written if it is still at the version the precondition was checked against,
+// so that concurrent writers cannot overwrite each other; the loser gets ErrPreconditionFailed.
+//
+// The owner and the grants of the pair are kept as they are; the caller needs write access to it.
+func (d *Data) Update(ctx context.Context, pair types.Pair, precondition types.Precondition) (models.Data, error) {
 	if d.serverCtx.Err() != nil || ctx.Err() != nil {
-		return types.ErrCancelledContext
+		return models.Data{}, types.ErrCancelledContext
+	}
+
+	expiresAt, err := pair.Expiry(time.Now())
+	if err != nil {
+		return models.Data{}, err
 	}
 
-	err := d.db.Update(ctx, models.Data{
-		ID:    key,
-		Value: value,
+	item := models.Data{ID: pair.Key, Value: pair.Value, ExpiresAt: expiresAt, UpdatedBy: author(ctx)}
+
+	var (
+		result models.Data
+		failed error
+	)
+
+	// the transaction keeps concurrent writes from slipping past the quota together
+	err = d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		result, failed = update(ctx, tx, item, precondition, d.quota(ctx))
+
+		return failed
 	})
+	if failed != nil {
+		return models.Data{}, failed
+	}
+
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not update data entry: %w", err)
+	}
+
+	return result, nil
+}
+
+// History returns a page of the revisions of a given key-value pair.
+func (d *Data) History(
+	ctx context.Context,
+	key string,
+	opts types.ListOptions,
+) (types.Page[models.DataVersion], error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.DataVersion]{}, types.ErrCancelledContext
+	}
+
+	db := d.store(ctx)
+
+	err := authorize(ctx, db, key, models.AccessRead)
+	if err != nil {
+		return types.Page[models.DataVersion]{}, fmt.Errorf("could not retrieve revisions: %w", err)
+	}
 
+	result, err := db.History(ctx, key, opts)
 	if err != nil {
-		return fmt.Errorf("could not update data entry: %w", err)
+		return types.Page[models.DataVersion]{}, fmt.Errorf("could not retrieve revisions: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetVersion returns the revision of a key-value pair with a given version.
+func (d *Data) GetVersion(ctx context.Context, key string, version int64) (models.DataVersion, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.DataVersion{}, types.ErrCancelledContext
+	}
+
+	db := d.store(ctx)
+
+	err := authorize(ctx, db, key, models.AccessRead)
+	if err != nil {
+		return models.DataVersion{}, fmt.Errorf("could not retrieve revision: %w", err)
+	}
+
+	result, err := db.Revision(ctx, key, version)
+	if err != nil {
+		return models.DataVersion{}, fmt.Errorf("could not retrieve revision: %w", err)
+	}
+
+	if result.Deleted {
+		return models.DataVersion{}, fmt.Errorf(
+			"revision %d of %q is a delete: %w", version, key, repository.ErrDoesNotExist,
+		)
+	}
+
+	return result, nil
+}
+
+// GetAt returns the revision of a key-value pair that was the latest one at a given moment.
+func (d *Data) GetAt(ctx context.Context, key string, at time.Time) (models.DataVersion, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.DataVersion{}, types.ErrCancelledContext
+	}
+
+	db := d.store(ctx)
+
+	err := authorize(ctx, db, key, models.AccessRead)
+	if err != nil {
+		return models.DataVersion{}, fmt.Errorf("could not retrieve revision: %w", err)
+	}
+
+	result, err := db.RevisionAt(ctx, key, at)
+	if err != nil {
+		return models.DataVersion{}, fmt.Errorf("could not retrieve revision: %w", err)
+	}
+
+	if !result.IsVisible(at) {
+		return models.DataVersion{}, fmt.Errorf("%q was deleted or expired at %s: %w", key, at, repository.ErrDoesNotExist)
+	}
+
+	return result, nil
+}
+
+// Revert sets the value of a key-value pair back to the one it had at a given version.
+//
+// The revert is a write like any other: it records a new revision. The expiry of the pair is left as is.
+func (d *Data) Revert(ctx context.Context, key string, version int64) error {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.ErrCancelledContext
+	}
+
+	revision, err := d.GetVersion(ctx, key, version)
+	if err != nil {
+		return err
+	}
+
+	err = d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		current, err := tx.ByID(ctx, key)
+		if err == nil {
+			err = permit(ctx, current.Owner, current.Grants, models.AccessWrite)
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not retrieve data entry: %w", err)
+		}
+
+		// current.Version makes it a conditional update: a concurrent write makes the revert fail, not get lost
+		item := current
+		item.Value, item.UpdatedBy = revision.Value, author(ctx)
+
+		err = d.quota(ctx).admits(ctx, tx, item, &current)
+		if err == nil {
+			_, err = tx.Update(ctx, item)
+		}
+
+		return conflict(err)
+	})
+	if err != nil {
+		return fmt.Errorf("could not revert data entry: %w", err)
 	}
 
 	return nil
 }
 
-// Delete a given key-value pair.
-func (d *Data) Delete(ctx context.Context, key string) error {
+// Share grants a principal an access (models.AccessRead or models.AccessWrite) to a key-value pair, and returns the
+// pair as stored. Only the owner of the pair, or an admin, may share it.
+func (d *Data) Share(ctx context.Context, key string, principalID string, access string) (models.Data, error) {
+	input := types.GrantInput{Access: access}
+
+	err := input.Validate(models.AccessRead, models.AccessWrite)
+	if err != nil {
+		return models.Data{}, err
+	}
+
+	return d.regrant(ctx, key, principalID, access)
+}
+
+// Unshare takes back the access granted to a principal to a key-value pair, and returns the pair as stored. Only the
+// owner of the pair, or an admin, may do so.
+func (d *Data) Unshare(ctx context.Context, key string, principalID string) (models.Data, error) {
+	return d.regrant(ctx, key, principalID, "")
+}
+
+// regrant sets the access of a principal to a key-value pair; none when empty.
+func (d *Data) regrant(ctx context.Context, key string, principalID string, access string) (models.Data, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Data{}, types.ErrCancelledContext
+	}
+
+	db := d.store(ctx)
+
+	current, err := db.ByID(ctx, key)
+	if err == nil {
+		err = permit(ctx, current.Owner, current.Grants, models.AccessRead)
+	}
+
+	if err == nil {
+		err = own(ctx, current.Owner)
+	}
+
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not share data entry: %w", err)
+	}
+
+	// current.Version makes it a conditional update: a concurrent share fails, instead of getting lost
+	current.Grants, current.UpdatedBy = share(current.Grants, principalID, access), author(ctx)
+
+	result, err := db.Update(ctx, current)
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not share data entry: %w", conflict(err))
+	}
+
+	return result, nil
+}
+
+// Delete a given key-value pair, if it passes the precondition.
+func (d *Data) Delete(ctx context.Context, key string, precondition types.Precondition) error {
 	if d.serverCtx.Err() != nil || ctx.Err() != nil {
 		return types.ErrCancelledContext
 	}
 
-	err := d.db.Delete(ctx, key)
+	return remove(ctx, d.store(ctx), key, precondition)
+}
+
+// Trash returns a page of the key-value pairs that were deleted, and can still be restored, that the caller may read.
+func (d *Data) Trash(ctx context.Context, opts types.ListOptions) (types.Page[models.Data], error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.Data]{}, types.ErrCancelledContext
+	}
+
+	opts.Viewer = viewer(ctx)
+
+	result, err := d.store(ctx).Trash(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("could not delete data entry: %w", err)
+		return types.Page[models.Data]{}, fmt.Errorf("could not list deleted data entries: %w", err)
+	}
+
+	return result, nil
+}
+
+// Restore a deleted key-value pair, and returns it as stored.
+func (d *Data) Restore(ctx context.Context, key string) (models.Data, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Data{}, types.ErrCancelledContext
+	}
+
+	var result models.Data
+
+	err := d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		item, err := trashed(ctx, tx, key)
+		if err == nil {
+			err = permit(ctx, item.Owner, item.Grants, models.AccessWrite)
+		}
+
+		if err == nil {
+			err = d.quota(ctx).admits(ctx, tx, item, nil)
+		}
+
+		if err == nil {
+			result, err = tx.Restore(ctx, key)
+		}
+
+		return err
+	})
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not restore data entry: %w", err)
+	}
+
+	return result, nil
+}
+
+// Purge deletes for good a key-value pair, deleted or not, along with its history, if it passes the precondition.
+//
+// Pairs in the trash do not exist as far as the precondition is concerned.
+func (d *Data) Purge(ctx context.Context, key string, precondition types.Precondition) error {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.ErrCancelledContext
+	}
+
+	err := d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		err := authorize(ctx, tx, key, models.AccessWrite)
+		if err != nil {
+			return err
+		}
+
+		if !precondition.IsEmpty() {
+			_, _, err := check(ctx, tx, key, precondition)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Purge(ctx, key)
+	})
+	if err != nil {
+		return fmt.Errorf("could not purge data entry: %w", err)
+	}
+
+	return nil
+}
+
+// Batch applies operations in order, all or nothing, and returns their results.
+//
+// When an operation fails, none are kept: the results stop at the failed one, which tells why, and the error is
+// returned along with them.
+func (d *Data) Batch(ctx context.Context, input types.BatchInput) ([]types.BatchResult, error) {
+	if d.serverCtx.Err() != nil || ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	err := input.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	now, quota := time.Now(), d.quota(ctx)
+	results := make([]types.BatchResult, 0, len(input.Operations))
+
+	err = d.store(ctx).Transaction(ctx, func(tx repository.DataStore) error {
+		for _, operation := range input.Operations {
+			version, err := apply(ctx, tx, operation, now, quota)
+			result := types.BatchResult{Op: operation.Op, Key: operation.Key, Version: version}
+
+			if err != nil {
+				result.Error = describe(err)
+				results = append(results, result)
+
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("could not apply batch: %w", err)
+	}
+
+	return results, nil
+}
+
+// apply a batch operation to a given repository, and returns the version of the key-value pair after it.
+func apply(
+	ctx context.Context,
+	db repository.DataStore,
+	operation types.BatchOperation,
+	now time.Time,
+	quota Quota,
+) (int64, error) {
+	switch operation.Op {
+	case types.BatchPut:
+		expiresAt, err := operation.Expiry(now)
+		if err != nil {
+			return 0, err
+		}
+
+		item := models.Data{ID: operation.Key, Value: operation.Value, ExpiresAt: expiresAt, UpdatedBy: author(ctx)}
+
+		result, err := update(ctx, db, item, operation.Precondition(), quota)
+
+		return result.Version, err
+	case types.BatchDelete:
+		return 0, remove(ctx, db, operation.Key, operation.Precondition())
+	default:
+		current, _, err := check(ctx, db, operation.Key, operation.Precondition())
+
+		return current.Version, err
+	}
+}
+
+// describe tells why a batch operation failed, without the details of the storage.
+func describe(err error) string {
+	switch {
+	case errors.Is(err, types.ErrPreconditionFailed):
+		return types.ErrPreconditionFailed.Error()
+	case errors.Is(err, repository.ErrDoesNotExist):
+		return "entry not found"
+	case errors.Is(err, types.ErrForbidden):
+		return types.ErrForbidden.Error()
+	case errors.Is(err, types.ErrValueTooLarge):
+		return types.ErrValueTooLarge.Error()
+	case errors.Is(err, types.ErrQuotaExceeded):
+		return types.ErrQuotaExceeded.Error()
+	case errors.Is(err, types.ErrInvalidExpiry):
+		return err.Error()
+	}
+
+	return "could not apply operation"
+}
+
+// update a key-value pair in a given repository, if it passes the precondition and the quota. See Data.Update.
+func update(
+	ctx context.Context,
+	db repository.DataStore,
+	item models.Data,
+	precondition types.Precondition,
+	quota Quota,
+) (models.Data, error) {
+	for attempt := 1; ; attempt++ {
+		current, exists, err := check(ctx, db, item.ID, precondition)
+		if err != nil {
+			return models.Data{}, err
+		}
+
+		if !exists && precondition.IfNoneMatch.Any {
+			return create(ctx, db, item, quota)
+		}
+
+		if !exists {
+			return models.Data{}, fmt.Errorf("could not update data entry: %w", repository.ErrDoesNotExist)
+		}
+
+		err = permit(ctx, current.Owner, current.Grants, models.AccessWrite)
+		if err != nil {
+			return models.Data{}, fmt.Errorf("could not update data entry: %w", err)
+		}
+
+		// the version keeps a concurrent write from changing the owner or the grants meanwhile
+		item.Version, item.Owner, item.Grants = current.Version, current.Owner, current.Grants
+
+		err = quota.admits(ctx, db, item, &current)
+		if err != nil {
+			return models.Data{}, fmt.Errorf("could not update data entry: %w", err)
+		}
+
+		result, err := db.Update(ctx, item)
+		if retry(err, precondition, attempt) {
+			continue
+		}
+
+		if err != nil {
+			return models.Data{}, fmt.Errorf("could not update data entry: %w", conflict(err))
+		}
+
+		return result, nil
+	}
+}
+
+// remove a key-value pair from a given repository, if it passes the precondition.
+func remove(ctx context.Context, db repository.DataStore, key string, precondition types.Precondition) error {
+	for attempt := 1; ; attempt++ {
+		current, exists, err := check(ctx, db, key, precondition)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("could not delete data entry: %w", repository.ErrDoesNotExist)
+		}
+
+		err = permit(ctx, current.Owner, current.Grants, models.AccessWrite)
+		if err != nil {
+			return fmt.Errorf("could not delete data entry: %w", err)
+		}
+
+		err = db.Delete(ctx, key, current.Version)
+		if retry(err, precondition, attempt) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not delete data entry: %w", conflict(err))
+		}
+
+		return nil
+	}
+}
+
+// retry tells whether a write should be tried again: when it lost a race to a concurrent one, that its caller did not
+// guard against with a precondition, and it was not tried too many times already.
+func retry(err error, precondition types.Precondition, attempt int) bool {
+	return errors.Is(err, repository.ErrVersionMismatch) && precondition.IsEmpty() && attempt < maxWriteAttempts
+}
+
+// check reads the current state of a key-value pair, and returns ErrPreconditionFailed if it fails the precondition.
+//
+// A pair the caller may not read does not exist, and yields repository.ErrDoesNotExist.
+func check(
+	ctx context.Context,
+	db repository.DataStore,
+	key string,
+	precondition types.Precondition,
+) (models.Data, bool, error) {
+	current, err := db.ByID(ctx, key)
+	if err == nil {
+		err = permit(ctx, current.Owner, current.Grants, models.AccessRead)
+	}
+
+	if err != nil && !errors.Is(err, repository.ErrDoesNotExist) {
+		return models.Data{}, false, fmt.Errorf("could not retrieve data entry: %w", err)
+	}
+
+	exists := err == nil
+	if !exists {
+		current = models.Data{}
+	}
+
+	if !precondition.Holds(exists, current.Version) {
+		return models.Data{}, false, types.ErrPreconditionFailed
+	}
+
+	return current, exists, nil
+}
+
+// create a key-value pair that must not exist yet, if it passes the quota. The caller becomes its owner.
+func create(ctx context.Context, db repository.DataStore, item models.Data, quota Quota) (models.Data, error) {
+	item.Owner, item.Grants = author(ctx), nil
+
+	err := vacant(ctx, db, item.ID)
+	if err == nil {
+		err = quota.admits(ctx, db, item, nil)
+	}
+
+	if err == nil {
+		item, err = db.Create(ctx, item)
+	}
+
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return models.Data{}, fmt.Errorf("%w: %w", types.ErrPreconditionFailed, err)
+	}
+
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not create data entry: %w", err)
+	}
+
+	return item, nil
+}
+
+// vacant returns repository.ErrAlreadyExists when a key is held by a pair in the trash that the caller may not change:
+// a new pair with that key would replace it, and take over its history.
+func vacant(ctx context.Context, db repository.DataStore, key string) error {
+	item, err := trashed(ctx, db, key)
+	if errors.Is(err, repository.ErrDoesNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if permit(ctx, item.Owner, item.Grants, models.AccessWrite) != nil {
+		return fmt.Errorf("%q is in the trash: %w", key, repository.ErrAlreadyExists)
 	}
 
 	return nil
 }
+
+// trashed returns the deleted key-value pair with a given key. Wraps repository.ErrDoesNotExist when it is not in the
+// trash.
+func trashed(ctx context.Context, db repository.DataStore, key string) (models.Data, error) {
+	result, err := db.TrashedByID(ctx, key)
+	if err != nil {
+		return models.Data{}, fmt.Errorf("could not find deleted data entry: %w", err)
+	}
+
+	return result, nil
+}
+
+// authorize returns nil when the caller may have an access to a key-value pair, live or in the trash; see permit.
+func authorize(ctx context.Context, db repository.DataStore, key string, access string) error {
+	principal, found := types.PrincipalFrom(ctx)
+	if !found || principal.HasScope(types.ScopeAdmin) {
+		return nil
+	}
+
+	item, err := db.ByID(ctx, key)
+	if errors.Is(err, repository.ErrDoesNotExist) {
+		item, err = trashed(ctx, db, key)
+	}
+
+	if err != nil {
+		// revisions outliving their pair have no owner to ask, so only admins see them
+		return fmt.Errorf("could not retrieve data entry: %w", err)
+	}
+
+	return permit(ctx, item.Owner, item.Grants, access)
+}
+
+// conflict turns a version mismatch, caused by a concurrent write, into a failed precondition.
+func conflict(err error) error {
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		return fmt.Errorf("%w: %w", types.ErrPreconditionFailed, err)
+	}
+
+	return err
+}
+
+// RunReaper purges expired key-value pairs, empties the trash and prunes old revisions every cfg.Interval, until the
+// server context is cancelled.
+//
+// Expired pairs are invisible as soon as they expire; the reaper only reclaims their storage. Meant to run in its own
+// goroutine.
+func (d *Data) RunReaper(cfg ReaperConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.serverCtx.Done():
+			return
+		case <-ticker.C:
+			d.reap("expired data entries", cfg.BatchSize, func() (int64, error) {
+				return d.db.PurgeExpired(d.serverCtx, time.Now(), cfg.BatchSize)
+			})
+
+			if cfg.TrashMaxAge > 0 {
+				d.reap("deleted data entries", cfg.BatchSize, func() (int64, error) {
+					return d.db.PurgeDeleted(d.serverCtx, time.Now().Add(-cfg.TrashMaxAge), cfg.BatchSize)
+				})
+			}
+
+			if cfg.HistoryKeep <= 0 && cfg.HistoryMaxAge <= 0 {
+				continue
+			}
+
+			var before time.Time
+			if cfg.HistoryMaxAge > 0 {
+				before = time.Now().Add(-cfg.HistoryMaxAge)
+			}
+
+			d.reap("old revisions", cfg.BatchSize, func() (int64, error) {
+				return d.db.PruneHistory(d.serverCtx, cfg.HistoryKeep, before, cfg.BatchSize)
+			})
+		}
+	}
+}
+
+// reap calls purge, one batch at a time, until a batch comes back short.
+func (d *Data) reap(what string, batchSize int, purge func() (int64, error)) {
+	for d.serverCtx.Err() == nil {
+		purged, err := purge()
+		if err != nil {
+			log.Default().Printf("could not purge %s: %s", what, err)
+
+			return
+		}
+
+		if purged < int64(batchSize) {
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// idempotencyInFlight is how long a request in flight holds its key: after that, it is deemed lost (i.e.: the
+// process crashed), and the key can be used again.
+const idempotencyInFlight = 10 * time.Minute
+
+var (
+	// ErrIdempotencyInFlight for when a request with the same idempotency key did not get its response yet.
+	ErrIdempotencyInFlight = errors.New("a request with this Idempotency-Key is in flight")
+	// ErrIdempotencyMismatch for when an idempotency key was used for a different request.
+	ErrIdempotencyMismatch = errors.New("this Idempotency-Key was used for a different request")
+)
+
+// Idempotency records the requests sent with an idempotency key, so that their responses can be replayed.
+type Idempotency struct {
+	db        repository.IdempotencyStore
+	serverCtx context.Context
+	// window is how long responses are replayed for.
+	window time.Duration
+}
+
+// NewIdempotency builds a new idempotency service, replaying responses for a given window.
+func NewIdempotency(ctx context.Context, db repository.IdempotencyStore, window time.Duration) *Idempotency {
+	return &Idempotency{
+		db:        db,
+		serverCtx: ctx,
+		window:    window,
+	}
+}
+
+// Begin records a request with a given key, method and target (path and query), unless the key is in use.
+//
+// A new request gets a record in flight, which must be completed or released. Otherwise, it returns the record of
+// the earlier request when it got its response, ErrIdempotencyInFlight when it did not, and ErrIdempotencyMismatch
+// when its method or target differ; the caller must also check the fingerprint of the body.
+func (i *Idempotency) Begin(
+	ctx context.Context,
+	key string,
+	method string,
+	target string,
+) (models.IdempotencyRecord, error) {
+	if i.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.IdempotencyRecord{}, types.ErrCancelledContext
+	}
+
+	now := time.Now()
+
+	record, err := i.db.Begin(ctx, models.IdempotencyRecord{
+		Key:       key,
+		Method:    method,
+		Target:    target,
+		ExpiresAt: now.Add(idempotencyInFlight),
+	}, now)
+	if err == nil {
+		return record, nil
+	}
+
+	if !errors.Is(err, repository.ErrAlreadyExists) {
+		return models.IdempotencyRecord{}, fmt.Errorf("could not begin request: %w", err)
+	}
+
+	if record.Method != method || record.Target != target {
+		return models.IdempotencyRecord{}, fmt.Errorf("%w: %s %s", ErrIdempotencyMismatch, record.Method, record.Target)
+	}
+
+	if record.IsInFlight() {
+		return models.IdempotencyRecord{}, ErrIdempotencyInFlight
+	}
+
+	return record, nil
+}
+
+// Complete records the response of a request begun with Begin, to be replayed during the window.
+func (i *Idempotency) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	record.ExpiresAt = time.Now().Add(i.window)
+
+	err := i.db.Complete(ctx, record)
+	if err != nil {
+		return fmt.Errorf("could not complete request: %w", err)
+	}
+
+	return nil
+}
+
+// Release forgets a request begun with Begin, so that it can be sent again with the same key.
+func (i *Idempotency) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	err := i.db.Release(ctx, record)
+	if err != nil {
+		return fmt.Errorf("could not release request: %w", err)
+	}
+
+	return nil
+}
+
+// RunReaper purges expired records, batchSize at a time, every interval, until the server context is cancelled.
+func (i *Idempotency) RunReaper(interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.serverCtx.Done():
+			return
+		case <-ticker.C:
+			for i.serverCtx.Err() == nil {
+				purged, err := i.db.PurgeExpired(i.serverCtx, time.Now(), batchSize)
+				if err != nil {
+					log.Default().Printf("could not purge expired idempotency records: %s", err)
+
+					break
+				}
+
+				if purged < int64(batchSize) {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+)
+
+func Test_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	idempotency := NewIdempotency(ctx, repository.NewMemoryIdempotency(), time.Hour)
+
+	record, err := idempotency.Begin(ctx, "key1", http.MethodPost, "/data")
+	assert.NoError(t, err)
+	assert.True(t, record.IsInFlight())
+
+	_, err = idempotency.Begin(ctx, "key1", http.MethodPost, "/data")
+	assert.ErrorIs(t, err, ErrIdempotencyInFlight)
+
+	// a released key can be used again
+	assert.NoError(t, idempotency.Release(ctx, record))
+
+	record, err = idempotency.Begin(ctx, "key1", http.MethodPost, "/data")
+	assert.NoError(t, err)
+
+	record.Fingerprint = "fingerprint"
+	record.StatusCode = http.StatusCreated
+	record.Header = http.Header{"Content-Type": {"application/json"}}
+	record.Body = []byte(`{"key":"key1"}`)
+	assert.NoError(t, idempotency.Complete(ctx, record))
+
+	replayed, err := idempotency.Begin(ctx, "key1", http.MethodPost, "/data")
+	assert.NoError(t, err)
+	assert.False(t, replayed.IsInFlight())
+	assert.Equal(t, record.Fingerprint, replayed.Fingerprint)
+	assert.Equal(t, record.Header, replayed.Header)
+	assert.Equal(t, record.Body, replayed.Body)
+
+	_, err = idempotency.Begin(ctx, "key1", http.MethodPut, "/data")
+	assert.ErrorIs(t, err, ErrIdempotencyMismatch)
+
+	_, err = idempotency.Begin(ctx, "key1", http.MethodPost, "/location")
+	assert.ErrorIs(t, err, ErrIdempotencyMismatch)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = idempotency.Begin(cancelled, "key2", http.MethodPost, "/data")
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wakka-2/Namless/backend/pkg/jwt"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+const (
+	// DefaultScopeClaim is the claim holding the scopes of a token, as in OAuth 2.0 (RFC 8693).
+	DefaultScopeClaim = "scope"
+	// tokenPrincipalPrefix starts the IDs of the callers using a token, followed by its issuer and its subject, so that
+	// a subject cannot pass for an API key (see apiKeyPrincipalPrefix), or for the subject of another issuer.
+	tokenPrincipalPrefix = "jwt:"
+)
+
+var (
+	// ErrInvalidToken for when a token is malformed, forged, expired, or meant for someone else.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnknownScope for when claims are mapped to a scope that does not exist.
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+// JWT offers the authentication of callers with JSON Web Tokens, issued by another service.
+//
+// The issuer and the subject of a token make the ID of the caller (jwt:<issuer>:<subject>), and the values of its
+// scope claim (a space-separated string, or an array of strings) become its scopes: through the scope map, or as they
+// are when they name a scope. Other values are left out.
+type JWT struct {
+	verifier  *jwt.Verifier
+	serverCtx context.Context
+	// scopeClaim names the claim holding the scopes.
+	scopeClaim string
+	// scopeMap maps values of the scope claim to scopes.
+	scopeMap map[string][]types.Scope
+}
+
+// NewJWT builds a new JWT service; scopeClaim is DefaultScopeClaim when empty. Returns ErrUnknownScope when scopeMap
+// maps a value to a scope that does not exist.
+func NewJWT(
+	ctx context.Context,
+	verifier *jwt.Verifier,
+	scopeClaim string,
+	scopeMap map[string][]types.Scope,
+) (*JWT, error) {
+	for value, scopes := range scopeMap {
+		for _, scope := range scopes {
+			if !slices.Contains(types.Scopes, scope) {
+				return nil, fmt.Errorf("%w %q for %q", ErrUnknownScope, scope, value)
+			}
+		}
+	}
+
+	if scopeClaim == "" {
+		scopeClaim = DefaultScopeClaim
+	}
+
+	return &JWT{
+		verifier:   verifier,
+		serverCtx:  ctx,
+		scopeClaim: scopeClaim,
+		scopeMap:   scopeMap,
+	}, nil
+}
+
+// Authenticate returns the caller holding a given token. Returns ErrInvalidToken when the token cannot be trusted,
+// and jwt.ErrKeysUnavailable when the signing keys could not be read.
+func (j *JWT) Authenticate(ctx context.Context, token string) (types.Principal, error) {
+	if j.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Principal{}, types.ErrCancelledContext
+	}
+
+	claims, err := j.verifier.Verify(ctx, token)
+	if errors.Is(err, jwt.ErrKeysUnavailable) {
+		return types.Principal{}, fmt.Errorf("could not verify token: %w", err)
+	}
+
+	if err != nil {
+		return types.Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" {
+		return types.Principal{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	result := types.Principal{ID: tokenPrincipalPrefix + claims.Issuer + ":" + claims.Subject, Name: claims.Subject}
+
+	if name, ok := claims.Raw["name"].(string); ok && name != "" {
+		result.Name = name
+	}
+
+	for _, value := range claimValues(claims.Raw[j.scopeClaim]) {
+		scopes, mapped := j.scopeMap[value]
+		if !mapped && slices.Contains(types.Scopes, types.Scope(value)) {
+			scopes = []types.Scope{types.Scope(value)}
+		}
+
+		for _, scope := range scopes {
+			if !slices.Contains(result.Scopes, scope) {
+				result.Scopes = append(result.Scopes, scope)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// claimValues returns the values of a claim: the words of a string, or the strings of an array.
+func claimValues(claim any) []string {
+	switch values := claim.(type) {
+	case string:
+		return strings.Fields(values)
+	case []any:
+		result := make([]string, 0, len(values))
+
+		for _, value := range values {
+			if text, ok := value.(string); ok {
+				result = append(result, text)
+			}
+		}
+
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/jwt"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// signEdDSA returns a token holding claims, signed with an Ed25519 key.
+func signEdDSA(t *testing.T, private ed25519.PrivateKey, claims map[string]any) string {
+	head := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","kid":"key"}`))
+
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	content := head + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return content + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(private, []byte(content)))
+}
+
+func Test_JWT(t *testing.T) {
+	ctx := context.Background()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	verifier := jwt.NewVerifier(jwt.KeySet{{ID: "key", Public: public}}, "https://issuer", "namless", 0)
+
+	_, err = NewJWT(ctx, verifier, "", map[string][]types.Scope{"editor": {"data:delete"}})
+	assert.ErrorIs(t, err, ErrUnknownScope)
+
+	tokens, err := NewJWT(ctx, verifier, "roles", map[string][]types.Scope{
+		"editor": {types.ScopeDataRead, types.ScopeDataWrite},
+	})
+	assert.NoError(t, err)
+
+	claims := map[string]any{
+		"sub":   "user-1",
+		"name":  "Ada",
+		"iss":   "https://issuer",
+		"aud":   "namless",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"editor", "data:read", "token:mint", "unknown"},
+	}
+
+	principal, err := tokens.Authenticate(ctx, signEdDSA(t, private, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, types.Principal{
+		ID:     "jwt:https://issuer:user-1",
+		Name:   "Ada",
+		Scopes: []types.Scope{types.ScopeDataRead, types.ScopeDataWrite, types.ScopeTokenMint},
+	}, principal)
+
+	// the default claim is a space-separated string
+	spaced, err := NewJWT(ctx, verifier, "", nil)
+	assert.NoError(t, err)
+
+	claims["scope"] = "location:write admin:nope"
+
+	principal, err = spaced.Authenticate(ctx, signEdDSA(t, private, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Scope{types.ScopeLocationWrite}, principal.Scopes)
+
+	claims["aud"] = "someone-else"
+
+	_, err = spaced.Authenticate(ctx, signEdDSA(t, private, claims))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorIs(t, err, jwt.ErrInvalidAudience)
+
+	claims["aud"], claims["sub"] = "namless", ""
+
+	_, err = spaced.Authenticate(ctx, signEdDSA(t, private, claims))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func Test_Attribution(t *testing.T) {
+	ctx := types.WithPrincipal(context.Background(), types.Principal{ID: "user-1"})
+
+	data := New(ctx, repository.NewMemory(), Quotas{})
+
+	created, err := data.Add(ctx, types.Pair{Key: "key", Value: "v1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", created.UpdatedBy)
+
+	_, err = data.Share(ctx, "key", "user-2", "write")
+	assert.NoError(t, err)
+
+	_, err = data.Update(types.WithPrincipal(ctx, types.Principal{ID: "user-2"}), types.Pair{Key: "key", Value: "v2"},
+		types.Precondition{})
+	assert.NoError(t, err)
+
+	revision, err := data.GetVersion(ctx, "key", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-2", revision.UpdatedBy)
+
+	revision, err = data.GetVersion(ctx, "key", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", revision.UpdatedBy)
+
+	locations, err := NewLocation(ctx, repository.NewMemoryLocation(), blob.NewMemory())
+	assert.NoError(t, err)
+
+	name, latitude, longitude := "Old Town", float32(44.43), float32(26.1)
+
+	location, err := locations.Add(ctx, types.LocationInput{Location: &name, Latitude: &latitude, Longitude: &longitude})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", location.UpdatedBy)
+
+	location, err = locations.Patch(context.Background(), location.ID, types.LocationInput{Location: &name})
+	assert.NoError(t, err)
+	assert.Empty(t, location.UpdatedBy, "anonymous writes are not attributed")
+}
+
+func Test_PrincipalSources(t *testing.T) {
+	ctx := context.Background()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	tokens, err := NewJWT(ctx, jwt.NewVerifier(jwt.KeySet{{ID: "key", Public: public}}, "", "", 0), "", nil)
+	assert.NoError(t, err)
+
+	keys := NewAPIKey(ctx, repository.NewMemoryAPIKey(), "a-long-enough-admin-key-for-tests")
+
+	admin, err := keys.Authenticate(ctx, "a-long-enough-admin-key-for-tests")
+	assert.NoError(t, err)
+
+	issued, key, err := keys.Issue(ctx, types.APIKeyInput{Name: "ci", Scopes: []types.Scope{types.ScopeDataWrite}})
+	assert.NoError(t, err)
+
+	holder, err := keys.Authenticate(ctx, key)
+	assert.NoError(t, err)
+
+	data := New(ctx, repository.NewMemory(), Quotas{})
+
+	_, err = data.Add(types.WithPrincipal(ctx, admin), types.Pair{Key: "admin's", Value: "v1"})
+	assert.NoError(t, err)
+
+	_, err = data.Add(types.WithPrincipal(ctx, holder), types.Pair{Key: "holder's", Value: "v1"})
+	assert.NoError(t, err)
+
+	// subjects that read like the ID of another caller do not pass for it
+	for subject, key := range map[string]string{"admin": "admin's", "config:admin": "admin's", issued.ID: "holder's"} {
+		claims := map[string]any{"sub": subject, "exp": time.Now().Add(time.Hour).Unix(), "scope": "data:write"}
+
+		impostor, err := tokens.Authenticate(ctx, signEdDSA(t, private, claims))
+		assert.NoError(t, err)
+
+		_, err = data.Share(types.WithPrincipal(ctx, impostor), key, impostor.ID, "write")
+		assert.ErrorIs(t, err, repository.ErrDoesNotExist, subject)
+	}
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/geo"
 	"github.com/wakka-2/Namless/backend/pkg/models"
 	"github.com/wakka-2/Namless/backend/pkg/repository"
 	"github.com/wakka-2/Namless/backend/pkg/types"
@@ -11,31 +15,61 @@ import (
 
 // Location offers Location-related functionality.
 type Location struct {
-	db        *repository.Location
+	db        repository.LocationStore
 	serverCtx context.Context
+	// index holds every location, to find the nearest ones to a point.
+	index *geo.Index[models.Location]
+	// writes keeps the changes of the DB and the ones of the index in the same order.
+	writes sync.Mutex
+	// blobs keeps uploaded images, and their thumbnails.
+	blobs blob.Store
 }
 
-// NewLocation builds a new Location service.
-func NewLocation(ctx context.Context, db *repository.Location) *Location {
+// NewLocation builds a new Location service, and indexes the locations already in the DB.
+func NewLocation(ctx context.Context, db repository.LocationStore, blobs blob.Store) (*Location, error) {
+	locations, err := db.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not index locations: %w", err)
+	}
+
+	index := geo.NewIndex[models.Location]()
+
+	for _, location := range locations {
+		index.Set(location.ID, position(location), location)
+	}
+
 	return &Location{
 		db:        db,
 		serverCtx: ctx,
-	}
+		index:     index,
+		blobs:     blobs,
+	}, nil
 }
 
-// Add a new key-value pair.
-func (l *Location) Add(ctx context.Context, location models.Location) error {
+// Add a new location, and returns it with the ID it was given. The caller becomes its owner.
+//
+// Returns a types.ValidationError when the input is invalid.
+func (l *Location) Add(ctx context.Context, input types.LocationInput) (models.Location, error) {
 	if l.serverCtx.Err() != nil || ctx.Err() != nil {
-		return types.ErrCancelledContext
+		return models.Location{}, types.ErrCancelledContext
 	}
 
-	_, err := l.db.Create(ctx, location)
+	location, err := input.Apply(models.Location{UpdatedBy: author(ctx), Owner: author(ctx)}, false)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not create Location entry: %w", err)
+	}
+
+	l.writes.Lock()
+	defer l.writes.Unlock()
 
+	created, err := l.db.Create(ctx, location)
 	if err != nil {
-		return fmt.Errorf("could not create Location entry: %w", err)
+		return models.Location{}, fmt.Errorf("could not create Location entry: %w", err)
 	}
 
-	return nil
+	l.index.Set(created.ID, position(created), created)
+
+	return created, nil
 }
 
 // Get the location with a given ID.
@@ -52,44 +86,351 @@ func (l *Location) Get(ctx context.Context, id int) (models.Location, error) {
 	return result, nil
 }
 
-// Get the key-value pairs.
-func (l *Location) GetAll(ctx context.Context) ([]models.Location, error) {
+// GetAll returns a page of locations.
+func (l *Location) GetAll(ctx context.Context, opts types.ListOptions) (types.Page[models.Location], error) {
 	if l.serverCtx.Err() != nil || ctx.Err() != nil {
-		return nil, types.ErrCancelledContext
+		return types.Page[models.Location]{}, types.ErrCancelledContext
+	}
+
+	result, err := l.db.List(ctx, opts)
+	if err != nil {
+		return types.Page[models.Location]{}, fmt.Errorf("could not retrieve locations: %w", err)
+	}
+
+	return result, nil
+}
+
+// InBox returns a page of the locations inside a bounding box.
+func (l *Location) InBox(
+	ctx context.Context,
+	box geo.BBox,
+	opts types.ListOptions,
+) (types.Page[models.Location], error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.Location]{}, types.ErrCancelledContext
 	}
 
-	result, err := l.db.GetAll(ctx)
+	result, err := l.db.InBox(ctx, box, opts)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve locations: %w", err)
+		return types.Page[models.Location]{}, fmt.Errorf("could not retrieve locations in a box: %w", err)
 	}
 
 	return result, nil
 }
 
-// Update a given key-value pair.
+// Near returns a page of the locations within radius meters of a point, with their distance, closest first.
+func (l *Location) Near(
+	ctx context.Context,
+	center geo.Point,
+	radius float64,
+	opts types.ListOptions,
+) (types.Page[models.LocationDistance], error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.Page[models.LocationDistance]{}, types.ErrCancelledContext
+	}
+
+	result, err := l.db.Near(ctx, center, radius, opts)
+	if err != nil {
+		return types.Page[models.LocationDistance]{}, fmt.Errorf("could not retrieve locations near a point: %w", err)
+	}
+
+	return result, nil
+}
+
+// Update a given location; its owner and grants are kept as they are.
+//
+// Returns a types.ValidationError when the location is invalid, and types.ErrForbidden when the caller may not change
+// it.
 func (l *Location) Update(ctx context.Context, location models.Location) error {
 	if l.serverCtx.Err() != nil || ctx.Err() != nil {
 		return types.ErrCancelledContext
 	}
 
-	err := l.db.Update(ctx, location)
+	err := types.ValidateLocation(location)
 	if err != nil {
 		return fmt.Errorf("could not update Location entry: %w", err)
 	}
 
+	l.writes.Lock()
+	defer l.writes.Unlock()
+
+	current, err := l.db.ByID(ctx, location.ID)
+	if err == nil {
+		err = changeable(ctx, current)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not update Location entry: %w", err)
+	}
+
+	location.UpdatedBy, location.Owner, location.Grants = author(ctx), current.Owner, current.Grants
+
+	err = l.db.Update(ctx, location)
+	if err != nil {
+		return fmt.Errorf("could not update Location entry: %w", err)
+	}
+
+	l.index.Set(location.ID, position(location), location)
+
 	return nil
 }
 
-// Delete a given key-value pair.
+// Replace every field of the location with a given ID, and returns the result.
+//
+// Returns a types.ValidationError when the input is invalid.
+func (l *Location) Replace(ctx context.Context, id int, input types.LocationInput) (models.Location, error) {
+	return l.modify(ctx, id, func(current models.Location) (models.Location, error) {
+		result, err := input.Apply(models.Location{ID: id}, false)
+
+		return keepThumbnails(current, result), err
+	})
+}
+
+// Patch the fields given in the input of the location with a given ID, and returns the result.
+//
+// Returns a types.ValidationError when the input is invalid.
+func (l *Location) Patch(ctx context.Context, id int, input types.LocationInput) (models.Location, error) {
+	return l.modify(ctx, id, func(current models.Location) (models.Location, error) {
+		result, err := input.Apply(current, true)
+
+		return keepThumbnails(current, result), err
+	})
+}
+
+// keepThumbnails returns result with the thumbnails of current when it has the same image, and with none otherwise.
+func keepThumbnails(current models.Location, result models.Location) models.Location {
+	result.Thumbnails = nil
+
+	if result.Image == current.Image {
+		result.Thumbnails = current.Thumbnails
+	}
+
+	return result
+}
+
+// modify the location with a given ID into the result of change, keeping its owner and grants. Returns
+// types.ErrForbidden when the caller may not change it.
+func (l *Location) modify(
+	ctx context.Context,
+	id int,
+	change func(current models.Location) (models.Location, error),
+) (models.Location, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Location{}, types.ErrCancelledContext
+	}
+
+	l.writes.Lock()
+	defer l.writes.Unlock()
+
+	current, err := l.db.ByID(ctx, id)
+	if err == nil {
+		err = changeable(ctx, current)
+	}
+
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not retrieve Location entry: %w", err)
+	}
+
+	result, err := change(current)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not update Location entry: %w", err)
+	}
+
+	result.UpdatedBy, result.Owner, result.Grants = author(ctx), current.Owner, current.Grants
+
+	err = l.db.Update(ctx, result)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not update Location entry: %w", err)
+	}
+
+	l.index.Set(result.ID, position(result), result)
+
+	return result, nil
+}
+
+// Delete the location with a given ID. Returns types.ErrForbidden when the caller may not change it.
 func (l *Location) Delete(ctx context.Context, id int) error {
 	if l.serverCtx.Err() != nil || ctx.Err() != nil {
 		return types.ErrCancelledContext
 	}
 
-	err := l.db.Delete(ctx, id)
+	l.writes.Lock()
+	defer l.writes.Unlock()
+
+	current, err := l.db.ByID(ctx, id)
+	if err == nil {
+		err = changeable(ctx, current)
+	}
+
 	if err != nil {
 		return fmt.Errorf("could not delete Location entry: %w", err)
 	}
 
+	err = l.db.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("could not delete Location entry: %w", err)
+	}
+
+	l.index.Remove(id)
+
 	return nil
 }
+
+// Import a single location: one with an ID replaces the location with this ID, one without is created.
+//
+// Invalid locations and unknown IDs are reported in the result, not as errors. In a dry run, nothing is written.
+func (l *Location) Import(ctx context.Context, input types.LocationInput, dryRun bool) (types.ImportResult, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.ImportResult{}, types.ErrCancelledContext
+	}
+
+	if input.ID == nil || *input.ID == 0 {
+		var (
+			created models.Location
+			err     error
+		)
+
+		if dryRun {
+			_, err = input.Apply(models.Location{}, false)
+		} else {
+			created, err = l.Add(ctx, input)
+		}
+
+		return importResult(types.ImportCreated, created.ID, err)
+	}
+
+	id := *input.ID
+
+	var err error
+
+	if dryRun {
+		var current models.Location
+
+		current, err = l.Get(ctx, id)
+		if err == nil {
+			err = changeable(ctx, current)
+		}
+
+		if err == nil {
+			_, err = input.Apply(models.Location{ID: id}, false)
+		}
+	} else {
+		_, err = l.Replace(ctx, id, input)
+	}
+
+	return importResult(types.ImportUpdated, id, err)
+}
+
+// importResult returns the result of an imported location, given the error writing it.
+func importResult(outcome string, id int, err error) (types.ImportResult, error) {
+	var invalid *types.ValidationError
+
+	switch {
+	case err == nil:
+		return types.ImportResult{ID: id, Outcome: outcome}, nil
+	case errors.As(err, &invalid):
+		return types.ImportResult{
+			ID: id, Outcome: types.ImportInvalid, Error: types.ErrInvalidInput.Error(), Fields: invalid.Fields,
+		}, nil
+	case errors.Is(err, repository.ErrDoesNotExist):
+		return types.ImportResult{ID: id, Outcome: types.ImportNotFound, Error: "location not found"}, nil
+	case errors.Is(err, types.ErrForbidden):
+		return types.ImportResult{ID: id, Outcome: types.ImportForbidden, Error: types.ErrForbidden.Error()}, nil
+	}
+
+	return types.ImportResult{}, err
+}
+
+// Nearest returns (at most) the k locations nearest to a point, with their distance, closest first.
+//
+// It is served from memory, and never waits for the DB.
+func (l *Location) Nearest(
+	ctx context.Context,
+	latitude float64,
+	longitude float64,
+	k int,
+) ([]models.LocationDistance, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	center := geo.Point{Latitude: latitude, Longitude: longitude}
+	if !center.IsValid() {
+		return nil, fmt.Errorf("%w: %v,%v", geo.ErrInvalidPoint, latitude, longitude)
+	}
+
+	neighbors := l.index.Nearest(center, k)
+	result := make([]models.LocationDistance, 0, len(neighbors))
+
+	for _, neighbor := range neighbors {
+		result = append(result, models.LocationDistance{Location: neighbor.Value, Distance: neighbor.Distance})
+	}
+
+	return result, nil
+}
+
+// Share grants a principal write access (models.AccessWrite) to a location, and returns the location. Locations can
+// be read by everyone, so there is no read access to grant. Only the owner of the location, or an admin, may share it.
+func (l *Location) Share(ctx context.Context, id int, principalID string, access string) (models.Location, error) {
+	input := types.GrantInput{Access: access}
+
+	err := input.Validate(models.AccessWrite)
+	if err != nil {
+		return models.Location{}, err
+	}
+
+	return l.regrant(ctx, id, principalID, access)
+}
+
+// Unshare takes back the access granted to a principal to a location, and returns the location. Only the owner of the
+// location, or an admin, may do so.
+func (l *Location) Unshare(ctx context.Context, id int, principalID string) (models.Location, error) {
+	return l.regrant(ctx, id, principalID, "")
+}
+
+// regrant sets the access of a principal to a location; none when empty.
+func (l *Location) regrant(ctx context.Context, id int, principalID string, access string) (models.Location, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Location{}, types.ErrCancelledContext
+	}
+
+	l.writes.Lock()
+	defer l.writes.Unlock()
+
+	current, err := l.db.ByID(ctx, id)
+	if err == nil {
+		err = own(ctx, current.Owner)
+	}
+
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not share Location entry: %w", err)
+	}
+
+	current.Grants, current.UpdatedBy = share(current.Grants, principalID, access), author(ctx)
+
+	err = l.db.Update(ctx, current)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not share Location entry: %w", err)
+	}
+
+	l.index.Set(current.ID, position(current), current)
+
+	return current, nil
+}
+
+// changeable returns types.ErrForbidden when the caller of a request may not change a location. Unlike data entries,
+// locations can be read by everyone, so there is nothing to hide.
+func changeable(ctx context.Context, location models.Location) error {
+	principal, found := types.PrincipalFrom(ctx)
+	if !found || principal.HasScope(types.ScopeAdmin) ||
+		models.Allows(location.Owner, location.Grants, principal.ID, models.AccessWrite) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: write access required", types.ErrForbidden)
+}
+
+// position returns the point of a location.
+func position(location models.Location) geo.Point {
+	return geo.Point{Latitude: float64(location.Latitude), Longitude: float64(location.Longitude)}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wakka-2/Namless/backend/pkg/blob"
+	"github.com/wakka-2/Namless/backend/pkg/imaging"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// thumbnailSizes are the thumbnails made of uploaded images, by name: each fits in a size x size square.
+var thumbnailSizes = map[string]int{
+	"small":  128,
+	"medium": 512,
+}
+
+// SetImage makes an uploaded image the image of the location with a given ID, and returns the location.
+//
+// The image is stored as it is, along with its thumbnails; the location gets their URLs. Returns
+// imaging.ErrUnsupportedImage when the content is not a supported image.
+func (l *Location) SetImage(ctx context.Context, id int, content []byte) (models.Location, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.Location{}, types.ErrCancelledContext
+	}
+
+	// no need to store anything for a missing location, or one the caller may not change
+	current, err := l.db.ByID(ctx, id)
+	if err == nil {
+		err = changeable(ctx, current)
+	}
+
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not retrieve Location entry: %w", err)
+	}
+
+	source, _, err := imaging.Decode(content)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not read image: %w", err)
+	}
+
+	hash, err := l.blobs.Put(ctx, content)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("could not store image: %w", err)
+	}
+
+	thumbnails := make(map[string]string, len(thumbnailSizes))
+
+	for name, size := range thumbnailSizes {
+		thumbnail, _, err := imaging.Encode(imaging.Thumbnail(source, size))
+		if err != nil {
+			return models.Location{}, fmt.Errorf("could not make %s thumbnail: %w", name, err)
+		}
+
+		thumbnailHash, err := l.blobs.Put(ctx, thumbnail)
+		if err != nil {
+			return models.Location{}, fmt.Errorf("could not store %s thumbnail: %w", name, err)
+		}
+
+		thumbnails[name] = types.BlobURL(thumbnailHash)
+	}
+
+	return l.modify(ctx, id, func(current models.Location) (models.Location, error) {
+		current.Image, current.Thumbnails = types.BlobURL(hash), thumbnails
+
+		return current, nil
+	})
+}
+
+// Blob returns the uploaded image (or thumbnail) with a given hash. Callers must close it.
+func (l *Location) Blob(ctx context.Context, hash string) (blob.Blob, error) {
+	if l.serverCtx.Err() != nil || ctx.Err() != nil {
+		return nil, types.ErrCancelledContext
+	}
+
+	result, err := l.blobs.Get(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve blob: %w", err)
+	}
+
+	return result, nil
+}
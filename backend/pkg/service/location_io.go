@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/wakka-2/Namless/backend/pkg/locationio"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// ImportFrom imports the locations read from a stream, one at a time, and passes the outcome of each to report.
+//
+// Bad records are reported, with their line, and skipped; see Import for the others. Stops at the first error of
+// reader or report.
+func (l *Location) ImportFrom(
+	ctx context.Context,
+	reader locationio.Reader,
+	dryRun bool,
+	report func(result types.ImportResult) error,
+) error {
+	for index := 0; ; index++ {
+		input, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var (
+			invalid *types.ValidationError
+			result  types.ImportResult
+		)
+
+		switch {
+		case errors.As(err, &invalid):
+			result = types.ImportResult{
+				Outcome: types.ImportInvalid, Error: types.ErrInvalidInput.Error(), Fields: invalid.Fields,
+			}
+		case err != nil:
+			return fmt.Errorf("could not read line %d: %w", line, err)
+		default:
+			result, err = l.Import(ctx, input, dryRun)
+			if err != nil {
+				return fmt.Errorf("could not import line %d: %w", line, err)
+			}
+		}
+
+		result.Index, result.Line = index, line
+
+		err = report(result)
+		if err != nil {
+			return fmt.Errorf("could not report line %d: %w", line, err)
+		}
+	}
+}
+
+// ExportTo writes every location to a stream, by ascending ID, and closes it.
+//
+// Locations are read a page at a time, so changes made meanwhile may or may not be exported.
+func (l *Location) ExportTo(ctx context.Context, writer locationio.Writer) error {
+	opts := types.ListOptions{Limit: types.MaxLimit}
+
+	for {
+		if l.serverCtx.Err() != nil || ctx.Err() != nil {
+			return types.ErrCancelledContext
+		}
+
+		page, err := l.db.List(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("could not retrieve locations: %w", err)
+		}
+
+		for _, location := range page.Items {
+			err = writer.Write(location)
+			if err != nil {
+				return fmt.Errorf("could not export location %d: %w", location.ID, err)
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+
+		opts.Cursor = page.NextCursor
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return fmt.Errorf("could not export locations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// Quota bounds what a namespace can hold; zero fields are unlimited.
+type Quota struct {
+	// MaxKeys is the largest number of data entries.
+	MaxKeys int64
+	// MaxValueBytes is the largest size of a value.
+	MaxValueBytes int64
+	// MaxTotalBytes is the largest total size of the keys and values.
+	MaxTotalBytes int64
+}
+
+// Quotas gives every namespace its quota.
+type Quotas struct {
+	// Default is the quota of the namespaces that are not in Namespaces.
+	Default Quota
+	// Namespaces holds the quotas of some namespaces, by name.
+	Namespaces map[string]Quota
+}
+
+// of returns the quota of a namespace.
+func (q Quotas) of(namespace string) Quota {
+	if result, found := q.Namespaces[namespace]; found {
+		return result
+	}
+
+	return q.Default
+}
+
+// admits returns nil when the namespace of a given repository can take item, in place of replaced (nil when the key
+// is new). Returns types.ErrValueTooLarge or types.ErrQuotaExceeded otherwise.
+//
+// Writes that do not make the namespace grow are admitted even when it is over its quota, so that it can get back
+// under it. The usage is only read when needed, so callers that need it exact must call admits in a transaction.
+func (q Quota) admits(ctx context.Context, db repository.DataStore, item models.Data, replaced *models.Data) error {
+	if q.MaxValueBytes > 0 && int64(len(item.Value)) > q.MaxValueBytes {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", types.ErrValueTooLarge, len(item.Value), q.MaxValueBytes)
+	}
+
+	keys, bytes := int64(1), size(item)
+	if replaced != nil {
+		keys, bytes = 0, bytes-size(*replaced)
+	}
+
+	if (q.MaxKeys <= 0 || keys <= 0) && (q.MaxTotalBytes <= 0 || bytes <= 0) {
+		return nil
+	}
+
+	usage, err := db.Usage(ctx)
+	if err != nil {
+		return fmt.Errorf("could not check quota: %w", err)
+	}
+
+	if q.MaxKeys > 0 && keys > 0 && usage.Keys+keys > q.MaxKeys {
+		return fmt.Errorf("%w: at most %d keys allowed", types.ErrQuotaExceeded, q.MaxKeys)
+	}
+
+	if q.MaxTotalBytes > 0 && bytes > 0 && usage.Bytes+bytes > q.MaxTotalBytes {
+		return fmt.Errorf("%w: at most %d bytes allowed, %d used", types.ErrQuotaExceeded, q.MaxTotalBytes, usage.Bytes)
+	}
+
+	return nil
+}
+
+// size returns the number of bytes a data entry counts for in the quota of its namespace.
+func size(item models.Data) int64 {
+	return int64(len(item.ID) + len(item.Value))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+func Test_Quotas(t *testing.T) {
+	background := context.Background()
+	small := types.WithNamespace(background, "small")
+	large := types.WithNamespace(background, "large")
+
+	data := New(background, repository.NewMemory(), Quotas{
+		Default: Quota{MaxKeys: 2, MaxValueBytes: 8, MaxTotalBytes: 12},
+		Namespaces: map[string]Quota{
+			"large": {},
+		},
+	})
+
+	// namespaces do not share keys
+	_, err := data.Add(small, types.Pair{Key: "a", Value: "small"})
+	assert.NoError(t, err)
+
+	_, err = data.Add(large, types.Pair{Key: "a", Value: "large"})
+	assert.NoError(t, err)
+
+	result, err := data.Get(large, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "large", result.Value)
+
+	_, err = data.Get(background, "a")
+	assert.ErrorIs(t, err, repository.ErrDoesNotExist)
+
+	_, err = data.Add(small, types.Pair{Key: "b", Value: strings.Repeat("x", 9)})
+	assert.ErrorIs(t, err, types.ErrValueTooLarge)
+
+	_, err = data.Add(large, types.Pair{Key: "b", Value: strings.Repeat("x", 9)})
+	assert.NoError(t, err, "large has no quota")
+
+	// a (6 bytes) + b (6 bytes) fill the 12 bytes of small
+	_, err = data.Add(small, types.Pair{Key: "b", Value: "12345"})
+	assert.NoError(t, err)
+
+	_, err = data.Update(small, types.Pair{Key: "b", Value: "123456"}, types.Precondition{})
+	assert.ErrorIs(t, err, types.ErrQuotaExceeded)
+
+	// writes that do not grow the namespace go through
+	_, err = data.Update(small, types.Pair{Key: "b", Value: "1234"}, types.Precondition{})
+	assert.NoError(t, err)
+
+	_, err = data.Add(small, types.Pair{Key: "c"})
+	assert.ErrorIs(t, err, types.ErrQuotaExceeded, "small holds 2 keys at most")
+
+	absent := false
+
+	results, err := data.Batch(small, types.BatchInput{Operations: []types.BatchOperation{
+		{Op: types.BatchDelete, Pair: types.Pair{Key: "a"}},
+		{Op: types.BatchPut, Pair: types.Pair{Key: "c", Value: "1"}, Exists: &absent},
+		{Op: types.BatchPut, Pair: types.Pair{Key: "d", Value: "1"}, Exists: &absent},
+	}})
+	assert.ErrorIs(t, err, types.ErrQuotaExceeded)
+	assert.Equal(t, types.ErrQuotaExceeded.Error(), results[2].Error)
+
+	usage, err := data.Usage(small)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Usage{Keys: 2, Bytes: 11}, usage, "the batch should be rolled back")
+
+	// deleted keys free their quota, until they are restored
+	assert.NoError(t, data.Delete(small, "a", types.Precondition{}))
+
+	_, err = data.Add(small, types.Pair{Key: "c", Value: "123456"})
+	assert.NoError(t, err)
+
+	_, err = data.Restore(small, "a")
+	assert.ErrorIs(t, err, types.ErrQuotaExceeded)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// idBytes is the number of random bytes in the IDs of mint jobs and claims.
+const idBytes = 16
+
+// ErrMintingDisabled for when no minting service is configured.
+var ErrMintingDisabled = errors.New("minting is not configured")
+
+// Token offers token-related functionality, on top of a minting service.
+//
+// Tokens are minted in the background: requests are saved as mint jobs, and processed by the workers of RunWorkers.
+type Token struct {
+	minter    minting.Minter
+	jobs      repository.MintJobStore
+	serverCtx context.Context
+	// wake tells an idle worker that a job was enqueued.
+	wake chan struct{}
+}
+
+// NewToken builds a new token service; a nil minter disables minting.
+func NewToken(ctx context.Context, minter minting.Minter, jobs repository.MintJobStore) *Token {
+	return &Token{
+		minter:    minter,
+		jobs:      jobs,
+		serverCtx: ctx,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Enqueue a job that uploads the token of the input, mints it, and sends it to the receiver of the input.
+func (t *Token) Enqueue(ctx context.Context, input types.TokenInput) (models.MintJob, error) {
+	err := t.check(ctx)
+	if err != nil {
+		return models.MintJob{}, err
+	}
+
+	token, err := input.Token()
+	if err != nil {
+		return models.MintJob{}, err
+	}
+
+	return t.enqueue(ctx, models.MintJob{Token: &token, Receiver: input.Receiver})
+}
+
+// EnqueueMint enqueues a job that mints the uploaded token with a given UID, and sends it to the receiver of the
+// input.
+func (t *Token) EnqueueMint(ctx context.Context, uid string, input types.MintInput) (models.MintJob, error) {
+	err := t.check(ctx)
+	if err != nil {
+		return models.MintJob{}, err
+	}
+
+	err = input.Validate()
+	if err != nil {
+		return models.MintJob{}, err
+	}
+
+	return t.enqueue(ctx, models.MintJob{UID: uid, Receiver: input.Receiver})
+}
+
+// Job returns the mint job with a given ID.
+func (t *Token) Job(ctx context.Context, jobID string) (models.MintJob, error) {
+	if t.serverCtx.Err() != nil || ctx.Err() != nil {
+		return models.MintJob{}, types.ErrCancelledContext
+	}
+
+	result, err := t.jobs.ByID(ctx, jobID)
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not get mint job: %w", err)
+	}
+
+	return result, nil
+}
+
+// Status returns the state of the uploaded token with a given UID.
+func (t *Token) Status(ctx context.Context, uid string) (minting.Status, error) {
+	err := t.check(ctx)
+	if err != nil {
+		return minting.Status{}, err
+	}
+
+	result, err := t.minter.Status(ctx, uid)
+	if err != nil {
+		return minting.Status{}, fmt.Errorf("could not get status: %w", err)
+	}
+
+	return result, nil
+}
+
+// enqueue saves a new job, due at once, and wakes a worker. The job gets a new ID, unless it has one.
+func (t *Token) enqueue(ctx context.Context, job models.MintJob) (models.MintJob, error) {
+	if job.ID == "" {
+		var err error
+
+		job.ID, err = newID()
+		if err != nil {
+			return models.MintJob{}, fmt.Errorf("could not generate job ID: %w", err)
+		}
+	}
+
+	job.State = models.JobQueued
+	job.NextAttemptAt = time.Now()
+
+	result, err := t.jobs.Create(ctx, job)
+	if err != nil {
+		return models.MintJob{}, fmt.Errorf("could not enqueue mint job: %w", err)
+	}
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+
+	return result, nil
+}
+
+// check returns an error when minting is disabled, or a context was cancelled.
+func (t *Token) check(ctx context.Context) error {
+	if t.minter == nil {
+		return ErrMintingDisabled
+	}
+
+	if t.serverCtx.Err() != nil || ctx.Err() != nil {
+		return types.ErrCancelledContext
+	}
+
+	return nil
+}
+
+// newID returns a new random ID, in hexadecimal.
+func newID() (string, error) {
+	id := make([]byte, idBytes)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("could not read random bytes: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
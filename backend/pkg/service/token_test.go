@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+	"github.com/wakka-2/Namless/backend/pkg/types"
+)
+
+// testMintConfig retries at once, so that tests do not wait.
+var testMintConfig = MintConfig{
+	Workers:      2,
+	MaxAttempts:  3,
+	RetryBase:    time.Millisecond,
+	RetryMax:     time.Millisecond,
+	Lease:        time.Minute,
+	PollInterval: time.Millisecond,
+}
+
+func Test_MintJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := minting.NewFake()
+	tokens := NewToken(ctx, fake, repository.NewMemoryMintJob())
+
+	// a temporary failure is retried
+	fake.Fail(minting.ErrUnavailable)
+
+	job, err := tokens.Enqueue(ctx, types.TokenInput{Tokenname: "Token1", FileFromBase64: "iVBORw0KGgo="})
+	assert.NoError(t, err)
+	assert.Equal(t, models.JobQueued, job.State)
+
+	_, err = tokens.Enqueue(ctx, types.TokenInput{Tokenname: "Token 1"})
+	assert.ErrorIs(t, err, types.ErrInvalidInput)
+
+	go tokens.RunWorkers(testMintConfig)
+
+	done := waitForJob(t, tokens, job.ID)
+	assert.Equal(t, models.JobDone, done.State)
+	assert.Equal(t, 2, done.Attempts)
+	assert.Empty(t, done.LastError)
+	assert.Nil(t, done.Token)
+	assert.Equal(t, minting.FakeReceiver, done.Receiver)
+
+	status, err := tokens.Status(ctx, done.UID)
+	assert.NoError(t, err)
+	assert.True(t, status.Minted)
+
+	// minting it again is refused for good
+	again, err := tokens.EnqueueMint(ctx, done.UID, types.MintInput{})
+	assert.NoError(t, err)
+
+	failed := waitForJob(t, tokens, again.ID)
+	assert.Equal(t, models.JobFailed, failed.State)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Contains(t, failed.LastError, "already minted")
+}
+
+func Test_MintJobRecovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := minting.NewFake()
+	jobs := repository.NewMemoryMintJob()
+	tokens := NewToken(ctx, fake, jobs)
+
+	upload, err := fake.Upload(ctx, minting.Token{Name: "Token1"})
+	assert.NoError(t, err)
+
+	job, err := tokens.EnqueueMint(ctx, upload.UID, types.MintInput{})
+	assert.NoError(t, err)
+
+	// a worker claims the job, mints the token, and crashes before recording it
+	claimed, err := jobs.Claim(ctx, time.Now(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	_, err = fake.MintAndSend(ctx, upload.UID, "")
+	assert.NoError(t, err)
+
+	go tokens.RunWorkers(testMintConfig)
+
+	done := waitForJob(t, tokens, job.ID)
+	assert.Equal(t, models.JobDone, done.State, "the token is not minted twice: %s", done.LastError)
+	assert.Equal(t, 2, done.Attempts)
+}
+
+func Test_UploadRecovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := minting.NewFake()
+	jobs := repository.NewMemoryMintJob()
+	tokens := NewToken(ctx, fake, jobs)
+
+	job, err := tokens.Enqueue(ctx, types.TokenInput{Tokenname: "Token1", FileFromBase64: "iVBORw0KGgo="})
+	assert.NoError(t, err)
+
+	// a worker claims the job, uploads the token, and crashes before recording its UID
+	claimed, err := jobs.Claim(ctx, time.Now(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	upload, err := fake.Upload(ctx, *claimed.Token)
+	assert.NoError(t, err)
+
+	go tokens.RunWorkers(testMintConfig)
+
+	done := waitForJob(t, tokens, job.ID)
+	assert.Equal(t, models.JobDone, done.State, "the token is not uploaded twice: %s", done.LastError)
+	assert.Equal(t, 2, done.Attempts)
+	assert.Equal(t, upload.UID, done.UID)
+
+	// the upload, its lookup, the status check and the mint
+	assert.Equal(t, 4, fake.Calls())
+}
+
+func Test_MintingDisabled(t *testing.T) {
+	tokens := NewToken(context.Background(), nil, repository.NewMemoryMintJob())
+
+	_, err := tokens.EnqueueMint(context.Background(), "uid", types.MintInput{})
+	assert.ErrorIs(t, err, ErrMintingDisabled)
+
+	// returns at once
+	tokens.RunWorkers(testMintConfig)
+}
+
+func Test_MintBackoff(t *testing.T) {
+	cfg := MintConfig{RetryBase: time.Second, RetryMax: time.Minute}
+
+	assert.Equal(t, time.Second, cfg.backoff(1))
+	assert.Equal(t, 4*time.Second, cfg.backoff(3))
+	assert.Equal(t, time.Minute, cfg.backoff(10))
+	assert.Equal(t, time.Minute, cfg.backoff(1_000))
+}
+
+// waitForJob returns the job with a given ID once it is finished.
+func waitForJob(t *testing.T, tokens *Token, jobID string) models.MintJob {
+	t.Helper()
+
+	var result models.MintJob
+
+	assert.Eventually(t, func() bool {
+		var err error
+
+		result, err = tokens.Job(context.Background(), jobID)
+
+		return err == nil && result.IsFinished()
+	}, 5*time.Second, time.Millisecond)
+
+	return result
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+	"github.com/wakka-2/Namless/backend/pkg/repository"
+)
+
+// MintConfig configures the workers that process mint jobs.
+type MintConfig struct {
+	// Workers is the number of jobs processed at once.
+	Workers int
+	// MaxAttempts is the number of times a job is tried before it fails.
+	MaxAttempts int
+	// RetryBase is the wait before the first retry; it doubles with every attempt, up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// Lease is how long a worker holds a job before another one can take it: it must outlast the calls to the minting
+	// service.
+	Lease time.Duration
+	// PollInterval is the time between two looks for due jobs, when none was enqueued meanwhile.
+	PollInterval time.Duration
+}
+
+// backoff returns the wait before the next attempt of a job, after a given number of attempts.
+func (mc MintConfig) backoff(attempts int) time.Duration {
+	result := mc.RetryBase
+
+	for i := 1; i < attempts && result < mc.RetryMax; i++ {
+		result *= 2
+	}
+
+	return min(result, mc.RetryMax)
+}
+
+// RunWorkers processes mint jobs until the server context is cancelled; it does nothing when minting is disabled.
+//
+// Jobs are leased while they are processed, so every job is held by a single worker, of this process or of another
+// one sharing the storage. Failures the minting service deems temporary are retried, with exponential backoff. Jobs
+// left behind by a crash are taken again once their lease ends; a job that may have minted its token already checks
+// its status first, so that a token is never minted twice.
+func (t *Token) RunWorkers(cfg MintConfig) {
+	if t.minter == nil {
+		return
+	}
+
+	var group sync.WaitGroup
+
+	for range cfg.Workers {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			t.work(cfg)
+		}()
+	}
+
+	group.Wait()
+}
+
+// work claims and processes due jobs, one at a time; it waits for an enqueued job, or for the next poll, when there
+// are none.
+func (t *Token) work(cfg MintConfig) {
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for t.serverCtx.Err() == nil {
+		now := time.Now()
+
+		job, err := t.jobs.Claim(t.serverCtx, now, now.Add(cfg.Lease))
+		if err == nil {
+			t.process(job, cfg)
+
+			continue
+		}
+
+		if !errors.Is(err, repository.ErrDoesNotExist) && t.serverCtx.Err() == nil {
+			log.Default().Printf("could not claim mint job: %s", err)
+		}
+
+		select {
+		case <-t.serverCtx.Done():
+		case <-t.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// process runs a claimed job, and records its failure: it is retried later, or it fails for good.
+func (t *Token) process(job models.MintJob, cfg MintConfig) {
+	err := t.run(t.serverCtx, &job)
+	if err == nil {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrVersionMismatch):
+		log.Default().Printf("mint job %s was taken by another worker", job.ID)
+
+		return
+	case t.serverCtx.Err() != nil:
+		// shutting down: the job is taken again once its lease ends
+		return
+	}
+
+	job.LastError = err.Error()
+	job.LeaseUntil = nil
+
+	if minting.IsRetryable(err) && job.Attempts < cfg.MaxAttempts {
+		job.State = models.JobQueued
+		job.NextAttemptAt = time.Now().Add(cfg.backoff(job.Attempts))
+	} else {
+		job.State = models.JobFailed
+	}
+
+	err = t.save(t.serverCtx, &job)
+	if err != nil {
+		log.Default().Printf("could not record the failure of mint job %s: %s", job.ID, err)
+	}
+}
+
+// run uploads the token of a job (unless it was), then mints it. The job is saved before every call to the minting
+// service, so that its state tells how far it got.
+func (t *Token) run(ctx context.Context, job *models.MintJob) error {
+	if job.UID == "" {
+		job.State = models.JobUploading
+
+		err := t.save(ctx, job)
+		if err != nil {
+			return err
+		}
+
+		upload, err := t.upload(ctx, job)
+		if err != nil {
+			return err
+		}
+
+		// the token is no longer needed, and it can be large
+		job.UID = upload.UID
+		job.Token = nil
+	}
+
+	job.State = models.JobMinting
+
+	err := t.save(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	minted := false
+
+	// an earlier attempt may have minted the token, and crashed (or timed out) before recording it
+	if job.Attempts > 1 {
+		minted, err = t.isMinted(ctx, job.UID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !minted {
+		mint, err := t.minter.MintAndSend(ctx, job.UID, job.Receiver)
+		if err != nil {
+			return fmt.Errorf("could not mint: %w", err)
+		}
+
+		job.MintID = mint.ID
+		job.Receiver = mint.Receiver
+	}
+
+	job.State = models.JobDone
+	job.LeaseUntil = nil
+	job.LastError = ""
+
+	return t.save(ctx, job)
+}
+
+// upload the token of a job. Token names are unique in the minting service, so the name (saved with the job before
+// the first upload) keys the upload: an earlier attempt may have uploaded the token, and crashed (or timed out) before
+// recording its UID, in which case that upload is reused rather than paid for again.
+func (t *Token) upload(ctx context.Context, job *models.MintJob) (minting.Upload, error) {
+	if job.Attempts > 1 {
+		found, err := t.minter.Find(ctx, job.Token.Name)
+		if err == nil {
+			return found, nil
+		}
+
+		if !errors.Is(err, minting.ErrNotFound) {
+			return minting.Upload{}, fmt.Errorf("could not check whether the token was uploaded: %w", err)
+		}
+	}
+
+	result, err := t.minter.Upload(ctx, *job.Token)
+	if err != nil {
+		return minting.Upload{}, fmt.Errorf("could not upload: %w", err)
+	}
+
+	return result, nil
+}
+
+// save a job, and keeps the result (with its new version) in place.
+func (t *Token) save(ctx context.Context, job *models.MintJob) error {
+	result, err := t.jobs.Update(ctx, *job)
+	if err != nil {
+		return fmt.Errorf("could not save mint job: %w", err)
+	}
+
+	*job = result
+
+	return nil
+}
+
+// isMinted tells whether the uploaded token with a given UID was minted; a token being minted is an error, worth
+// retrying.
+func (t *Token) isMinted(ctx context.Context, uid string) (bool, error) {
+	status, err := t.minter.Status(ctx, uid)
+	if err != nil {
+		return false, fmt.Errorf("could not check whether the token was minted: %w", err)
+	}
+
+	if status.State == minting.StateReserved {
+		return false, fmt.Errorf("%w: token %q is being minted", minting.ErrUnavailable, uid)
+	}
+
+	return status.Minted, nil
+}
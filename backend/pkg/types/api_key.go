@@ -0,0 +1,42 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxAPIKeyName is the largest number of characters in the name of an API key.
+const MaxAPIKeyName = 100
+
+// APIKeyInput models the body of a request issuing an API key.
+type APIKeyInput struct {
+	// Name tells what (or who) the key is for.
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// Validate returns a ValidationError listing every invalid field.
+func (ai *APIKeyInput) Validate() error {
+	problems := &ValidationError{}
+
+	switch {
+	case strings.TrimSpace(ai.Name) == "":
+		problems.Add("name", "must not be empty")
+	case utf8.RuneCountInString(ai.Name) > MaxAPIKeyName:
+		problems.Add("name", fmt.Sprintf("must be at most %d characters", MaxAPIKeyName))
+	}
+
+	if len(ai.Scopes) == 0 {
+		problems.Add("scopes", "must not be empty")
+	}
+
+	for _, scope := range ai.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			problems.Add("scopes", fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+
+	return problems.OrNil()
+}
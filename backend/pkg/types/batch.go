@@ -0,0 +1,95 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// BatchPut writes a key value pair, like PUT /data.
+	BatchPut = "put"
+	// BatchDelete deletes a key value pair, like DELETE /data/{key}.
+	BatchDelete = "delete"
+	// BatchCheck only checks the precondition of a key value pair; it exists when there is none.
+	BatchCheck = "check"
+
+	// MaxBatchOperations is the largest number of operations a batch can hold.
+	MaxBatchOperations = 100
+)
+
+var (
+	// ErrInvalidBatch for when a batch is empty, too large, or holds an unknown operation.
+	ErrInvalidBatch = errors.New("invalid batch")
+)
+
+// BatchOperation models a single operation of a batch.
+type BatchOperation struct {
+	// Op is one of BatchPut, BatchDelete or BatchCheck.
+	Op string `json:"op"`
+	Pair
+	// Version, when set, is the version the pair must be at.
+	Version *int64 `json:"version,omitempty"`
+	// Exists, when set, tells whether the pair must exist. A put of a pair that must not exist creates it.
+	Exists *bool `json:"exists,omitempty"`
+}
+
+// Precondition returns the conditions the operation puts on the current version of the pair.
+func (bo *BatchOperation) Precondition() Precondition {
+	result := Precondition{}
+
+	switch {
+	case bo.Version != nil:
+		result.IfMatch = ETagCondition{Present: true, Versions: []int64{*bo.Version}}
+	case bo.Exists != nil && *bo.Exists, bo.Exists == nil && bo.Op == BatchCheck:
+		result.IfMatch = ETagCondition{Present: true, Any: true}
+	}
+
+	if bo.Exists != nil && !*bo.Exists {
+		result.IfNoneMatch = ETagCondition{Present: true, Any: true}
+	}
+
+	return result
+}
+
+// BatchInput models a request to apply operations all or nothing.
+type BatchInput struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// Validate checks that the batch is not empty, not too large, and that it only holds known operations.
+func (bi *BatchInput) Validate() error {
+	if len(bi.Operations) == 0 || len(bi.Operations) > MaxBatchOperations {
+		return fmt.Errorf("%w: expected between 1 and %d operations", ErrInvalidBatch, MaxBatchOperations)
+	}
+
+	for i, operation := range bi.Operations {
+		switch operation.Op {
+		case BatchPut, BatchDelete, BatchCheck:
+		default:
+			return fmt.Errorf("%w: unknown operation %q at %d", ErrInvalidBatch, operation.Op, i)
+		}
+
+		if operation.Key == "" {
+			return fmt.Errorf("%w: missing key at %d", ErrInvalidBatch, i)
+		}
+	}
+
+	return nil
+}
+
+// BatchResult models the outcome of a single operation of a batch.
+type BatchResult struct {
+	Op  string `json:"op"`
+	Key string `json:"key"`
+	// Version is the version of the pair after the operation.
+	Version int64 `json:"version,omitempty"`
+	// Error tells why the operation failed; empty when it did not.
+	Error string `json:"error,omitempty"`
+}
+
+// BatchOutput models the outcome of a batch.
+type BatchOutput struct {
+	// Committed is false when an operation failed, and none were kept.
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
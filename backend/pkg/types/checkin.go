@@ -0,0 +1,40 @@
+package types
+
+import "fmt"
+
+// CheckinInput models the body of a request checking in at a location.
+type CheckinInput struct {
+	// Latitude and Longitude are where the check-in is made from, in degrees.
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// Wallet is the address the earned token is sent to.
+	Wallet string `json:"wallet"`
+}
+
+// Validate returns a ValidationError listing every invalid field.
+func (ci *CheckinInput) Validate() error {
+	problems := &ValidationError{}
+
+	switch {
+	case ci.Latitude == nil:
+		problems.Add("latitude", "is required")
+	case *ci.Latitude < -maxLatitude || *ci.Latitude > maxLatitude:
+		problems.Add("latitude", fmt.Sprintf("must be in [-%d, %d]", maxLatitude, maxLatitude))
+	}
+
+	switch {
+	case ci.Longitude == nil:
+		problems.Add("longitude", "is required")
+	case *ci.Longitude < -maxLongitude || *ci.Longitude > maxLongitude:
+		problems.Add("longitude", fmt.Sprintf("must be in [-%d, %d]", maxLongitude, maxLongitude))
+	}
+
+	switch {
+	case ci.Wallet == "":
+		problems.Add("wallet", "is required")
+	case !IsWalletAddress(ci.Wallet):
+		problems.Add("wallet", "must be a Cardano wallet address")
+	}
+
+	return problems.OrNil()
+}
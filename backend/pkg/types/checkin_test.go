@@ -0,0 +1,28 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckinInputValidate(t *testing.T) {
+	latitude, longitude := 44.43, 26.1
+	wallet := "addr_test1qpfakewa77etfakewa77etfakewa77etfakewa77etfakewa77etfakewa77etq0"
+
+	assert.NoError(t, (&CheckinInput{Latitude: &latitude, Longitude: &longitude, Wallet: wallet}).Validate())
+
+	tooFar := -91.0
+	err := (&CheckinInput{Latitude: &tooFar, Wallet: "addr1"}).Validate()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	var invalid *ValidationError
+
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []FieldError{
+		{Field: "latitude", Message: "must be in [-90, 90]"},
+		{Field: "longitude", Message: "is required"},
+		{Field: "wallet", Message: "must be a Cardano wallet address"},
+	}, invalid.Fields)
+}
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+const (
+	// GeoJSONFeatureCollection is the type of GeoJSON feature collections.
+	GeoJSONFeatureCollection = "FeatureCollection"
+	// GeoJSONFeature is the type of GeoJSON features.
+	GeoJSONFeature = "Feature"
+	// GeoJSONPoint is the type of GeoJSON points, the only geometry locations have.
+	GeoJSONPoint = "Point"
+
+	// pointCoordinates is the number of coordinates of a point: longitude and latitude, in this order.
+	pointCoordinates = 2
+)
+
+// FeatureCollection models a GeoJSON feature collection (RFC 7946).
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+	// NextCursor and Total are foreign members, set when the collection is a page of a listing (see Page).
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// Feature models a GeoJSON feature.
+type Feature struct {
+	Type string `json:"type"`
+	// ID is a number or a string, when set.
+	ID         any            `json:"id,omitempty"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry models a GeoJSON geometry. Coordinates are kept raw, as their shape depends on the type.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LocationFeature returns a location as a GeoJSON point feature, with its name and image as properties.
+func LocationFeature(location models.Location) Feature {
+	coordinates := []byte{'['}
+	coordinates = strconv.AppendFloat(coordinates, float64(location.Longitude), 'g', -1, 32)
+	coordinates = append(coordinates, ',')
+	coordinates = strconv.AppendFloat(coordinates, float64(location.Latitude), 'g', -1, 32)
+	coordinates = append(coordinates, ']')
+
+	return Feature{
+		Type:     GeoJSONFeature,
+		ID:       location.ID,
+		Geometry: &Geometry{Type: GeoJSONPoint, Coordinates: coordinates},
+		Properties: map[string]any{
+			"location": location.Location,
+			"image":    location.Image,
+		},
+	}
+}
+
+// LocationInput returns the location a GeoJSON point feature describes.
+//
+// The ID is the one of the feature, or else its "id" property; the name and the image are its "location" and "image"
+// properties. Returns a ValidationError when the feature does not fit.
+func (f *Feature) LocationInput() (LocationInput, error) {
+	result := LocationInput{}
+	problems := &ValidationError{}
+
+	if f.Type != GeoJSONFeature {
+		problems.Add("type", "must be "+GeoJSONFeature)
+	}
+
+	var coordinates []float64
+
+	switch {
+	case f.Geometry == nil || f.Geometry.Type != GeoJSONPoint:
+		problems.Add("geometry", "must be a "+GeoJSONPoint)
+	case json.Unmarshal(f.Geometry.Coordinates, &coordinates) != nil || len(coordinates) < pointCoordinates:
+		problems.Add("geometry.coordinates", "must be [longitude, latitude]")
+	default:
+		longitude, latitude := float32(coordinates[0]), float32(coordinates[1])
+		result.Longitude, result.Latitude = &longitude, &latitude
+	}
+
+	id := f.ID
+	if id == nil {
+		id = f.Properties["id"]
+	}
+
+	if id != nil {
+		value, valid := featureID(id)
+		if valid {
+			result.ID = &value
+		} else {
+			problems.Add("id", "must be an integer")
+		}
+	}
+
+	result.Location = stringProperty(f.Properties, "location", problems)
+	result.Image = stringProperty(f.Properties, "image", problems)
+
+	return result, problems.OrNil()
+}
+
+// stringProperty returns the string property of a feature with a given name; nil when it is not set.
+func stringProperty(properties map[string]any, name string, problems *ValidationError) *string {
+	value, found := properties[name]
+	if !found || value == nil {
+		return nil
+	}
+
+	result, isString := value.(string)
+	if !isString {
+		problems.Add("properties."+name, "must be a string")
+
+		return nil
+	}
+
+	return &result
+}
+
+// featureID returns the ID of a feature, which is a number or a string holding one, and whether it is an integer.
+func featureID(id any) (int, bool) {
+	switch value := id.(type) {
+	case float64:
+		// JSON numbers are decoded as float64
+		return int(value), value == math.Trunc(value) && math.Abs(value) <= math.MaxInt32
+	case string:
+		result, err := strconv.Atoi(value)
+
+		return result, err == nil
+	}
+
+	return 0, false
+}
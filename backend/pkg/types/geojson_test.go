@@ -0,0 +1,46 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+func Test_LocationFeature(t *testing.T) {
+	location := models.Location{ID: 3, Location: "Paris", Latitude: 48.8566, Longitude: 2.3522, Image: "https://a.b/c.png"}
+
+	asJSON, err := json.Marshal(LocationFeature(location))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "Feature",
+		"id": 3,
+		"geometry": {"type": "Point", "coordinates": [2.3522, 48.8566]},
+		"properties": {"location": "Paris", "image": "https://a.b/c.png"}
+	}`, string(asJSON))
+
+	feature := Feature{}
+
+	err = json.Unmarshal(asJSON, &feature)
+	assert.NoError(t, err)
+
+	input, err := feature.LocationInput()
+	assert.NoError(t, err)
+
+	result, err := input.Apply(models.Location{ID: 3}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, location, result)
+
+	err = json.Unmarshal([]byte(`{
+		"type": "Feature",
+		"id": 1.5,
+		"geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]},
+		"properties": {"location": 7}
+	}`), &feature)
+	assert.NoError(t, err)
+
+	_, err = feature.LocationInput()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.ErrorContains(t, err, "geometry: must be a Point; id: must be an integer; properties.location: must be")
+}
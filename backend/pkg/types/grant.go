@@ -0,0 +1,24 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// GrantInput models the body of a request sharing an item with a principal.
+type GrantInput struct {
+	// Access is models.AccessRead or models.AccessWrite.
+	Access string `json:"access"`
+}
+
+// Validate returns a ValidationError when the access is not one of the allowed ones.
+func (gi *GrantInput) Validate(allowed ...string) error {
+	problems := &ValidationError{}
+
+	if !slices.Contains(allowed, gi.Access) {
+		problems.Add("access", fmt.Sprintf("must be one of %s", strings.Join(allowed, ", ")))
+	}
+
+	return problems.OrNil()
+}
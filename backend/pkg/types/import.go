@@ -0,0 +1,69 @@
+package types
+
+import (
+	"errors"
+)
+
+const (
+	// ImportCreated for when an imported location was created.
+	ImportCreated = "created"
+	// ImportUpdated for when an imported location replaced the one with its ID.
+	ImportUpdated = "updated"
+	// ImportInvalid for when an imported location is invalid; see ImportResult.Fields.
+	ImportInvalid = "invalid"
+	// ImportNotFound for when an imported location has the ID of no location.
+	ImportNotFound = "not_found"
+	// ImportForbidden for when an imported location has the ID of a location the caller may not change.
+	ImportForbidden = "forbidden"
+
+	// MaxImportFeatures is the largest number of locations an import can hold.
+	MaxImportFeatures = 10_000
+)
+
+var (
+	// ErrInvalidImport for when an import is malformed, or too large.
+	ErrInvalidImport = errors.New("invalid import")
+)
+
+// ImportResult models the outcome of a single imported location.
+type ImportResult struct {
+	// Index is the position of the location in the import, from 0.
+	Index int `json:"index"`
+	// Line is the line the location starts on, in imports of line-based formats.
+	Line int `json:"line,omitempty"`
+	// ID is the one of the location written; unknown for the ones created in a dry run.
+	ID      int          `json:"id,omitempty"`
+	Outcome string       `json:"outcome"`
+	Error   string       `json:"error,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// ImportOutput models the outcome of an import.
+type ImportOutput struct {
+	// DryRun tells that nothing was written: the results are what would have happened.
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Failed  int            `json:"failed"`
+	Results []ImportResult `json:"results"`
+	// Error tells why the import stopped early, after the counted locations.
+	Error string `json:"error,omitempty"`
+}
+
+// Add the result of an imported location, and counts it.
+func (io *ImportOutput) Add(result ImportResult) {
+	io.Count(result)
+	io.Results = append(io.Results, result)
+}
+
+// Count the result of an imported location, without keeping it.
+func (io *ImportOutput) Count(result ImportResult) {
+	switch result.Outcome {
+	case ImportCreated:
+		io.Created++
+	case ImportUpdated:
+		io.Updated++
+	default:
+		io.Failed++
+	}
+}
@@ -1,7 +1,52 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MaxTTL is the largest TTL of a pair, in seconds: about 292 years, the longest time.Duration.
+const MaxTTL = math.MaxInt64 / int64(time.Second)
+
+var (
+	// ErrInvalidExpiry for when a pair has a negative TTL or one above MaxTTL, an expiry in the past, or both a TTL
+	// and an expiry.
+	ErrInvalidExpiry = errors.New("invalid expiry")
+)
+
 // Pair models a key value pair.
 type Pair struct {
 	Key   string
 	Value string
+	// TTL is the number of seconds the pair lives for; zero when it does not expire. Excludes ExpiresAt.
+	TTL int64 `json:"ttl,omitempty"`
+	// ExpiresAt is when the pair expires; nil when it does not expire. Excludes TTL.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expiry returns when the pair expires, relative to now; nil when it does not expire.
+func (p *Pair) Expiry(now time.Time) (*time.Time, error) {
+	switch {
+	case p.TTL < 0:
+		return nil, fmt.Errorf("%w: negative TTL", ErrInvalidExpiry)
+	case p.TTL > MaxTTL:
+		return nil, fmt.Errorf("%w: TTL above %d seconds", ErrInvalidExpiry, MaxTTL)
+	case p.TTL > 0 && p.ExpiresAt != nil:
+		return nil, fmt.Errorf("%w: both a TTL and an expiry", ErrInvalidExpiry)
+	case p.TTL > 0:
+		result := now.Add(time.Duration(p.TTL) * time.Second)
+
+		return &result, nil
+	case p.ExpiresAt != nil && !p.ExpiresAt.After(now):
+		return nil, fmt.Errorf("%w: expiry in the past", ErrInvalidExpiry)
+	}
+
+	return p.ExpiresAt, nil
+}
+
+// RevertInput models a request to revert a key value pair to one of its versions.
+type RevertInput struct {
+	Version int64 `json:"version"`
 }
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PairExpiry(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	expiry, err := (&Pair{}).Expiry(now)
+	assert.NoError(t, err)
+	assert.Nil(t, expiry)
+
+	expiry, err = (&Pair{TTL: 60}).Expiry(now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), *expiry)
+
+	expiry, err = (&Pair{TTL: MaxTTL}).Expiry(now)
+	assert.NoError(t, err)
+	assert.True(t, expiry.After(now))
+
+	expiry, err = (&Pair{ExpiresAt: &later}).Expiry(now)
+	assert.NoError(t, err)
+	assert.Equal(t, later, *expiry)
+
+	for _, invalid := range []Pair{
+		{TTL: -1},
+		{TTL: MaxTTL + 1},
+		{TTL: 10_000_000_000},
+		{ExpiresAt: &earlier},
+		{TTL: 60, ExpiresAt: &later},
+	} {
+		_, err = invalid.Expiry(now)
+		assert.ErrorIs(t, err, ErrInvalidExpiry, invalid)
+	}
+}
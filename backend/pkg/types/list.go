@@ -0,0 +1,63 @@
+package types
+
+import "errors"
+
+const (
+	// DefaultLimit is the page size used when a listing does not ask for one.
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a listing can ask for.
+	MaxLimit = 500
+)
+
+var (
+	// ErrInvalidCursor for when a listing cursor was not issued by us, or does not match the listing.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort for when a listing asks to be sorted by an unknown field.
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidFilter for when a listing asks to be filtered by an unknown field.
+	ErrInvalidFilter = errors.New("invalid filter field")
+	// ErrInvalidRange for when a key range is not supported by a listing, or ends before it starts.
+	ErrInvalidRange = errors.New("invalid key range")
+)
+
+// ListOptions models the paging, sorting and filtering of a listing.
+type ListOptions struct {
+	// Limit is the page size. Zero means DefaultLimit; it is capped at MaxLimit.
+	Limit int
+	// Cursor is the NextCursor of the previous page; empty for the first page.
+	Cursor string
+	// SortBy names the field to sort by; empty for the default one.
+	SortBy string
+	// Descending reverses the sort order.
+	Descending bool
+	// Filters keeps only the items whose field (key) equals the given value.
+	Filters map[string]string
+	// Range keeps only the items whose key is in a given range. Only supported by data listings.
+	Range KeyRange
+	// Viewer, when not empty, keeps only the items the principal with this ID owns, or was granted, along with the
+	// ones without an owner. Only supported by data listings.
+	Viewer string
+}
+
+// KeyRange models a range of keys, compared byte by byte: the keys in [Start, End) that start with Prefix.
+//
+// Empty fields do not restrict the range.
+type KeyRange struct {
+	Prefix string
+	Start  string
+	End    string
+}
+
+// IsEmpty tells whether the range does not restrict anything.
+func (kr KeyRange) IsEmpty() bool {
+	return kr.Prefix == "" && kr.Start == "" && kr.End == ""
+}
+
+// Page models one page of a listing.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor fetches the next page; empty on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of items matching the filters, across all pages.
+	Total int64 `json:"total"`
+}
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+const (
+	// MaxLocationName is the largest number of characters in the name of a location.
+	MaxLocationName = 200
+	// MaxImageURL is the largest number of bytes in the image URL of a location.
+	MaxImageURL = 2048
+	// MaxRadius is the largest check-in radius of a location, in meters.
+	MaxRadius = 10_000
+
+	// maxLatitude and maxLongitude bound the coordinates of locations, in degrees.
+	maxLatitude  = 90
+	maxLongitude = 180
+)
+
+// BlobURLPrefix starts the URLs of uploaded images, and of their thumbnails; the hash of their content follows.
+const BlobURLPrefix = "/blobs/"
+
+// blobHash matches the hashes of uploaded images.
+var blobHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// BlobURL returns the URL of the uploaded image (or thumbnail) with a given hash.
+func BlobURL(hash string) string {
+	return BlobURLPrefix + hash
+}
+
+// requiredFields are the fields a location must be written with, unless partially.
+var requiredFields = [...]string{"location", "latitude", "longitude"}
+
+// LocationInput models the body of a request writing a location. Nil fields were not given.
+//
+// The ID is assigned by the server: when given, it must be the one of the location being written.
+type LocationInput struct {
+	ID        *int     `json:"id,omitempty"`
+	Location  *string  `json:"location,omitempty"`
+	Latitude  *float32 `json:"latitude,omitempty"`
+	Longitude *float32 `json:"longitude,omitempty"`
+	// Longitutde is the misspelled key used before it was renamed; Longitude wins when both are given.
+	Longitutde *float32 `json:"longitutde,omitempty"`
+	Image      *string  `json:"image,omitempty"`
+	Radius     *float64 `json:"radius,omitempty"`
+}
+
+// Apply returns base with the fields given in the input. Unless partial, the name and the coordinates must be given.
+//
+// Returns a ValidationError listing every invalid field, of the input or of the result.
+func (li *LocationInput) Apply(base models.Location, partial bool) (models.Location, error) {
+	result := base
+	problems := &ValidationError{}
+
+	if li.ID != nil && *li.ID != base.ID {
+		problems.Add("id", "is assigned by the server")
+	}
+
+	longitude := li.Longitude
+	if longitude == nil {
+		longitude = li.Longitutde
+	}
+
+	if !partial {
+		for index, given := range [...]bool{li.Location != nil, li.Latitude != nil, longitude != nil} {
+			if !given {
+				problems.Add(requiredFields[index], "is required")
+			}
+		}
+	}
+
+	if li.Location != nil {
+		result.Location = *li.Location
+	}
+
+	if li.Latitude != nil {
+		result.Latitude = *li.Latitude
+	}
+
+	if longitude != nil {
+		result.Longitude = *longitude
+	}
+
+	if li.Image != nil {
+		result.Image = *li.Image
+	}
+
+	if li.Radius != nil {
+		result.Radius = *li.Radius
+	}
+
+	validateLocation(result, problems)
+
+	return result, problems.OrNil()
+}
+
+// ValidateLocation checks the fields of a location, and returns a ValidationError listing the invalid ones.
+func ValidateLocation(location models.Location) error {
+	problems := &ValidationError{}
+
+	validateLocation(location, problems)
+
+	return problems.OrNil()
+}
+
+// validateLocation adds the invalid fields of a location to problems.
+func validateLocation(location models.Location, problems *ValidationError) {
+	name := strings.TrimSpace(location.Location)
+
+	switch {
+	case name == "":
+		problems.Add("location", "must not be empty")
+	case utf8.RuneCountInString(location.Location) > MaxLocationName:
+		problems.Add("location", fmt.Sprintf("must be at most %d characters", MaxLocationName))
+	}
+
+	// the ranges are checked so that NaN, which fails every comparison, is out of them
+	if !(location.Latitude >= -maxLatitude && location.Latitude <= maxLatitude) {
+		problems.Add("latitude", fmt.Sprintf("must be in [-%d, %d]", maxLatitude, maxLatitude))
+	}
+
+	if !(location.Longitude >= -maxLongitude && location.Longitude <= maxLongitude) {
+		problems.Add("longitude", fmt.Sprintf("must be in [-%d, %d]", maxLongitude, maxLongitude))
+	}
+
+	if !(location.Radius >= 0 && location.Radius <= MaxRadius) {
+		problems.Add("radius", fmt.Sprintf("must be in [0, %d] meters", MaxRadius))
+	}
+
+	if location.Image != "" {
+		if message := imageProblem(location.Image); message != "" {
+			problems.Add("image", message)
+		}
+	}
+}
+
+// imageProblem returns what is wrong with the URL of an image; empty when nothing is.
+func imageProblem(image string) string {
+	if len(image) > MaxImageURL {
+		return fmt.Sprintf("must be at most %d bytes", MaxImageURL)
+	}
+
+	if hash, isBlob := strings.CutPrefix(image, BlobURLPrefix); isBlob {
+		if !blobHash.MatchString(hash) {
+			return "must be " + BlobURLPrefix + " followed by a SHA-256 hash"
+		}
+
+		return ""
+	}
+
+	parsed, err := url.Parse(image)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "must be an absolute http or https URL, or the URL of an uploaded image"
+	}
+
+	return ""
+}
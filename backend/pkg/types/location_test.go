@@ -0,0 +1,68 @@
+package types
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/models"
+)
+
+func Test_LocationInputApply(t *testing.T) {
+	name, latitude, longitude := "Old Town", float32(44.43), float32(26.1)
+
+	result, err := (&LocationInput{Location: &name, Latitude: &latitude, Longitutde: &longitude}).
+		Apply(models.Location{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Location{Location: name, Latitude: latitude, Longitude: longitude}, result)
+
+	id, blank, tooFar, image := 7, " ", float32(500), "ftp://example.com/a.png"
+
+	_, err = (&LocationInput{ID: &id, Location: &blank, Latitude: &tooFar, Image: &image}).Apply(models.Location{}, false)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	var invalid *ValidationError
+
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []FieldError{
+		{Field: "id", Message: "is assigned by the server"},
+		{Field: "longitude", Message: "is required"},
+		{Field: "location", Message: "must not be empty"},
+		{Field: "latitude", Message: "must be in [-90, 90]"},
+		{Field: "image", Message: "must be an absolute http or https URL, or the URL of an uploaded image"},
+	}, invalid.Fields)
+
+	// a patch keeps the fields that are not given
+	image = "https://example.com/a.png"
+	current := models.Location{ID: id, Location: name, Latitude: latitude, Longitude: longitude}
+
+	result, err = (&LocationInput{ID: &id, Image: &image}).Apply(current, true)
+	assert.NoError(t, err)
+	assert.Equal(t, image, result.Image)
+	assert.Equal(t, name, result.Location)
+
+	assert.NoError(t, ValidateLocation(current))
+
+	current.Image = BlobURL("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	assert.NoError(t, ValidateLocation(current))
+
+	current.Image = BlobURL("../etc/passwd")
+	assert.ErrorIs(t, ValidateLocation(current), ErrInvalidInput)
+	assert.ErrorIs(t, ValidateLocation(models.Location{Location: name, Longitude: -181}), ErrInvalidInput)
+	assert.ErrorIs(t, ValidateLocation(models.Location{Location: name, Radius: -1}), ErrInvalidInput)
+	assert.ErrorIs(t, ValidateLocation(models.Location{Location: name, Radius: MaxRadius + 1}), ErrInvalidInput)
+
+	nan, inf := math.NaN(), math.Inf(1)
+
+	for _, location := range []models.Location{
+		{Location: name, Latitude: float32(nan)},
+		{Location: name, Longitude: float32(nan)},
+		{Location: name, Latitude: float32(-inf)},
+		{Location: name, Longitude: float32(inf)},
+		{Location: name, Radius: nan},
+		{Location: name, Radius: inf},
+	} {
+		assert.ErrorIs(t, ValidateLocation(location), ErrInvalidInput, "%+v", location)
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultNamespace holds the data entries of the requests that name no namespace, and the ones written before
+	// namespaces.
+	DefaultNamespace = "default"
+	// MaxNamespace is the largest number of characters in the name of a namespace.
+	MaxNamespace = 63
+)
+
+var (
+	// ErrInvalidNamespace for when the name of a namespace is empty, too long, or holds other characters than lowercase
+	// letters, digits, '-' and '_'.
+	ErrInvalidNamespace = errors.New("invalid namespace")
+	// ErrValueTooLarge for when a value is larger than the quota of its namespace allows.
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrQuotaExceeded for when a write would take a namespace over its quota of keys or bytes.
+	ErrQuotaExceeded = errors.New("namespace quota exceeded")
+)
+
+// Usage models how much of its quota a namespace uses.
+type Usage struct {
+	// Keys is the number of data entries that were not deleted and did not expire.
+	Keys int64 `json:"keys"`
+	// Bytes is the total size of their keys and values.
+	Bytes int64 `json:"bytes"`
+}
+
+// ValidateNamespace returns ErrInvalidNamespace when name is not a valid namespace: 1 to MaxNamespace lowercase
+// letters, digits, '-' and '_', starting with a letter or a digit.
+func ValidateNamespace(name string) error {
+	if name == "" || len(name) > MaxNamespace {
+		return fmt.Errorf("%w %q: expected 1 to %d characters", ErrInvalidNamespace, name, MaxNamespace)
+	}
+
+	for position, char := range name {
+		alphanumeric := (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9')
+		if !alphanumeric && (position == 0 || (char != '-' && char != '_')) {
+			return fmt.Errorf("%w %q: expected lowercase letters, digits, '-' and '_'", ErrInvalidNamespace, name)
+		}
+	}
+
+	return nil
+}
+
+// namespaceKey is the context key of namespaces.
+type namespaceKey struct{}
+
+// WithNamespace returns a copy of a context holding the namespace a request works in.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the namespace held by a context; DefaultNamespace when there is none.
+func NamespaceFrom(ctx context.Context) string {
+	result, found := ctx.Value(namespaceKey{}).(string)
+	if !found || result == "" {
+		return DefaultNamespace
+	}
+
+	return result
+}
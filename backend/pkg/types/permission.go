@@ -3,14 +3,70 @@ Package types offers DTOs that cross layers.
 */
 package types
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"slices"
+)
 
 const (
 	// PermissionReadWrite used in file creation.
 	PermissionReadWrite = 0600
+	// PermissionDirectory used in directory creation.
+	PermissionDirectory = 0700
 )
 
 var (
 	// ErrCancelledContext for when the context was cancelled.
 	ErrCancelledContext = errors.New("cancelled context")
+	// ErrForbidden for when the caller may see an item, but not change it.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Scope grants access to a group of routes.
+type Scope string
+
+const (
+	// ScopeDataRead reads data entries, their history and the trash.
+	ScopeDataRead Scope = "data:read"
+	// ScopeDataWrite writes data entries.
+	ScopeDataWrite Scope = "data:write"
+	// ScopeLocationWrite writes locations, imports them and uploads their images.
+	ScopeLocationWrite Scope = "location:write"
+	// ScopeTokenMint mints tokens, follows their mint jobs, and checks in at locations.
+	ScopeTokenMint Scope = "token:mint"
+	// ScopeAdmin grants every other scope, and manages API keys.
+	ScopeAdmin Scope = "admin"
 )
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopeDataRead, ScopeDataWrite, ScopeLocationWrite, ScopeTokenMint, ScopeAdmin}
+
+// Principal models the authenticated caller of a request.
+type Principal struct {
+	// ID identifies the caller, prefixed by where it comes from: key:<ID of its API key>, jwt:<issuer>:<subject of
+	// its token>, or config:admin for the admin key. Writes are attributed to it, and items are owned by it.
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// HasScope tells whether the principal was granted a scope, or ScopeAdmin.
+func (p Principal) HasScope(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// principalKey is the context key of principals.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of a context holding the caller of a request.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller held by a context, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	result, found := ctx.Value(principalKey{}).(Principal)
+
+	return result, found
+}
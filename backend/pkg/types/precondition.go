@@ -0,0 +1,54 @@
+package types
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrPreconditionFailed for when a write is conditioned on a version the entry does not have.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// ETagCondition models the value of an If-Match or If-None-Match header, with versions standing for ETags.
+type ETagCondition struct {
+	// Present is true when the header was sent.
+	Present bool
+	// Any is true for "*", which matches any existing entry.
+	Any bool
+	// Versions lists the versions that match.
+	Versions []int64
+}
+
+// Matches tells whether an entry matches the condition.
+func (ec ETagCondition) Matches(exists bool, version int64) bool {
+	if !exists {
+		return false
+	}
+
+	return ec.Any || slices.Contains(ec.Versions, version)
+}
+
+// Precondition models the conditions a request puts on the current version of an entry.
+type Precondition struct {
+	IfMatch     ETagCondition
+	IfNoneMatch ETagCondition
+}
+
+// IsEmpty tells whether there are no conditions.
+func (p Precondition) IsEmpty() bool {
+	return !p.IfMatch.Present && !p.IfNoneMatch.Present
+}
+
+// Holds tells whether an entry passes the conditions.
+func (p Precondition) Holds(exists bool, version int64) bool {
+	if p.IfMatch.Present && !p.IfMatch.Matches(exists, version) {
+		return false
+	}
+
+	if p.IfNoneMatch.Present && p.IfNoneMatch.Matches(exists, version) {
+		return false
+	}
+
+	return true
+}
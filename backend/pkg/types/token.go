@@ -1,13 +1,114 @@
 package types
 
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+)
+
+var (
+	// tokenName matches the names of tokens: asset names, on chain.
+	tokenName = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+	// walletAddress matches Cardano (Shelley) wallet addresses, of the main and the test networks.
+	walletAddress = regexp.MustCompile(`^addr(_test)?1[02-9ac-hj-np-z]{50,110}$`)
+)
+
 // TokenInput models a token input.
 type TokenInput struct {
-	Tokenname                string `json:"tokenname"`
-	Bearer                   string `json:"bearer"`
-	Displayname              string `json:"displayname"`
-	Description              string `json:"description"`
+	Tokenname   string `json:"tokenname"`
+	Displayname string `json:"displayname"`
+	Description string `json:"description"`
+	// Mimetype of the image; guessed from the content of FileFromBase64 when empty.
+	Mimetype                 string `json:"mimetype"`
 	FileFromIPFS             string `json:"fileFromIPFS"`
 	FileFromBase64           string `json:"fileFromBase64"`
 	MetadataPlaceholderName  string `json:"metadataPlaceholderName"`
 	MetadataPlaceholderValue string `json:"metadataPlaceholderValue"`
+	// Receiver is the wallet the token is sent to; the one of the project when empty.
+	Receiver string `json:"receiver"`
+}
+
+// Token returns the token to upload; the receiver is checked, but left out.
+//
+// Returns a ValidationError listing every invalid field.
+func (ti *TokenInput) Token() (minting.Token, error) {
+	problems := &ValidationError{}
+
+	if !tokenName.MatchString(ti.Tokenname) {
+		problems.Add("tokenname", "must be 1 to 32 letters or digits")
+	}
+
+	mimeType := ti.Mimetype
+
+	switch {
+	case (ti.FileFromIPFS == "") == (ti.FileFromBase64 == ""):
+		problems.Add("fileFromIPFS", "expected either fileFromIPFS or fileFromBase64")
+	case ti.FileFromBase64 != "":
+		content, err := base64.StdEncoding.DecodeString(ti.FileFromBase64)
+		if err != nil {
+			problems.Add("fileFromBase64", "must be base64")
+		} else if mimeType == "" {
+			mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(content))
+		}
+	case mimeType == "":
+		problems.Add("mimetype", "is required with fileFromIPFS")
+	}
+
+	if mimeType != "" {
+		_, _, err := mime.ParseMediaType(mimeType)
+		if err != nil {
+			problems.Add("mimetype", "must be a media type, i.e.: image/png")
+		}
+	}
+
+	if ti.MetadataPlaceholderName == "" && ti.MetadataPlaceholderValue != "" {
+		problems.Add("metadataPlaceholderName", "is required with metadataPlaceholderValue")
+	}
+
+	if ti.Receiver != "" && !IsWalletAddress(ti.Receiver) {
+		problems.Add("receiver", "must be a Cardano wallet address")
+	}
+
+	err := problems.OrNil()
+	if err != nil {
+		return minting.Token{}, err
+	}
+
+	result := minting.Token{
+		Name:        ti.Tokenname,
+		DisplayName: ti.Displayname,
+		Description: ti.Description,
+		Image:       minting.Image{MimeType: mimeType, IPFS: ti.FileFromIPFS, Base64: ti.FileFromBase64},
+	}
+
+	if ti.MetadataPlaceholderName != "" {
+		result.Metadata = map[string]string{ti.MetadataPlaceholderName: ti.MetadataPlaceholderValue}
+	}
+
+	return result, nil
+}
+
+// MintInput models the body of a request minting a token.
+type MintInput struct {
+	// Receiver is the wallet the token is sent to; the one of the project when empty.
+	Receiver string `json:"receiver"`
+}
+
+// Validate returns a ValidationError when the receiver is not a wallet address.
+func (mi *MintInput) Validate() error {
+	problems := &ValidationError{}
+
+	if mi.Receiver != "" && !IsWalletAddress(mi.Receiver) {
+		problems.Add("receiver", "must be a Cardano wallet address")
+	}
+
+	return problems.OrNil()
+}
+
+// IsWalletAddress tells whether a string looks like a Cardano wallet address.
+func IsWalletAddress(address string) bool {
+	return walletAddress.MatchString(address)
 }
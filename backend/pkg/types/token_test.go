@@ -0,0 +1,46 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wakka-2/Namless/backend/pkg/minting"
+)
+
+func Test_TokenInput(t *testing.T) {
+	token, err := (&TokenInput{
+		Tokenname:                "Token1",
+		FileFromBase64:           "iVBORw0KGgo=",
+		MetadataPlaceholderName:  "city",
+		MetadataPlaceholderValue: "Paris",
+	}).Token()
+	assert.NoError(t, err)
+	assert.Equal(t, minting.Token{
+		Name:     "Token1",
+		Image:    minting.Image{MimeType: "image/png", Base64: "iVBORw0KGgo="},
+		Metadata: map[string]string{"city": "Paris"},
+	}, token)
+
+	_, err = (&TokenInput{Tokenname: "Token 1", FileFromIPFS: "Qm", MetadataPlaceholderValue: "Paris"}).Token()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	var invalid *ValidationError
+
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []FieldError{
+		{Field: "tokenname", Message: "must be 1 to 32 letters or digits"},
+		{Field: "mimetype", Message: "is required with fileFromIPFS"},
+		{Field: "metadataPlaceholderName", Message: "is required with metadataPlaceholderValue"},
+	}, invalid.Fields)
+
+	_, err = (&TokenInput{Tokenname: "Token1", FileFromIPFS: "Qm", FileFromBase64: "AA=="}).Token()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = (&TokenInput{Tokenname: "Token1", FileFromBase64: "not base64!"}).Token()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	assert.NoError(t, (&MintInput{}).Validate())
+	assert.NoError(t, (&MintInput{Receiver: minting.FakeReceiver}).Validate())
+	assert.ErrorIs(t, (&MintInput{Receiver: "addr1short"}).Validate(), ErrInvalidInput)
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidInput for when some fields of an input are invalid; see ValidationError.
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// FieldError models what is wrong with a single field of an input.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of an input. It matches ErrInvalidInput.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add a field error, unless the field already has one.
+func (ve *ValidationError) Add(field string, message string) {
+	for _, existing := range ve.Fields {
+		if existing.Field == field {
+			return
+		}
+	}
+
+	ve.Fields = append(ve.Fields, FieldError{Field: field, Message: message})
+}
+
+// OrNil returns the validation error when it holds field errors, nil otherwise.
+func (ve *ValidationError) OrNil() error {
+	if len(ve.Fields) == 0 {
+		return nil
+	}
+
+	return ve
+}
+
+func (ve *ValidationError) Error() string {
+	messages := make([]string, 0, len(ve.Fields))
+
+	for _, field := range ve.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+
+	return ErrInvalidInput.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is tells whether target is ErrInvalidInput.
+func (ve *ValidationError) Is(target error) bool {
+	return target == ErrInvalidInput
+}